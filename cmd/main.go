@@ -1,31 +1,500 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Richthonio10/requirement-swtpro/generated"
 	"github.com/Richthonio10/requirement-swtpro/handler"
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
+	"github.com/Richthonio10/requirement-swtpro/pkg/ratelimit"
 	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/Richthonio10/requirement-swtpro/sms"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
 	e := echo.New()
+	// The client IP is used to throttle logins, so forwarding headers are only
+	// trusted when the service is known to run behind a proxy that sets them.
+	if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
+
+	repo := repository.NewRepository(repository.NewRepositoryOptions{
+		Dsn: os.Getenv("DATABASE_URL"),
+	})
+	server := newServer(repo)
+	go pruneOneTimeCodes(repo)
+	go pruneRegistrations(repo)
 
-	var server generated.ServerInterface = newServer()
-	generated.RegisterHandlers(e, server)
+	limiter, err := newRateLimiter(repo.Db, server)
+	if err != nil {
+		panic(err)
+	}
+	e.Use(limiter)
+
+	var si generated.ServerInterface = server
+	generated.RegisterHandlers(e, si)
 
 	e.Logger.Fatal(e.Start(":1323"))
 }
 
-func newServer() *handler.Server {
-	dbDsn := os.Getenv("DATABASE_URL")
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
-		Dsn: dbDsn,
-	})
+func newServer(repo repository.RepositoryInterface) *handler.Server {
+	keys, err := newKeySet()
+	if err != nil {
+		panic(err)
+	}
+	lockout, err := newLockoutOptions()
+	if err != nil {
+		panic(err)
+	}
+	sessions, err := newSessionOptions()
+	if err != nil {
+		panic(err)
+	}
+	totpKey, err := newTOTPEncryptionKey()
+	if err != nil {
+		panic(err)
+	}
+	passwordHasher, err := newPasswordHasher()
+	if err != nil {
+		panic(err)
+	}
+	breachedPasswords, err := newBreachedList()
+	if err != nil {
+		panic(err)
+	}
+	minPasswordScore, err := envInt("PASSWORD_MIN_SCORE")
+	if err != nil {
+		panic(err)
+	}
+	// Unset means the default score. A score of 0 would accept any password,
+	// so it is refused rather than taken for unset.
+	if os.Getenv("PASSWORD_MIN_SCORE") != "" && (minPasswordScore < 1 || minPasswordScore > 4) {
+		panic(fmt.Errorf("invalid PASSWORD_MIN_SCORE: want 1 to 4, got %d", minPasswordScore))
+	}
+	passwordHistorySize, err := envInt("PASSWORD_HISTORY_SIZE")
+	if err != nil {
+		panic(err)
+	}
+	maxPasswordAge, err := envDuration("PASSWORD_MAX_AGE")
+	if err != nil {
+		panic(err)
+	}
+	hashingPool, err := newHashingPool()
+	if err != nil {
+		panic(err)
+	}
 	opts := handler.NewServerOptions{
-		Repository: repo,
+		Repository:  repo,
+		Keys:        keys,
+		Lockout:     lockout,
+		Sessions:    sessions,
+		AdminAPIKey: os.Getenv("ADMIN_API_KEY"),
+		// Lets accounts created before phone verification existed keep
+		// logging in until they verify.
+		AllowUnverifiedLogin:    os.Getenv("LOGIN_ALLOW_UNVERIFIED") == "true",
+		NotifyPhoneNumberChange: os.Getenv("PHONE_CHANGE_NOTIFY_CURRENT") == "true",
+		EnumerationSafe:         os.Getenv("ENUMERATION_SAFE") == "true",
+		CookieSessions:          os.Getenv("SESSION_COOKIES") == "true",
+		TOTPEncryptionKey:       totpKey,
+		PasswordHasher:          passwordHasher,
+		BreachedPasswords:       breachedPasswords,
+		MinPasswordScore:        minPasswordScore,
+		PasswordHistorySize:     passwordHistorySize,
+		MaxPasswordAge:          maxPasswordAge,
+		HashingPool:             hashingPool,
+		SMSSender: sms.NewFileSender(sms.NewFileSenderOptions{
+			Path: os.Getenv("SMS_OUTBOX_FILE"),
+		}),
 	}
 	return handler.NewServer(opts)
 }
+
+// defaultRateLimitRules applies when RATE_LIMIT_RULES is not set.
+const defaultRateLimitRules = "POST /login=10/1m:phone,POST /login/mfa=10/1m,POST /register=5/1h,POST /token/refresh=30/1m," +
+	"POST /login/otp=3/15m:phone,POST /login/otp/verify=10/15m:phone,POST /login/password-change=10/15m," +
+	"POST /register/verify=10/15m:phone,POST /register/verification-codes=3/15m:phone," +
+	"POST /password/reset-requests=3/15m:phone,POST /password/resets=10/15m:phone," +
+	"PATCH /profile=10/15m:user,POST /profile/phone-number/verify=10/15m:user,POST /profile/totp/recovery-codes=5/1h:user," +
+	"*=120/1m:user"
+
+// newRateLimiter builds the rate limiting middleware from the environment:
+//
+//	RATE_LIMIT_RULES   see ratelimit.ParseRules, keys are ip, user and phone
+//	RATE_LIMIT_STORE   memory (default) or postgres
+func newRateLimiter(db *sql.DB, server *handler.Server) (echo.MiddlewareFunc, error) {
+	spec := os.Getenv("RATE_LIMIT_RULES")
+	if spec == "" {
+		spec = defaultRateLimitRules
+	}
+	rules, err := ratelimit.ParseRules(spec, map[string]ratelimit.KeyFunc{
+		"ip":    ratelimit.KeyByIP,
+		"user":  ratelimit.KeyByUserID(server.UserIDFromToken),
+		"phone": ratelimit.KeyByPhoneNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var store ratelimit.Store
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		postgresStore := ratelimit.NewPostgresStore(db)
+		go pruneRateLimitBuckets(postgresStore, rules)
+		store = postgresStore
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+
+	return ratelimit.Middleware(ratelimit.Config{
+		Store:       store,
+		Rules:       rules,
+		DenyHandler: server.RateLimitExceeded,
+	}), nil
+}
+
+// pruneRateLimitBuckets periodically deletes the buckets that had time to
+// refill completely under every rule.
+func pruneRateLimitBuckets(store *ratelimit.PostgresStore, rules map[string]ratelimit.Rule) {
+	var longestPeriod time.Duration
+	for _, rule := range rules {
+		if rule.Limit.Period > longestPeriod {
+			longestPeriod = rule.Limit.Period
+		}
+	}
+
+	for range time.Tick(time.Duration(10) * time.Minute) {
+		err := store.DeleteBefore(context.Background(), time.Now().Add(-longestPeriod))
+		if err != nil {
+			log.Errorf("Error When DeleteBefore: %s", err.Error())
+		}
+	}
+}
+
+// pruneRegistrations periodically deletes the users who did not verify their
+// phone number in time, which frees the number for its owner.
+func pruneRegistrations(repo repository.RepositoryInterface) {
+	for range time.Tick(time.Duration(10) * time.Minute) {
+		err := repo.DeleteExpiredRegistrations(context.Background(), time.Now())
+		if err != nil {
+			log.Errorf("Error When DeleteExpiredRegistrations: %s", err.Error())
+		}
+	}
+}
+
+// pruneOneTimeCodes periodically deletes expired one time codes, which also
+// drops the phone number changes that were never verified.
+func pruneOneTimeCodes(repo repository.RepositoryInterface) {
+	for range time.Tick(time.Duration(10) * time.Minute) {
+		err := repo.DeleteExpiredOneTimeCodes(context.Background(), time.Now())
+		if err != nil {
+			log.Errorf("Error When DeleteExpiredOneTimeCodes: %s", err.Error())
+		}
+	}
+}
+
+// newKeySet loads the JWT keys from the environment:
+//
+//	JWT_SIGNING_KEY_ID     key id put in the kid header of new tokens
+//	JWT_SIGNING_KEY        PEM encoded RSA private key, or
+//	JWT_SIGNING_KEY_FILE   path to it
+//	JWT_VERIFY_KEY_FILES   comma separated kid=path pairs of public keys that
+//	                       are still accepted, e.g. keys retired by a rotation
+//
+// A signing key is required; only in development mode does the server fall
+// back to its development key, which is public.
+func newKeySet() (*handler.KeySet, error) {
+	signingKey := []byte(os.Getenv("JWT_SIGNING_KEY"))
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		signingKey = content
+	}
+	if len(signingKey) == 0 {
+		if strings.TrimSpace(os.Getenv("JWT_VERIFY_KEY_FILES")) != "" {
+			return nil, errors.New("JWT_VERIFY_KEY_FILES is set without JWT_SIGNING_KEY or JWT_SIGNING_KEY_FILE")
+		}
+		if !developmentMode() {
+			return nil, errors.New("JWT_SIGNING_KEY or JWT_SIGNING_KEY_FILE is required, set APP_ENV=development to use the development key")
+		}
+		fmt.Fprintln(os.Stderr, "JWT_SIGNING_KEY is not set, using the development signing key")
+		return nil, nil
+	}
+
+	verifyKeys := make(map[string][]byte)
+	for _, pair := range strings.Split(os.Getenv("JWT_VERIFY_KEY_FILES"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		keyID, path, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid JWT_VERIFY_KEY_FILES entry %q", pair)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		verifyKeys[keyID] = content
+	}
+
+	return handler.NewKeySet(handler.NewKeySetOptions{
+		SigningKeyID:  os.Getenv("JWT_SIGNING_KEY_ID"),
+		SigningKeyPEM: signingKey,
+		VerifyKeyPEMs: verifyKeys,
+	})
+}
+
+// newTOTPEncryptionKey reads TOTP_ENCRYPTION_KEY, the base64 encoded 32 byte
+// key the authenticator app secrets are encrypted with. It is required; only
+// in development mode does the server fall back to its development key.
+func newTOTPEncryptionKey() ([]byte, error) {
+	value := os.Getenv("TOTP_ENCRYPTION_KEY")
+	if value == "" {
+		if !developmentMode() {
+			return nil, errors.New("TOTP_ENCRYPTION_KEY is required, set APP_ENV=development to use the development key")
+		}
+		fmt.Fprintln(os.Stderr, "TOTP_ENCRYPTION_KEY is not set, using the development encryption key")
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP_ENCRYPTION_KEY: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid TOTP_ENCRYPTION_KEY: want 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// newPasswordHasher reads how new passwords are hashed:
+//
+//	PASSWORD_HASH_ALGORITHM       bcrypt (default) or argon2id
+//	PASSWORD_BCRYPT_COST          defaults to 12
+//	PASSWORD_ARGON2ID_MEMORY      in KiB, defaults to 65536
+//	PASSWORD_ARGON2ID_ITERATIONS  defaults to 3
+//	PASSWORD_ARGON2ID_PARALLELISM defaults to 2
+//
+// Stored hashes of either algorithm keep working and are upgraded to these
+// settings the next time their user logs in.
+func newPasswordHasher() (password.PasswordHasher, error) {
+	hasher, err := newBaseHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	peppers, err := parsePeppers(os.Getenv("PASSWORD_PEPPERS"))
+	if err != nil {
+		return nil, err
+	}
+	if len(peppers) == 0 {
+		return hasher, nil
+	}
+	return password.NewPepperedHasher(password.PepperedHasherOptions{
+		Hasher:   hasher,
+		Current:  peppers[0],
+		Previous: peppers[1:],
+	})
+}
+
+// parsePeppers reads PASSWORD_PEPPERS, a comma separated list of id:key
+// pairs with base64 encoded keys. The first pepper is applied to new
+// hashes, the others are kept while hashes made with them remain.
+func parsePeppers(value string) (peppers []password.Pepper, err error) {
+	if value == "" {
+		return nil, nil
+	}
+	for i, entry := range strings.Split(value, ",") {
+		// The entry is not quoted in the error, it may be a bare key.
+		id, encodedKey, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid PASSWORD_PEPPERS entry %d: want id:key", i+1)
+		}
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PASSWORD_PEPPERS key of %q: %w", id, err)
+		}
+		if len(key) < 32 {
+			return nil, fmt.Errorf("invalid PASSWORD_PEPPERS key of %q: want at least 32 bytes, got %d", id, len(key))
+		}
+		peppers = append(peppers, password.Pepper{ID: id, Key: key})
+	}
+	return peppers, nil
+}
+
+// newBreachedList reads PASSWORD_BREACHED_LIST, the path of the breached
+// passwords new passwords are checked against: a directory of range files,
+// or a file of full SHA-1 hashes that is loaded in memory. Unset means no
+// check.
+func newBreachedList() (password.BreachedList, error) {
+	path := os.Getenv("PASSWORD_BREACHED_LIST")
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_BREACHED_LIST: %w", err)
+	}
+	if info.IsDir() {
+		return password.NewBreachedDir(path), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_BREACHED_LIST: %w", err)
+	}
+	defer file.Close()
+	return password.LoadBreachedSet(file)
+}
+
+func newBaseHasher() (password.PasswordHasher, error) {
+	switch algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM"); algorithm {
+	case "", "bcrypt":
+		cost, err := envInt("PASSWORD_BCRYPT_COST")
+		if err != nil {
+			return nil, err
+		}
+		if cost != 0 && (cost < bcrypt.MinCost || cost > bcrypt.MaxCost) {
+			return nil, fmt.Errorf("invalid PASSWORD_BCRYPT_COST: want %d to %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+		}
+		return password.NewBcryptHasher(password.BcryptOptions{Cost: cost}), nil
+	case "argon2id":
+		var opts password.Argon2idOptions
+		memory, err := envInt("PASSWORD_ARGON2ID_MEMORY")
+		if err != nil {
+			return nil, err
+		}
+		iterations, err := envInt("PASSWORD_ARGON2ID_ITERATIONS")
+		if err != nil {
+			return nil, err
+		}
+		parallelism, err := envInt("PASSWORD_ARGON2ID_PARALLELISM")
+		if err != nil {
+			return nil, err
+		}
+		if memory < 0 || iterations < 0 || parallelism < 0 || parallelism > 255 {
+			return nil, fmt.Errorf("invalid argon2id parameters: memory %d, iterations %d, parallelism %d", memory, iterations, parallelism)
+		}
+		opts.Memory = uint32(memory)
+		opts.Iterations = uint32(iterations)
+		opts.Parallelism = uint8(parallelism)
+		return password.NewArgon2idHasher(opts), nil
+	default:
+		return nil, fmt.Errorf("invalid PASSWORD_HASH_ALGORITHM: %q", algorithm)
+	}
+}
+
+// newHashingPool bounds password hashing to PASSWORD_HASHING_CONCURRENCY
+// hashes at once, GOMAXPROCS when unset, with PASSWORD_HASHING_QUEUE_DEPTH
+// more requests waiting.
+func newHashingPool() (*password.Pool, error) {
+	concurrency, err := envInt("PASSWORD_HASHING_CONCURRENCY")
+	if err != nil {
+		return nil, err
+	}
+	queueDepth, err := envInt("PASSWORD_HASHING_QUEUE_DEPTH")
+	if err != nil {
+		return nil, err
+	}
+	if concurrency < 0 || queueDepth < 0 {
+		return nil, fmt.Errorf("invalid password hashing pool: concurrency %d, queue depth %d", concurrency, queueDepth)
+	}
+	return password.NewPool(password.PoolOptions{
+		Concurrency: concurrency,
+		QueueDepth:  queueDepth,
+	}), nil
+}
+
+// newLockoutOptions reads the login throttling limits from the environment.
+// Unset variables keep the handler defaults.
+func newLockoutOptions() (opts handler.LockoutOptions, err error) {
+	if opts.MaxAccountFailures, err = envInt("LOGIN_MAX_ACCOUNT_FAILURES"); err != nil {
+		return opts, err
+	}
+	if opts.MaxIPFailures, err = envInt("LOGIN_MAX_IP_FAILURES"); err != nil {
+		return opts, err
+	}
+	if opts.LockoutDuration, err = envDuration("LOGIN_LOCKOUT_DURATION"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// newSessionOptions reads how long sessions last and how many a user may
+// have from the environment:
+//
+//	SESSION_IDLE_TIMEOUT   e.g. 30m, unset means sessions do not go idle
+//	SESSION_MAX_LIFETIME   defaults to 30 days
+//	SESSION_MAX_CONCURRENT unset means no limit
+//	SESSION_LIMIT_POLICY   evict_oldest (default) or reject
+func newSessionOptions() (opts handler.SessionOptions, err error) {
+	if opts.IdleTimeout, err = envDuration("SESSION_IDLE_TIMEOUT"); err != nil {
+		return opts, err
+	}
+	if opts.MaxLifetime, err = envDuration("SESSION_MAX_LIFETIME"); err != nil {
+		return opts, err
+	}
+	if opts.IdleTimeout < 0 || opts.MaxLifetime < 0 {
+		return opts, fmt.Errorf("invalid session options: idle timeout %s, max lifetime %s", opts.IdleTimeout, opts.MaxLifetime)
+	}
+	if opts.MaxConcurrent, err = envInt("SESSION_MAX_CONCURRENT"); err != nil {
+		return opts, err
+	}
+	if opts.MaxConcurrent < 0 {
+		return opts, fmt.Errorf("invalid SESSION_MAX_CONCURRENT %d", opts.MaxConcurrent)
+	}
+	switch policy := handler.SessionLimitPolicy(os.Getenv("SESSION_LIMIT_POLICY")); policy {
+	case "", handler.SessionLimitEvictOldest, handler.SessionLimitReject:
+		opts.LimitPolicy = policy
+	default:
+		return opts, fmt.Errorf("invalid SESSION_LIMIT_POLICY %q", policy)
+	}
+	return opts, nil
+}
+
+// developmentMode reports whether APP_ENV=development, which lets the
+// server start with the development keys committed in this repository.
+func developmentMode() bool {
+	return os.Getenv("APP_ENV") == "development"
+}
+
+func envInt(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return n, nil
+}
+
+func envDuration(name string) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return d, nil
+}
@@ -13,6 +13,106 @@ CREATE TABLE "user" (
 	phone_number VARCHAR NOT NULL,
 	"password" VARCHAR NOT NULL,
 	full_name VARCHAR NOT NULL,
-	login_count BIGINT
+	phone_verified_at TIMESTAMPTZ,
+	password_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_login_at TIMESTAMPTZ,
+	token_version BIGINT NOT NULL DEFAULT 0,
+	/** Set while a new registration waits for its phone number to be verified; the user is deleted once it passes. */
+	registration_expires_at TIMESTAMPTZ
 );
-CREATE INDEX CONCURRENTLY IF NOT EXISTS user_phone_number ON "user"(phone_number);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS user_phone_number ON "user"(phone_number);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS user_registration_expires_at ON "user"(registration_expires_at) WHERE phone_verified_at IS NULL;
+
+/** Every password hash a user had, for the reuse check. Rehashes of the same password are not recorded. */
+CREATE TABLE password_history (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	"password" VARCHAR NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS password_history_user_id ON password_history(user_id, created_at);
+
+CREATE TABLE "session" (
+	id VARCHAR PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	device_name VARCHAR NOT NULL DEFAULT '',
+	user_agent VARCHAR NOT NULL DEFAULT '',
+	ip_address VARCHAR NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS session_user_id ON "session"(user_id);
+
+CREATE TABLE refresh_token (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	family_id VARCHAR NOT NULL,
+	token_hash VARCHAR NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	rotated_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS refresh_token_family_id ON refresh_token(family_id);
+
+/** Every login attempt. user_id is NULL when no user had the phone number. */
+CREATE TABLE login_events (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT REFERENCES "user"(id) ON DELETE CASCADE,
+	phone_number VARCHAR NOT NULL,
+	ip_address VARCHAR NOT NULL,
+	user_agent VARCHAR NOT NULL,
+	outcome VARCHAR NOT NULL,
+	failure_reason VARCHAR,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS login_events_user_id ON login_events(user_id, id);
+
+/** Failed login counters keyed by "phone:<number>" or "ip:<address>". */
+CREATE TABLE login_attempt (
+	"key" VARCHAR PRIMARY KEY,
+	failed_count INT NOT NULL,
+	last_failed_at TIMESTAMPTZ NOT NULL,
+	locked_until TIMESTAMPTZ
+);
+
+/** Token buckets of the rate limiter when RATE_LIMIT_STORE=postgres. */
+CREATE TABLE rate_limit_bucket (
+	"key" VARCHAR PRIMARY KEY,
+	tokens DOUBLE PRECISION NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS rate_limit_bucket_updated_at ON rate_limit_bucket(updated_at);
+
+/** Hashed one-time codes sent by SMS, e.g. for password resets. */
+CREATE TABLE one_time_code (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	purpose VARCHAR NOT NULL,
+	phone_number VARCHAR NOT NULL,
+	code_hash VARCHAR NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS one_time_code_user_id_purpose ON one_time_code(user_id, purpose);
+
+CREATE TABLE user_totp (
+	user_id BIGINT PRIMARY KEY REFERENCES "user"(id) ON DELETE CASCADE,
+	encrypted_secret VARCHAR NOT NULL,
+	last_used_step BIGINT,
+	confirmed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE recovery_code (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+	code_hash VARCHAR NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS recovery_code_user_id ON recovery_code(user_id);
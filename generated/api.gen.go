@@ -8,18 +8,99 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
+	"github.com/oapi-codegen/runtime"
 )
 
 const (
-	BearerAuthScopes = "BearerAuth.Scopes"
+	AdminKeyAuthScopes = "AdminKeyAuth.Scopes"
+	BearerAuthScopes   = "BearerAuth.Scopes"
 )
 
+// Defines values for LoginEventOutcome.
+const (
+	LoginEventOutcomeFailure                LoginEventOutcome = "failure"
+	LoginEventOutcomeLocked                 LoginEventOutcome = "locked"
+	LoginEventOutcomeMfaRequired            LoginEventOutcome = "mfa_required"
+	LoginEventOutcomePasswordChangeRequired LoginEventOutcome = "password_change_required"
+	LoginEventOutcomeSuccess                LoginEventOutcome = "success"
+)
+
+// ActiveSession defines model for ActiveSession.
+type ActiveSession struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Current Whether this is the session making the request.
+	Current bool `json:"current"`
+
+	// DeviceName The X-Device-Name header sent at login, if any.
+	DeviceName *string `json:"device_name,omitempty"`
+	Id         string  `json:"id"`
+
+	// IpAddress The address the session was last seen from.
+	IpAddress  string    `json:"ip_address"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	UserAgent  string    `json:"user_agent"`
+}
+
+// AdminUnlockRequest defines model for AdminUnlockRequest.
+type AdminUnlockRequest struct {
+	IpAddress   *string `json:"ip_address,omitempty"`
+	PhoneNumber *string `json:"phone_number,omitempty"`
+}
+
+// AdminUnlockResponse defines model for AdminUnlockResponse.
+type AdminUnlockResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// ConfirmTotpRequest defines model for ConfirmTotpRequest.
+type ConfirmTotpRequest struct {
+	Code string `json:"code"`
+}
+
+// ConfirmTotpResponse defines model for ConfirmTotpResponse.
+type ConfirmTotpResponse struct {
+	Data   *RecoveryCodesResponseData `json:"data,omitempty"`
+	Header ResponseHeader             `json:"header"`
+}
+
+// EnrollTotpResponse defines model for EnrollTotpResponse.
+type EnrollTotpResponse struct {
+	Data   *EnrollTotpResponseData `json:"data,omitempty"`
+	Header ResponseHeader          `json:"header"`
+}
+
+// EnrollTotpResponseData defines model for EnrollTotpResponseData.
+type EnrollTotpResponseData struct {
+	Secret string `json:"secret"`
+
+	// Uri otpauth:// URI of the secret, to be shown as a QR code.
+	Uri string `json:"uri"`
+}
+
+// GetProfileLoginsResponse defines model for GetProfileLoginsResponse.
+type GetProfileLoginsResponse struct {
+	Data   *GetProfileLoginsResponseData `json:"data,omitempty"`
+	Header ResponseHeader                `json:"header"`
+}
+
+// GetProfileLoginsResponseData defines model for GetProfileLoginsResponseData.
+type GetProfileLoginsResponseData struct {
+	// Logins Newest first.
+	Logins []LoginEvent `json:"logins"`
+
+	// NextCursor Absent on the last page.
+	NextCursor *string `json:"next_cursor,omitempty"`
+}
+
 // GetProfileResponse defines model for GetProfileResponse.
 type GetProfileResponse struct {
 	Data   *GetProfileResponseData `json:"data,omitempty"`
@@ -28,10 +109,74 @@ type GetProfileResponse struct {
 
 // GetProfileResponseData defines model for GetProfileResponseData.
 type GetProfileResponseData struct {
-	FullName    string `json:"full_name"`
+	FullName string `json:"full_name"`
+
+	// LastLoginAt Absent until the first successful login.
+	LastLoginAt            *time.Time `json:"last_login_at,omitempty"`
+	PhoneNumber            string     `json:"phone_number"`
+	PhoneVerified          bool       `json:"phone_verified"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// JsonWebKey defines model for JsonWebKey.
+type JsonWebKey struct {
+	Alg string `json:"alg"`
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	Use string `json:"use"`
+}
+
+// JsonWebKeySet defines model for JsonWebKeySet.
+type JsonWebKeySet struct {
+	Keys []JsonWebKey `json:"keys"`
+}
+
+// ListSessionsResponse defines model for ListSessionsResponse.
+type ListSessionsResponse struct {
+	Data   *ListSessionsResponseData `json:"data,omitempty"`
+	Header ResponseHeader            `json:"header"`
+}
+
+// ListSessionsResponseData defines model for ListSessionsResponseData.
+type ListSessionsResponseData struct {
+	// Sessions Most recently used first.
+	Sessions []ActiveSession `json:"sessions"`
+}
+
+// LoginEvent defines model for LoginEvent.
+type LoginEvent struct {
+	CreatedAt     time.Time         `json:"created_at"`
+	FailureReason *string           `json:"failure_reason,omitempty"`
+	IpAddress     string            `json:"ip_address"`
+	Outcome       LoginEventOutcome `json:"outcome"`
+	UserAgent     string            `json:"user_agent"`
+}
+
+// LoginEventOutcome defines model for LoginEvent.Outcome.
+type LoginEventOutcome string
+
+// LoginMfaRequest defines model for LoginMfaRequest.
+type LoginMfaRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+
+	// Code A code from the authenticator app or an unused recovery code.
+	Code string `json:"code"`
+}
+
+// LoginOtpRequest defines model for LoginOtpRequest.
+type LoginOtpRequest struct {
+	Code        string `json:"code"`
 	PhoneNumber string `json:"phone_number"`
 }
 
+// LoginPasswordChangeRequest defines model for LoginPasswordChangeRequest.
+type LoginPasswordChangeRequest struct {
+	ChangeToken string `json:"change_token"`
+	NewPassword string `json:"new_password"`
+}
+
 // LoginRequest defines model for LoginRequest.
 type LoginRequest struct {
 	Password    string `json:"password"`
@@ -40,14 +185,99 @@ type LoginRequest struct {
 
 // LoginResponse defines model for LoginResponse.
 type LoginResponse struct {
-	Data   *LoginResponseData `json:"data,omitempty"`
-	Header ResponseHeader     `json:"header"`
+	Data                   *LoginResponseData      `json:"data,omitempty"`
+	Header                 ResponseHeader          `json:"header"`
+	MfaChallenge           *MfaChallenge           `json:"mfa_challenge,omitempty"`
+	PasswordChangeRequired *PasswordChangeRequired `json:"password_change_required,omitempty"`
 }
 
 // LoginResponseData defines model for LoginResponseData.
 type LoginResponseData struct {
-	Id  int64  `json:"id"`
-	Jwt string `json:"jwt"`
+	Id int64 `json:"id"`
+
+	// Jwt Left out when cookie sessions are on, unless the request has an X-Token-Delivery header set to body.
+	Jwt *string `json:"jwt,omitempty"`
+
+	// RefreshToken Left out when cookie sessions are on, unless the request has an X-Token-Delivery header set to body.
+	RefreshToken *string `json:"refresh_token,omitempty"`
+}
+
+// LogoutResponse defines model for LogoutResponse.
+type LogoutResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// Metrics defines model for Metrics.
+type Metrics struct {
+	PasswordHashing PasswordHashingMetrics `json:"password_hashing"`
+}
+
+// MetricsResponse defines model for MetricsResponse.
+type MetricsResponse struct {
+	Data   *Metrics       `json:"data,omitempty"`
+	Header ResponseHeader `json:"header"`
+}
+
+// MfaChallenge defines model for MfaChallenge.
+type MfaChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+
+	// ExpiresIn Seconds until the challenge token expires.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// PasswordChangeRequired defines model for PasswordChangeRequired.
+type PasswordChangeRequired struct {
+	ChangeToken string `json:"change_token"`
+
+	// ExpiresIn Seconds until the change token expires.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// PasswordHashingMetrics defines model for PasswordHashingMetrics.
+type PasswordHashingMetrics struct {
+	// Canceled Requests that ended while waiting for a slot.
+	Canceled  int64 `json:"canceled"`
+	Completed int64 `json:"completed"`
+
+	// Concurrency Hashes computed at once at most, 0 when unbounded.
+	Concurrency int `json:"concurrency"`
+
+	// QueueDepth Requests that may wait for a hashing slot at most.
+	QueueDepth int `json:"queue_depth"`
+	Queued     int `json:"queued"`
+
+	// Rejected Requests turned away with 503 because the queue was full.
+	Rejected       int64   `json:"rejected"`
+	Running        int     `json:"running"`
+	WaitSecondsMax float64 `json:"wait_seconds_max"`
+
+	// WaitSecondsTotal Time completed hashes spent waiting for a slot.
+	WaitSecondsTotal float64 `json:"wait_seconds_total"`
+}
+
+// RecoveryCodesResponseData defines model for RecoveryCodesResponseData.
+type RecoveryCodesResponseData struct {
+	// RecoveryCodes One-time codes accepted instead of an authenticator app code. They are only shown once.
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// RefreshTokenRequest defines model for RefreshTokenRequest.
+type RefreshTokenRequest struct {
+	// RefreshToken Taken from the refresh_token cookie when left out and cookie sessions are on.
+	RefreshToken *string `json:"refresh_token,omitempty"`
+}
+
+// RefreshTokenResponse defines model for RefreshTokenResponse.
+type RefreshTokenResponse struct {
+	Data   *LoginResponseData `json:"data,omitempty"`
+	Header ResponseHeader     `json:"header"`
+}
+
+// RegenerateRecoveryCodesResponse defines model for RegenerateRecoveryCodesResponse.
+type RegenerateRecoveryCodesResponse struct {
+	Data   *RecoveryCodesResponseData `json:"data,omitempty"`
+	Header ResponseHeader             `json:"header"`
 }
 
 // RegistrationRequest defines model for RegistrationRequest.
@@ -68,6 +298,48 @@ type RegistrationResponseData struct {
 	Id int64 `json:"id"`
 }
 
+// RequestLoginOtpRequest defines model for RequestLoginOtpRequest.
+type RequestLoginOtpRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+// RequestLoginOtpResponse defines model for RequestLoginOtpResponse.
+type RequestLoginOtpResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// RequestPasswordResetRequest defines model for RequestPasswordResetRequest.
+type RequestPasswordResetRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+// RequestPasswordResetResponse defines model for RequestPasswordResetResponse.
+type RequestPasswordResetResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// ResendRegistrationCodeRequest defines model for ResendRegistrationCodeRequest.
+type ResendRegistrationCodeRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+// ResendRegistrationCodeResponse defines model for ResendRegistrationCodeResponse.
+type ResendRegistrationCodeResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// ResetPasswordRequest defines model for ResetPasswordRequest.
+type ResetPasswordRequest struct {
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// ResetPasswordResponse defines model for ResetPasswordResponse.
+type ResetPasswordResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
 // ResponseHeader defines model for ResponseHeader.
 type ResponseHeader struct {
 	Messages   *[]string `json:"messages,omitempty"`
@@ -75,6 +347,34 @@ type ResponseHeader struct {
 	Successful *bool     `json:"successful,omitempty"`
 }
 
+// RevokeOtherSessionsResponse defines model for RevokeOtherSessionsResponse.
+type RevokeOtherSessionsResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// RevokeSessionResponse defines model for RevokeSessionResponse.
+type RevokeSessionResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// UpdatePasswordRequest defines model for UpdatePasswordRequest.
+type UpdatePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// UpdatePasswordResponse defines model for UpdatePasswordResponse.
+type UpdatePasswordResponse struct {
+	Data   *UpdatePasswordResponseData `json:"data,omitempty"`
+	Header ResponseHeader              `json:"header"`
+}
+
+// UpdatePasswordResponseData defines model for UpdatePasswordResponseData.
+type UpdatePasswordResponseData struct {
+	// Jwt Left out when the request was authenticated by the access_token cookie, which is replaced instead.
+	Jwt *string `json:"jwt,omitempty"`
+}
+
 // UpdateProfileRequest defines model for UpdateProfileRequest.
 type UpdateProfileRequest struct {
 	FullName    *string `json:"full_name,omitempty"`
@@ -86,29 +386,163 @@ type UpdateProfileResponse struct {
 	Header ResponseHeader `json:"header"`
 }
 
+// VerifyPhoneNumberChangeRequest defines model for VerifyPhoneNumberChangeRequest.
+type VerifyPhoneNumberChangeRequest struct {
+	Code string `json:"code"`
+}
+
+// VerifyPhoneNumberChangeResponse defines model for VerifyPhoneNumberChangeResponse.
+type VerifyPhoneNumberChangeResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// VerifyRegistrationRequest defines model for VerifyRegistrationRequest.
+type VerifyRegistrationRequest struct {
+	Code        string `json:"code"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+// VerifyRegistrationResponse defines model for VerifyRegistrationResponse.
+type VerifyRegistrationResponse struct {
+	Header ResponseHeader `json:"header"`
+}
+
+// GetProfileLoginsParams defines parameters for GetProfileLogins.
+type GetProfileLoginsParams struct {
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor The next_cursor of the previous page.
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+}
+
+// AdminUnlockJSONRequestBody defines body for AdminUnlock for application/json ContentType.
+type AdminUnlockJSONRequestBody = AdminUnlockRequest
+
 // LoginJSONRequestBody defines body for Login for application/json ContentType.
 type LoginJSONRequestBody = LoginRequest
 
+// LoginMfaJSONRequestBody defines body for LoginMfa for application/json ContentType.
+type LoginMfaJSONRequestBody = LoginMfaRequest
+
+// RequestLoginOtpJSONRequestBody defines body for RequestLoginOtp for application/json ContentType.
+type RequestLoginOtpJSONRequestBody = RequestLoginOtpRequest
+
+// LoginOtpJSONRequestBody defines body for LoginOtp for application/json ContentType.
+type LoginOtpJSONRequestBody = LoginOtpRequest
+
+// LoginPasswordChangeJSONRequestBody defines body for LoginPasswordChange for application/json ContentType.
+type LoginPasswordChangeJSONRequestBody = LoginPasswordChangeRequest
+
+// RequestPasswordResetJSONRequestBody defines body for RequestPasswordReset for application/json ContentType.
+type RequestPasswordResetJSONRequestBody = RequestPasswordResetRequest
+
+// ResetPasswordJSONRequestBody defines body for ResetPassword for application/json ContentType.
+type ResetPasswordJSONRequestBody = ResetPasswordRequest
+
 // UpdateProfileJSONRequestBody defines body for UpdateProfile for application/json ContentType.
 type UpdateProfileJSONRequestBody = UpdateProfileRequest
 
+// UpdatePasswordJSONRequestBody defines body for UpdatePassword for application/json ContentType.
+type UpdatePasswordJSONRequestBody = UpdatePasswordRequest
+
+// VerifyPhoneNumberChangeJSONRequestBody defines body for VerifyPhoneNumberChange for application/json ContentType.
+type VerifyPhoneNumberChangeJSONRequestBody = VerifyPhoneNumberChangeRequest
+
+// ConfirmTotpJSONRequestBody defines body for ConfirmTotp for application/json ContentType.
+type ConfirmTotpJSONRequestBody = ConfirmTotpRequest
+
 // RegisterJSONRequestBody defines body for Register for application/json ContentType.
 type RegisterJSONRequestBody = RegistrationRequest
 
+// ResendRegistrationCodeJSONRequestBody defines body for ResendRegistrationCode for application/json ContentType.
+type ResendRegistrationCodeJSONRequestBody = ResendRegistrationCodeRequest
+
+// VerifyRegistrationJSONRequestBody defines body for VerifyRegistration for application/json ContentType.
+type VerifyRegistrationJSONRequestBody = VerifyRegistrationRequest
+
+// RefreshTokenJSONRequestBody defines body for RefreshToken for application/json ContentType.
+type RefreshTokenJSONRequestBody = RefreshTokenRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// GetJwks
+	// (GET /.well-known/jwks.json)
+	GetJwks(ctx echo.Context) error
+	// GetMetrics
+	// (GET /admin/metrics)
+	GetMetrics(ctx echo.Context) error
+	// AdminUnlock
+	// (POST /admin/unlock)
+	AdminUnlock(ctx echo.Context) error
 	// Login
 	// (POST /login)
 	Login(ctx echo.Context) error
+	// LoginMfa
+	// (POST /login/mfa)
+	LoginMfa(ctx echo.Context) error
+	// RequestLoginOtp
+	// (POST /login/otp)
+	RequestLoginOtp(ctx echo.Context) error
+	// LoginOtp
+	// (POST /login/otp/verify)
+	LoginOtp(ctx echo.Context) error
+	// LoginPasswordChange
+	// (POST /login/password-change)
+	LoginPasswordChange(ctx echo.Context) error
+	// Logout
+	// (POST /logout)
+	Logout(ctx echo.Context) error
+	// RequestPasswordReset
+	// (POST /password/reset-requests)
+	RequestPasswordReset(ctx echo.Context) error
+	// ResetPassword
+	// (POST /password/resets)
+	ResetPassword(ctx echo.Context) error
 	// GetProfile
 	// (GET /profile)
 	GetProfile(ctx echo.Context) error
 	// UpdateProfile
 	// (PATCH /profile)
 	UpdateProfile(ctx echo.Context) error
+	// GetProfileLogins
+	// (GET /profile/logins)
+	GetProfileLogins(ctx echo.Context, params GetProfileLoginsParams) error
+	// UpdatePassword
+	// (PUT /profile/password)
+	UpdatePassword(ctx echo.Context) error
+	// VerifyPhoneNumberChange
+	// (POST /profile/phone-number/verify)
+	VerifyPhoneNumberChange(ctx echo.Context) error
+	// EnrollTotp
+	// (POST /profile/totp)
+	EnrollTotp(ctx echo.Context) error
+	// ConfirmTotp
+	// (POST /profile/totp/confirm)
+	ConfirmTotp(ctx echo.Context) error
+	// RegenerateRecoveryCodes
+	// (POST /profile/totp/recovery-codes)
+	RegenerateRecoveryCodes(ctx echo.Context) error
 	// Register
 	// (POST /register)
 	Register(ctx echo.Context) error
+	// ResendRegistrationCode
+	// (POST /register/verification-codes)
+	ResendRegistrationCode(ctx echo.Context) error
+	// VerifyRegistration
+	// (POST /register/verify)
+	VerifyRegistration(ctx echo.Context) error
+	// RevokeOtherSessions
+	// (DELETE /sessions)
+	RevokeOtherSessions(ctx echo.Context) error
+	// ListSessions
+	// (GET /sessions)
+	ListSessions(ctx echo.Context) error
+	// RevokeSession
+	// (DELETE /sessions/{id})
+	RevokeSession(ctx echo.Context, id string) error
+	// RefreshToken
+	// (POST /token/refresh)
+	RefreshToken(ctx echo.Context) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -116,6 +550,37 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// GetJwks converts echo context to params.
+func (w *ServerInterfaceWrapper) GetJwks(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetJwks(ctx)
+	return err
+}
+
+// GetMetrics converts echo context to params.
+func (w *ServerInterfaceWrapper) GetMetrics(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(AdminKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetMetrics(ctx)
+	return err
+}
+
+// AdminUnlock converts echo context to params.
+func (w *ServerInterfaceWrapper) AdminUnlock(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(AdminKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.AdminUnlock(ctx)
+	return err
+}
+
 // Login converts echo context to params.
 func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	var err error
@@ -125,6 +590,71 @@ func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	return err
 }
 
+// LoginMfa converts echo context to params.
+func (w *ServerInterfaceWrapper) LoginMfa(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LoginMfa(ctx)
+	return err
+}
+
+// RequestLoginOtp converts echo context to params.
+func (w *ServerInterfaceWrapper) RequestLoginOtp(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RequestLoginOtp(ctx)
+	return err
+}
+
+// LoginOtp converts echo context to params.
+func (w *ServerInterfaceWrapper) LoginOtp(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LoginOtp(ctx)
+	return err
+}
+
+// LoginPasswordChange converts echo context to params.
+func (w *ServerInterfaceWrapper) LoginPasswordChange(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LoginPasswordChange(ctx)
+	return err
+}
+
+// Logout converts echo context to params.
+func (w *ServerInterfaceWrapper) Logout(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Logout(ctx)
+	return err
+}
+
+// RequestPasswordReset converts echo context to params.
+func (w *ServerInterfaceWrapper) RequestPasswordReset(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RequestPasswordReset(ctx)
+	return err
+}
+
+// ResetPassword converts echo context to params.
+func (w *ServerInterfaceWrapper) ResetPassword(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ResetPassword(ctx)
+	return err
+}
+
 // GetProfile converts echo context to params.
 func (w *ServerInterfaceWrapper) GetProfile(ctx echo.Context) error {
 	var err error
@@ -147,6 +677,88 @@ func (w *ServerInterfaceWrapper) UpdateProfile(ctx echo.Context) error {
 	return err
 }
 
+// GetProfileLogins converts echo context to params.
+func (w *ServerInterfaceWrapper) GetProfileLogins(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetProfileLoginsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetProfileLogins(ctx, params)
+	return err
+}
+
+// UpdatePassword converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdatePassword(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UpdatePassword(ctx)
+	return err
+}
+
+// VerifyPhoneNumberChange converts echo context to params.
+func (w *ServerInterfaceWrapper) VerifyPhoneNumberChange(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.VerifyPhoneNumberChange(ctx)
+	return err
+}
+
+// EnrollTotp converts echo context to params.
+func (w *ServerInterfaceWrapper) EnrollTotp(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.EnrollTotp(ctx)
+	return err
+}
+
+// ConfirmTotp converts echo context to params.
+func (w *ServerInterfaceWrapper) ConfirmTotp(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ConfirmTotp(ctx)
+	return err
+}
+
+// RegenerateRecoveryCodes converts echo context to params.
+func (w *ServerInterfaceWrapper) RegenerateRecoveryCodes(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RegenerateRecoveryCodes(ctx)
+	return err
+}
+
 // Register converts echo context to params.
 func (w *ServerInterfaceWrapper) Register(ctx echo.Context) error {
 	var err error
@@ -156,6 +768,73 @@ func (w *ServerInterfaceWrapper) Register(ctx echo.Context) error {
 	return err
 }
 
+// ResendRegistrationCode converts echo context to params.
+func (w *ServerInterfaceWrapper) ResendRegistrationCode(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ResendRegistrationCode(ctx)
+	return err
+}
+
+// VerifyRegistration converts echo context to params.
+func (w *ServerInterfaceWrapper) VerifyRegistration(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.VerifyRegistration(ctx)
+	return err
+}
+
+// RevokeOtherSessions converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeOtherSessions(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RevokeOtherSessions(ctx)
+	return err
+}
+
+// ListSessions converts echo context to params.
+func (w *ServerInterfaceWrapper) ListSessions(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListSessions(ctx)
+	return err
+}
+
+// RevokeSession converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeSession(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RevokeSession(ctx, id)
+	return err
+}
+
+// RefreshToken converts echo context to params.
+func (w *ServerInterfaceWrapper) RefreshToken(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RefreshToken(ctx)
+	return err
+}
+
 // This is a simple interface which specifies echo.Route addition functions which
 // are present on both echo.Echo and echo.Group, since we want to allow using
 // either of them for path registration
@@ -184,27 +863,84 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/.well-known/jwks.json", wrapper.GetJwks)
+	router.GET(baseURL+"/admin/metrics", wrapper.GetMetrics)
+	router.POST(baseURL+"/admin/unlock", wrapper.AdminUnlock)
 	router.POST(baseURL+"/login", wrapper.Login)
+	router.POST(baseURL+"/login/mfa", wrapper.LoginMfa)
+	router.POST(baseURL+"/login/otp", wrapper.RequestLoginOtp)
+	router.POST(baseURL+"/login/otp/verify", wrapper.LoginOtp)
+	router.POST(baseURL+"/login/password-change", wrapper.LoginPasswordChange)
+	router.POST(baseURL+"/logout", wrapper.Logout)
+	router.POST(baseURL+"/password/reset-requests", wrapper.RequestPasswordReset)
+	router.POST(baseURL+"/password/resets", wrapper.ResetPassword)
 	router.GET(baseURL+"/profile", wrapper.GetProfile)
 	router.PATCH(baseURL+"/profile", wrapper.UpdateProfile)
+	router.GET(baseURL+"/profile/logins", wrapper.GetProfileLogins)
+	router.PUT(baseURL+"/profile/password", wrapper.UpdatePassword)
+	router.POST(baseURL+"/profile/phone-number/verify", wrapper.VerifyPhoneNumberChange)
+	router.POST(baseURL+"/profile/totp", wrapper.EnrollTotp)
+	router.POST(baseURL+"/profile/totp/confirm", wrapper.ConfirmTotp)
+	router.POST(baseURL+"/profile/totp/recovery-codes", wrapper.RegenerateRecoveryCodes)
 	router.POST(baseURL+"/register", wrapper.Register)
+	router.POST(baseURL+"/register/verification-codes", wrapper.ResendRegistrationCode)
+	router.POST(baseURL+"/register/verify", wrapper.VerifyRegistration)
+	router.DELETE(baseURL+"/sessions", wrapper.RevokeOtherSessions)
+	router.GET(baseURL+"/sessions", wrapper.ListSessions)
+	router.DELETE(baseURL+"/sessions/:id", wrapper.RevokeSession)
+	router.POST(baseURL+"/token/refresh", wrapper.RefreshToken)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/7xWTW/bMAz9KwO3Y1BnH9jBtxUDtgIbMGTrqQgKVWZsFbakUlSLoPB/HyQ7H46VZmub",
-	"nFqr1OPj4yPVR5CmsUajZgf5IzhZYSPir9+Qf5FZqBpn6KzRDsOpJWORWGGMKQSL8PMd4QJyeJtt0LIe",
-	"KhvjfA232glUKAqkQ/dXt7530W07AcI7rwgLyK9WIPMJ8NIi5GBublFywN+TeVTFwtf1tRZNLLBHcUxK",
-	"lwHFVkbjtfbNTcd1J2CHzgZr52aK4A9TKj3DO4+Ox7SscO7BUPEKrAbRkw3yE6Re0vIBxKm6PU464q6i",
-	"lgtDjWDIQWn+/AnWUEozliHnBG4f+LCoqoAuMsVmhqVyTIKV2d/hA8Y7Wv8HJn3KC8MqXmKJFNKpnLE3",
-	"93MNMjZCOu2A8ChZg86Jsk/M2Lhko/sDQSSW4duxYO+upSm2XbPlXeelROcWvt76+40xNQodqY+IXtpC",
-	"MK5X5fPMetCPh9Luc9dx3REClV6YgF8riT2Frk74efEn9kBxHT4vHdKb30j3SobRuUdyymjI4f3Z9Gwa",
-	"Io1FLayCHD7GozBfXMU6sjosqFif6fQNVUZTXhSQd/sLOtro+NwUyxAkjWbUMV5YWysZb2S3zujNg/2P",
-	"C7nrbDsUh8ljPOgEjFw/TKevnbtvb9vG9M43jaDluuxwltnOCgGwxIRCm/ccjsg38X/PijRKT4qXkF89",
-	"wjkKQvriuYL8at7Ot2vaIhr3OMtqXM3A/kfqe3KyT9z/9Jj/r6RDtaJdKK71frMmR2q2ijiOuqk3/sTi",
-	"Jh/o0Yytdeg1p7C5ouSeasihYrZ5ltVGiroKSrbz9m8AAAD//0C5ePEaDAAA",
+	"H4sIAAAAAAAC/9Rc247bONJ+FUL/fym3e3Z298J3mWSwk5l0ku10dgYIGgYtlSzGEqnw0I4R+N0XpChb",
+	"EqmD25GdvUrb5qHqqwOrisV8CyKWF4wClSJYfAtElEKOzZ8vIkme4AMIQRjVXxScFcAlAfNzxAFLiJdY",
+	"6k8J47n+K4ixhJkkOQRhIHcFBItASE7oOtiHQaQ4B2omxCAiTgpp1g7+TEGmwJFMiUBEIJkCEuXOKMcb",
+	"QtfmKw5fFAh5c1x7xVgGmOrFY3giESwpzsHd4CEF9NfslRkye4tzQCngGDgSQCXCEmVsTWiISIIw3d34",
+	"iCexXtb9uljiOOYghH9X+2ODpS0WKMNCIgFAUcJZ7t1RD1nqISeBrATwJV5bnFs/78NAg0g4xMHik+ap",
+	"MaHBTliXcYuaoywfDySw1WeIpCbhRZwT+pFmLNrclyJz9aeJm8NFkTIKS6ryFXA/H/27ioJRAe62pdj1",
+	"X//PIQkWwf/NjxYwt+o/r+b/Vo5uw2YX8bH+ktGE8PyByaKT9YjFMCwbM2pwiy4+YyzxMJcRewK+e8li",
+	"ENVSr/TEfTg5UL9SzrLsfCbcda7HwStLb5MLAREH6dVyxYnrNZgssJLpYj5HH+9fI5ZY36FXCZFkaAVI",
+	"pGxLERYIo3/fI60qHg/SYsXSUe7qY+hfIN9zlpAM3mhvKM4TTNdqlxJP7/4OR8b/e1z4W9iCkCghvDx3",
+	"iIRcDBFtNvz1CaghxFKGOcc7/ZnCV7mMFBeMu/u9WJkTiVEjdHNIFHg9QrqWgX4kvpdEryfLfikmKssO",
+	"EYD/PDU42QPVC72ikmQGfSN0JFQUgRCJysogQYti3Ek8cIZVA56Ak4RAPbyohTXcOumlNnKx5JBjQvX8",
+	"42hCJaw9gB7RaNHi7NyzjU8cvwtG/4TVH7BzRYCztZdXv0g2HVHVRu6831Pvt0qMOE/1kuXQ0BBZbq6X",
+	"1MT1s/kBPMf4Bnbm31Eu4biW6xLahOp1ffS8IULaePxM3+xb6VK23Lm35+AsR7mmeseERBwioDLbISUg",
+	"PtFDN5ObIYkcCPHyc3T23yVJSjDJFIclBywYHZFzOD8zJSNW+kCgKjcclE4sOCyvA3oWbYzt5wleHtgN",
+	"gwILsWU8XkYppms4/vR4ZrbRSCcaiUYjBanI7wT7LsHdsXWKsww02ZJtwA9fFX+33L8Jo0w2Zry/jsGA",
+	"ShJhyTjCRYH0PxQpavStcpgjg682XWF3fG94fHd6/jAmaaqT1DoS+ul5b5XipdGJPvj7saewXVYKNkJj",
+	"6su1JneS2klcz75nYjeGqLP8dX2J8xx1ae4HdRyafpfgl4ex+x7nMLCOqz9m1innhoOBW1OIG26WUPnP",
+	"vwehEyaFweetJ/57A4lETEm0TYGiiLENOZRsBMIcEKMhUjSryjm2HIVSnYdR9NfsQevp7BVkxHiGQ4lJ",
+	"mqyNxf7CEoeEg0iPRvMjkOUWijpkwpS8XrXlDiQnkeg29WWKRWqj5THq+Vs5vFrXMfv2sj1EnWfxBwqm",
+	"D8gaJv6sAxW+FoSDWBKP+n6AiNFY1NKqw4rIrIjs7BuPpQ4eorWdfax1eJ3Tj62TOXwme8/graWyLm+Y",
+	"RpBB7BJuD0rtNbBEQGOI0TYlGaAtJlIX3RMd8iCRMdnIersdq1bMDCSMdcQRo2UhOdq59GnOQCC9ppIQ",
+	"6zI9oxHof3MmZIhuS5+o6IopTf2Nd48vChQsYyhkOoRBjneGd8u4tXIDQLVrzx6xLyfXItYi6xeA4lQz",
+	"uNX7E5mif9z+jFYQYSXAaJRZ39wb6KR+pDC4ol2VgjDQbC5FqbrLHH9tJihMrbJadmIDnfY0ySTOPNce",
+	"JAd00ASDIggkCqBySLO6NnZq40e1aQr4yPVBKHWtrEkjPFqGly0PRD4z7C6iO5bYrLG4uL2jZVZoUgqB",
+	"cBRBoQEkVEjAsa4EY+rJS0wGgh5S2NlwINvZArE2l0ZO7Di23ry3RbCffRO8mBCjM/AeiHAe8MZeg9n4",
+	"pTa6CniMoWdVKIRp3BEJ+UOZAbJ/kNB8/JF9D2ugwLEEr/r9D14H3cOaCMmxVolOPeov706X3jUKqX2p",
+	"XpOL84ThrnQdWXyHhGtcMmHFPlj+OEOUo7a9VjJjCaniunsQIC8MQmvv6yEhgMZ1NdQ+6XJY+He/Jho1",
+	"yZxaFByouH2PquGIylyLiStiWZ/p7J+DEHgNzXudgaApDITEUollSwS1YPt4h+i75PMHKE9sA+9kCnz4",
+	"ymd61DQxlo7rkfGxiLGEYUsoO5P6lf7EOnR7yREa36b2nGjAv9al4oGe3R1uRlRW6zVKndHWEhqI0Wpn",
+	"BmBjMI0cINTFiSjV/YEcigxHx9RoZNRvGak6CZ4XZZ7emNba9lrm8x992797r+l/a8gfuss5r02tc7vr",
+	"8j8q0bjwDZuPsuugpAcSmjC9fkYisCSU1hDcvX4wxx+Rmf74UQBHH4DrptogDJ6Al63CwU83tze3eiQr",
+	"gOKCBIvgZ/OVTp9kaviY32why2YbyrZ0/nm7ETef7XX3umy20EwbLF7HwUJ3AP2+3QhTwClZM6v87fa2",
+	"lBeV9goaF0WmXQlhdF6tWKIyvkVDt3vs9wYMofIc812NAv3tHOte03l+rLl2EV2VZSeku33fUFEOkeJE",
+	"7oLFp29lb+wfsHuhZBosPj3uH1us1W88LHfKtNIaDWTCw12t3zYo1QuE/IXFu+/GmaePeN9UZckV7CfE",
+	"1tdTfDq+daQMwKaRrBtZk4tOhGnjivzCaDZvwh0LMz/XAJrnCR4A6S7BU+JU6zT5AaHSzNfQYrLoRqtV",
+	"5ZgItI4SzoWx66roOCi2BrbAnJv2yN2ABk4H5pVRHKOBLdCqfGhW3mQOINe8nJ0SRH/z0g+IZwuSClqm",
+	"ZC+W+vdpKa93efjOv18Ac+D+088SaJipNGTOQYCcWYmLQb/VKExO67y89dfreDB/ObbLjTVG++DuhblW",
+	"npsMX08d8+LA+sqQHkRrwyyUZfreF+vbDH9KU/Q85TjVHGuEmkszGaUuN42SxUT64K3GXFgf/KWZUyFt",
+	"olVXl/nxXdGA1pSPlEyGzHEOErgw+xIaLIIvCvguCKs8PCM5kUFY4zHHX0muW71/ur0Ng5xQ+8l3F+d7",
+	"pVp7k1Q9OCs4PBGmxOH9kY+SckqDlHZJ5PEi5tB6sfZ8o7ByaAixXigulOw0l2n9p7/8fR2D6fKgJ1qM",
+	"z8POTdVsVlbNBmPwjirjRCIYKKFeWBZDFdZThdKFZUM6sjfNPL6JnfIU9LwdPpXXGqEOe/OofGHdzWbt",
+	"CfZEmuZ5R35h7fI9Mz8V5TpOLsxVe9ns0A/XFZx6u52CSePE/garU4HoYsGAwk3hH3gvAuWIqSJz907k",
+	"4oG55/LDE5dbHBq4lYeE3XdYmXzdHROmPN2NLFfIfXr6WrxJkDveB/3g+VxfZNKj+QfQ5J7LPAdid2wJ",
+	"b/PlawYSfM3juilDIDCPeuwMtFLSRO+MQsf/nNM2B6fPZFq/2t3WcrpPdUnfh/48q/7oeNJSme+J9skF",
+	"szqxDX2YfyPxvqkUPnHayR3JpL6CPWZwJA7a1nGtbM7fZvQ8taggMPCZLpK57SvvOxqOTeGTHQhuu/zF",
+	"jwFP67vH+R9HlRQK4E+VHimeBYsglbJYzOcZi3CWakT3j/v/DgC9bcfZUUwAAA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/generated"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	accessTokenCookie  = "access_token"
+	refreshTokenCookie = "refresh_token"
+	csrfTokenCookie    = "csrf_token"
+	csrfTokenHeader    = "X-CSRF-Token"
+	csrfTokenSize      = 32
+
+	// tokenDeliveryHeader lets clients that are not browsers ask for the
+	// tokens in the response body when cookie sessions are on.
+	tokenDeliveryHeader = "X-Token-Delivery"
+	tokenDeliveryBody   = "body"
+
+	// refreshTokenCookiePath keeps browsers from sending the refresh token
+	// anywhere but to the refresh endpoint.
+	refreshTokenCookiePath = "/token/refresh"
+)
+
+var errInvalidCSRFToken = errors.New("Invalid CSRF token")
+
+// setSessionCookies hands the tokens of a login or a refresh to the browser
+// in HttpOnly cookies, out of reach of scripts, together with a new CSRF
+// token that scripts can read and have to send back in the X-CSRF-Token
+// header. The access token cookie expires with the token it carries.
+func (s *Server) setSessionCookies(ctx echo.Context, accessToken string, refreshToken string) error {
+	csrfToken, err := generateRandomString(csrfTokenSize)
+	if err != nil {
+		return err
+	}
+
+	s.setAccessTokenCookie(ctx, accessToken)
+	ctx.SetCookie(newSessionCookie(refreshTokenCookie, refreshToken, refreshTokenCookiePath, time.Now().Add(refreshExpirationDuration), true))
+	ctx.SetCookie(newSessionCookie(csrfTokenCookie, csrfToken, "/", time.Now().Add(refreshExpirationDuration), false))
+	return nil
+}
+
+// setAccessTokenCookie replaces the access token cookie alone, for a new
+// access token in the same session.
+func (s *Server) setAccessTokenCookie(ctx echo.Context, accessToken string) {
+	accessTokenDuration := s.Sessions.withDefaults().accessTokenDuration()
+	ctx.SetCookie(newSessionCookie(accessTokenCookie, accessToken, "/", time.Now().Add(accessTokenDuration), true))
+}
+
+// loginResponseData returns the tokens of a login or a refresh. With cookie
+// sessions they are only in the body when the client asks for them with the
+// X-Token-Delivery header, and never for a refresh made with the refresh
+// token cookie, so that scripts reading the response do not get what the
+// HttpOnly cookies keep from them.
+func (s *Server) loginResponseData(ctx echo.Context, userID int64, accessToken string, refreshToken string, fromCookie bool) *generated.LoginResponseData {
+	data := &generated.LoginResponseData{
+		Id: userID,
+	}
+	if s.CookieSessions && (fromCookie || ctx.Request().Header.Get(tokenDeliveryHeader) != tokenDeliveryBody) {
+		return data
+	}
+	data.Jwt = &accessToken
+	data.RefreshToken = &refreshToken
+	return data
+}
+
+// clearSessionCookies asks the browser to drop the cookies set by
+// setSessionCookies.
+func clearSessionCookies(ctx echo.Context) {
+	for _, cookie := range []*http.Cookie{
+		newSessionCookie(accessTokenCookie, "", "/", time.Unix(0, 0), true),
+		newSessionCookie(refreshTokenCookie, "", refreshTokenCookiePath, time.Unix(0, 0), true),
+		newSessionCookie(csrfTokenCookie, "", "/", time.Unix(0, 0), false),
+	} {
+		cookie.MaxAge = -1
+		ctx.SetCookie(cookie)
+	}
+}
+
+func newSessionCookie(name string, value string, path string, expires time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// cookieValue returns the value of the cookie, or an empty string when the
+// request has none or cookie sessions are off.
+func (s *Server) cookieValue(ctx echo.Context, name string) string {
+	if !s.CookieSessions {
+		return ""
+	}
+	cookie, err := ctx.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// checkCSRFToken guards requests authenticated by a cookie. Browsers attach
+// cookies to requests made by other sites too, but those sites cannot read
+// the CSRF token cookie to repeat it in the header. Safe methods are not
+// checked since they change nothing.
+func checkCSRFToken(ctx echo.Context) error {
+	switch ctx.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	cookie, err := ctx.Cookie(csrfTokenCookie)
+	if err != nil || cookie.Value == "" {
+		return errInvalidCSRFToken
+	}
+	header := ctx.Request().Header.Get(csrfTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+		return errInvalidCSRFToken
+	}
+	return nil
+}
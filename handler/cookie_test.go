@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func Test_checkCSRFToken(t *testing.T) {
+	newContext := func(method string, cookie string, header string) echo.Context {
+		req, _ := http.NewRequest(method, "url", nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: csrfTokenCookie, Value: cookie})
+		}
+		if header != "" {
+			req.Header.Set(csrfTokenHeader, header)
+		}
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+	tests := []struct {
+		name      string
+		ctx       echo.Context
+		detailErr error
+	}{
+		{
+			name:      "safe method",
+			ctx:       newContext(http.MethodGet, "", ""),
+			detailErr: nil,
+		},
+		{
+			name:      "no cookie",
+			ctx:       newContext(http.MethodPatch, "", "some-token"),
+			detailErr: errInvalidCSRFToken,
+		},
+		{
+			name:      "no header",
+			ctx:       newContext(http.MethodPatch, "some-token", ""),
+			detailErr: errInvalidCSRFToken,
+		},
+		{
+			name:      "different header",
+			ctx:       newContext(http.MethodPatch, "some-token", "other-token"),
+			detailErr: errInvalidCSRFToken,
+		},
+		{
+			name:      "passed",
+			ctx:       newContext(http.MethodPatch, "some-token", "some-token"),
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCSRFToken(tt.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When checkCSRFToken() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_setSessionCookies(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "url", nil)
+	res := httptest.NewRecorder()
+	s := &Server{
+		Sessions: SessionOptions{IdleTimeout: 5 * time.Minute},
+	}
+	err := s.setSessionCookies(echo.New().NewContext(req, res), "<jwt>", "<refresh token>")
+	if err != nil {
+		t.Errorf("Error When setSessionCookies() %s", err.Error())
+	}
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range res.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	for name, want := range map[string]struct {
+		value    string
+		path     string
+		httpOnly bool
+	}{
+		accessTokenCookie:  {value: "<jwt>", path: "/", httpOnly: true},
+		refreshTokenCookie: {value: "<refresh token>", path: refreshTokenCookiePath, httpOnly: true},
+		csrfTokenCookie:    {path: "/", httpOnly: false},
+	} {
+		cookie, found := cookies[name]
+		if !found {
+			t.Errorf("Result When setSessionCookies() no %s cookie", name)
+			continue
+		}
+		if (want.value != "" && cookie.Value != want.value) || cookie.Value == "" || cookie.Path != want.path ||
+			cookie.HttpOnly != want.httpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode {
+			t.Errorf("Result When setSessionCookies() %s cookie = %+v", name, cookie)
+		}
+	}
+	// The access token cookie must not outlive the token it carries.
+	if cookie, found := cookies[accessTokenCookie]; found && cookie.Expires.After(time.Now().Add(5*time.Minute)) {
+		t.Errorf("Result When setSessionCookies() access token cookie expires = %s", cookie.Expires)
+	}
+}
+
+func Test_loginResponseData(t *testing.T) {
+	newContext := func(tokenDelivery string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", nil)
+		if tokenDelivery != "" {
+			req.Header.Set(tokenDeliveryHeader, tokenDelivery)
+		}
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+	tests := []struct {
+		name           string
+		cookieSessions bool
+		ctx            echo.Context
+		fromCookie     bool
+		withTokens     bool
+	}{
+		{
+			name:           "cookie sessions off",
+			cookieSessions: false,
+			ctx:            newContext(""),
+			withTokens:     true,
+		},
+		{
+			name:           "cookie sessions on",
+			cookieSessions: true,
+			ctx:            newContext(""),
+			withTokens:     false,
+		},
+		{
+			name:           "tokens asked for in the body",
+			cookieSessions: true,
+			ctx:            newContext(tokenDeliveryBody),
+			withTokens:     true,
+		},
+		{
+			name:           "refresh made with the cookie",
+			cookieSessions: true,
+			ctx:            newContext(tokenDeliveryBody),
+			fromCookie:     true,
+			withTokens:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				CookieSessions: tt.cookieSessions,
+			}
+			res := s.loginResponseData(tt.ctx, 1, "<jwt>", "<refresh token>", tt.fromCookie)
+			if res.Id != 1 || (res.Jwt != nil) != tt.withTokens || (res.RefreshToken != nil) != tt.withTokens {
+				t.Errorf("Result When loginResponseData() %+v, withTokens = %v", res, tt.withTokens)
+			}
+		})
+	}
+}
+
+func Test_getSessionClaims_cookie(t *testing.T) {
+	jwt, _ := (&Server{}).generateToken(repository.User{
+		ID:          1,
+		PhoneNumber: "+62821232342",
+	}, "some-session")
+	newContext := func(method string, csrfToken string) echo.Context {
+		req, _ := http.NewRequest(method, "url", strings.NewReader("{}"))
+		req.AddCookie(&http.Cookie{Name: accessTokenCookie, Value: jwt})
+		req.AddCookie(&http.Cookie{Name: csrfTokenCookie, Value: "some-token"})
+		if csrfToken != "" {
+			req.Header.Set(csrfTokenHeader, csrfToken)
+		}
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		cookieSessions bool
+		ctx            echo.Context
+		mock           func(fields *fields)
+		detailErr      string
+	}{
+		{
+			name: "cookie sessions off",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			cookieSessions: false,
+			ctx:            newContext(http.MethodGet, ""),
+			mock:           func(fields *fields) {},
+			detailErr:      "Unauthorized",
+		},
+		{
+			name: "no CSRF token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			cookieSessions: true,
+			ctx:            newContext(http.MethodPatch, ""),
+			mock:           func(fields *fields) {},
+			detailErr:      errInvalidCSRFToken.Error(),
+		},
+		{
+			name: "safe method",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			cookieSessions: true,
+			ctx:            newContext(http.MethodGet, ""),
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			detailErr: "",
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			cookieSessions: true,
+			ctx:            newContext(http.MethodPatch, "some-token"),
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			detailErr: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository:     tt.fields.Repository,
+				CookieSessions: tt.cookieSessions,
+			}
+			tt.mock(&tt.fields)
+			res, err := s.getSessionClaims(tt.ctx)
+			if utilsHelper.ErrorMessage(err) != tt.detailErr {
+				t.Errorf("Error When getSessionClaims() %s, detailErr = %s", utilsHelper.ErrorMessage(err), tt.detailErr)
+			}
+			if err == nil && res.UserID != 1 {
+				t.Errorf("Result When getSessionClaims() %+v", res)
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
@@ -2,10 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
 	"github.com/Richthonio10/requirement-swtpro/generated"
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
+	"github.com/Richthonio10/requirement-swtpro/pkg/totp"
 	"github.com/Richthonio10/requirement-swtpro/repository"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
@@ -19,11 +24,22 @@ func (s *Server) Login(ctx echo.Context) error {
 
 	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
 	if err != nil {
-		log.Errorf("Error When Decode Request: %s with request: %s", err.Error(), request)
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
 		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
+	retryAfter, lockoutMessages, err := s.checkLoginLockout(ctx.Request().Context(), request.PhoneNumber, ctx.RealIP())
+	if err != nil {
+		log.Errorf("Error When checkLoginLockout: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if len(lockoutMessages) > 0 {
+		return s.rejectLockedLogin(ctx, 0, request.PhoneNumber, retryAfter, lockoutMessages)
+	}
+
 	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
 	if err != nil {
 		log.Errorf("Error When GetUserByPhoneNumber: %s with phone number: %s", err.Error(), request.PhoneNumber)
@@ -32,195 +48,1747 @@ func (s *Server) Login(ctx echo.Context) error {
 	}
 
 	if user.ID == 0 {
-		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is not found"}, false)
-		return ctx.JSON(http.StatusBadRequest, response)
+		err = s.compareDummyPassword(ctx.Request().Context(), request.Password)
+		if err != nil {
+			log.Errorf("Error When compareDummyPassword: %s with phone number: %s", err.Error(), request.PhoneNumber)
+			statusCode, header := hashingFailure(ctx, err)
+			response.Header = header
+			return ctx.JSON(statusCode, response)
+		}
+		return s.rejectLogin(ctx, 0, request.PhoneNumber, loginFailurePhoneNumberNotFound)
 	}
 
-	if !comparePasswords(user.Password, request.Password) {
-		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Wrong password"}, false)
-		return ctx.JSON(http.StatusBadRequest, response)
+	ok, err := s.comparePasswords(ctx.Request().Context(), user.Password, request.Password)
+	if err != nil {
+		log.Errorf("Error When comparePasswords: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+	if !ok {
+		return s.rejectLogin(ctx, user.ID, request.PhoneNumber, loginFailureWrongPassword)
+	}
+
+	s.rehashPassword(ctx.Request().Context(), user, request.Password)
+
+	return s.continueLogin(ctx, user)
+}
+
+// continueLogin takes a login on once the user proved the first factor,
+// either the password or a code sent to the phone number: it asks for the
+// second factor when two-factor authentication is enabled and starts the
+// session otherwise.
+func (s *Server) continueLogin(ctx echo.Context, user repository.User) error {
+	var response generated.LoginResponse
+
+	if user.PhoneVerifiedAt.IsZero() && !s.AllowUnverifiedLogin {
+		err := s.recordLoginEvent(ctx, user.ID, user.PhoneNumber, repository.LoginOutcomeFailure, "Phone number is not verified")
+		if err != nil {
+			log.Errorf("Error When recordLoginEvent: %s with user id: %d", err.Error(), user.ID)
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Phone number is not verified"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
 	}
 
-	jwtToken, err := generateToken(user)
+	totpSecret, err := s.Repository.GetTOTPSecret(ctx.Request().Context(), user.ID)
 	if err != nil {
-		log.Errorf("Error When generateToken: %s with data user: %s", err.Error(), user)
+		log.Errorf("Error When GetTOTPSecret: %s with user id: %d", err.Error(), user.ID)
 		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	err = s.Repository.CreateLoginCount(ctx.Request().Context(), user.ID)
+	// With two-factor authentication the failed logins are only reset once
+	// the second step succeeds, so that the lockout also covers guessing
+	// codes.
+	if !totpSecret.ConfirmedAt.IsZero() {
+		challengeToken, err := s.generateMFAChallengeToken(user)
+		if err != nil {
+			log.Errorf("Error When generateMFAChallengeToken: %s with user id: %d", err.Error(), user.ID)
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+
+		err = s.recordLoginEvent(ctx, user.ID, user.PhoneNumber, repository.LoginOutcomeMFARequired, "")
+		if err != nil {
+			log.Errorf("Error When recordLoginEvent: %s with user id: %d", err.Error(), user.ID)
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+
+		response.Header = createResponseHeader(200, []string{"Two-factor authentication code required"}, true)
+		response.MfaChallenge = &generated.MfaChallenge{
+			ChallengeToken: challengeToken,
+			ExpiresIn:      int(mfaChallengeDuration.Seconds()),
+		}
+		return ctx.JSON(http.StatusOK, response)
+	}
+
+	err = s.resetLoginFailures(ctx.Request().Context(), user.PhoneNumber)
 	if err != nil {
-		log.Errorf("Error When CreateLoginCount: %s with user id: %s", err.Error(), user.ID)
+		log.Errorf("Error When resetLoginFailures: %s with phone number: %s", err.Error(), user.PhoneNumber)
 		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	response.Header = createResponseHeader(200, []string{"Successfully Login!"}, true)
-	response.Data = &generated.LoginResponseData{
-		Id:  user.ID,
-		Jwt: jwtToken,
-	}
-
-	return ctx.JSON(http.StatusOK, response)
+	return s.completeLogin(ctx, user)
 }
 
-func (s *Server) Register(ctx echo.Context) error {
+// RequestLoginOtp sends a login code to the phone number, for users who log
+// in without their password.
+func (s *Server) RequestLoginOtp(ctx echo.Context) error {
 	var (
-		request  generated.RegistrationRequest
-		response generated.RegistrationResponse
+		request  generated.RequestLoginOtpRequest
+		response generated.RequestLoginOtpResponse
 	)
 
 	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
 	if err != nil {
-		log.Errorf("Error When Decode Request: %s with request: %s", err.Error(), request)
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
 		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	requestValidationErrors := validateRegistration(request)
-	if len(requestValidationErrors) != 0 {
-		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, requestValidationErrors, false)
+	if errorMessages := checkPhoneNumber(request.PhoneNumber); len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	phoneNumber, err := ValidatePhoneNumber(ctx.Request().Context(), s, request.PhoneNumber)
+	retryAfter, lockoutMessages, err := s.checkLoginLockout(ctx.Request().Context(), request.PhoneNumber, ctx.RealIP())
 	if err != nil {
-		log.Errorf("Error when ValidatePhoneNumber: %s with phoneNumber: %s", err.Error(), request.PhoneNumber)
+		log.Errorf("Error When checkLoginLockout: %s with phone number: %s", err.Error(), request.PhoneNumber)
 		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
-	if !phoneNumber {
-		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is already registered"}, false)
+
+	if len(lockoutMessages) > 0 {
+		ctx.Response().Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(retryAfter), 10))
+		response.Header = createResponseHeader(utilsHelper.TooManyRequestsErrorCode, lockoutMessages, false)
+		return ctx.JSON(http.StatusTooManyRequests, response)
+	}
+
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When GetUserByPhoneNumber: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// As for password resets, the answer does not tell whether the phone
+	// number is registered, nor whether it was sent too many codes already.
+	if user.ID != 0 {
+		err = s.sendOneTimeCode(ctx.Request().Context(), user.ID, repository.OneTimeCodePurposeLogin, user.PhoneNumber, loginCodeMessage)
+		if err == errTooManyOneTimeCodes {
+			log.Warnf("Too many login codes requested for user id: %d", user.ID)
+		} else if err != nil {
+			log.Errorf("Error When sendOneTimeCode: %s with user id: %d", err.Error(), user.ID)
+			statusCode, header := hashingFailure(ctx, err)
+			response.Header = header
+			return ctx.JSON(statusCode, response)
+		}
+	}
+
+	response.Header = createResponseHeader(200, []string{"If the phone number is registered, a login code has been sent to it"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// LoginOtp logs in with a code sent by RequestLoginOtp instead of the
+// password. Wrong codes count as failed logins, on top of the attempts
+// allowed per code.
+func (s *Server) LoginOtp(ctx echo.Context) error {
+	var (
+		request  generated.LoginOtpRequest
+		response generated.LoginResponse
+	)
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	hashedPassword, err := createHashPassword(request.Password)
+	retryAfter, lockoutMessages, err := s.checkLoginLockout(ctx.Request().Context(), request.PhoneNumber, ctx.RealIP())
 	if err != nil {
-		log.Errorf("Error when createHashPassword: %s", err.Error())
-		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"error when hashing password"}, false)
+		log.Errorf("Error When checkLoginLockout: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	id, err := s.Repository.InsertUser(ctx.Request().Context(), repository.User{
-		PhoneNumber: request.PhoneNumber,
-		Password:    hashedPassword,
-		FullName:    request.FullName,
-	})
+	if len(lockoutMessages) > 0 {
+		return s.rejectLockedLogin(ctx, 0, request.PhoneNumber, retryAfter, lockoutMessages)
+	}
+
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
 	if err != nil {
-		log.Errorf("Error when InsertUser: %s", err.Error())
-		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error when register"}, false)
+		log.Errorf("Error When GetUserByPhoneNumber: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	response.Header = createResponseHeader(200, []string{"Successfully Create User Register!"}, true)
-	response.Data = &generated.RegistrationResponseData{
-		Id: id,
+	if user.ID == 0 {
+		return s.rejectLogin(ctx, 0, request.PhoneNumber, errInvalidOneTimeCode.Error())
 	}
 
-	return ctx.JSON(http.StatusOK, response)
+	_, err = s.verifyOneTimeCode(ctx.Request().Context(), user.ID, repository.OneTimeCodePurposeLogin, request.Code)
+	if err == errInvalidOneTimeCode {
+		return s.rejectLogin(ctx, user.ID, user.PhoneNumber, err.Error())
+	}
+	if err != nil {
+		log.Errorf("Error When verifyOneTimeCode: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	return s.continueLogin(ctx, user)
 }
 
-func (s *Server) GetProfile(ctx echo.Context) error {
+// LoginMfa is the second step of a login for users with two-factor
+// authentication: it trades the challenge token returned by Login and a code
+// from the authenticator app for the session tokens.
+func (s *Server) LoginMfa(ctx echo.Context) error {
 	var (
-		response generated.GetProfileResponse
+		request  generated.LoginMfaRequest
+		response generated.LoginResponse
 	)
 
-	sessionClaims, err := getSessionClaims(ctx)
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	challenge, err := s.parseMFAChallengeToken(request.ChallengeToken)
 	if err != nil {
 		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
 		return ctx.JSON(http.StatusForbidden, response)
 	}
 
-	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), challenge.UserID)
 	if err != nil {
-		log.Errorf("Error When GetUserByID: %s", err.Error())
+		log.Errorf("Error When GetUserByID: %s with user id: %d", err.Error(), challenge.UserID)
 		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	response.Header = createResponseHeader(200, []string{"Successfully Get User Profile!"}, true)
-	response.Data = &generated.GetProfileResponseData{
-		FullName:    user.FullName,
-		PhoneNumber: user.PhoneNumber,
+	if user.ID == 0 {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{errInvalidMFAChallenge.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
 	}
 
-	return ctx.JSON(http.StatusOK, response)
+	retryAfter, lockoutMessages, err := s.checkLoginLockout(ctx.Request().Context(), user.PhoneNumber, ctx.RealIP())
+	if err != nil {
+		log.Errorf("Error When checkLoginLockout: %s with phone number: %s", err.Error(), user.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if len(lockoutMessages) > 0 {
+		return s.rejectLockedLogin(ctx, user.ID, user.PhoneNumber, retryAfter, lockoutMessages)
+	}
+
+	totpSecret, err := s.Repository.GetTOTPSecret(ctx.Request().Context(), user.ID)
+	if err != nil {
+		log.Errorf("Error When GetTOTPSecret: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if totpSecret.ConfirmedAt.IsZero() {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{errInvalidMFAChallenge.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	valid, err := s.verifySecondFactor(ctx.Request().Context(), totpSecret, request.Code)
+	if err != nil {
+		log.Errorf("Error When verifySecondFactor: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if !valid {
+		return s.rejectLogin(ctx, user.ID, user.PhoneNumber, "Invalid two-factor authentication code")
+	}
+
+	err = s.resetLoginFailures(ctx.Request().Context(), user.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When resetLoginFailures: %s with phone number: %s", err.Error(), user.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	return s.completeLogin(ctx, user)
 }
 
-func (s *Server) UpdateProfile(ctx echo.Context) error {
+// LoginPasswordChange sets a new password for a user whose password
+// expired during login, then completes the login.
+func (s *Server) LoginPasswordChange(ctx echo.Context) error {
 	var (
-		request  generated.UpdateProfileRequest
-		response generated.UpdateProfileResponse
+		request  generated.LoginPasswordChangeRequest
+		response generated.LoginResponse
 	)
 
-	var (
-		fullName     string
-		phoneNumber  string
-		updated bool
-	)
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
 
-	sessionClaims, err := getSessionClaims(ctx)
+	claims, err := s.parsePasswordChangeToken(request.ChangeToken)
 	if err != nil {
 		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
 		return ctx.JSON(http.StatusForbidden, response)
 	}
 
-	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), claims.UserID)
 	if err != nil {
-		log.Errorf("Error When Decode Request: %s with request: %s", err.Error(), request)
-		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		log.Errorf("Error When GetUserByID: %s with user id: %d", err.Error(), claims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// Once the password is changed the token is of no further use.
+	if user.ID == 0 || !s.passwordExpired(user) {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{errInvalidPasswordChange.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	if !validatePassword(request.NewPassword) {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{passwordRequirementMessage}, false)
 		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	if request.FullName != nil && *request.FullName != "" {
-		fullName = *request.FullName
+	samePassword, err := s.comparePasswords(ctx.Request().Context(), user.Password, request.NewPassword)
+	if err != nil {
+		log.Errorf("Error When comparePasswords: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+	if samePassword {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"New password must be different from the current password"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
 
-		if errorMessages := validateFullName(fullName); len(errorMessages) != 0 {
-			response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
-			return ctx.JSON(http.StatusBadRequest, response)
-		}
+	if errorMessages := s.checkPasswordStrength(request.NewPassword, user); len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
 
-		updated = true;
+	errorMessages, err := s.checkPasswordReuse(ctx.Request().Context(), user.ID, request.NewPassword)
+	if err != nil {
+		log.Errorf("Error When checkPasswordReuse: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+	if len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+		return ctx.JSON(http.StatusBadRequest, response)
 	}
 
-	if request.PhoneNumber != nil && *request.PhoneNumber != "" {
-		phoneNumber = *request.PhoneNumber
+	hashedPassword, err := s.createHashPassword(ctx.Request().Context(), request.NewPassword)
+	if err != nil {
+		log.Errorf("Error When createHashPassword: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
 
-		if errorMessages := checkPhoneNumber(phoneNumber); len(errorMessages) != 0 {
-			response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
-			return ctx.JSON(http.StatusBadRequest, response)
+	tokenVersion, err := s.Repository.UpdatePassword(ctx.Request().Context(), user.ID, hashedPassword)
+	if err != nil {
+		log.Errorf("Error When UpdatePassword: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	// The token version went up, tokens issued before are outdated now.
+	s.sessionCache.deleteUser(user.ID)
+
+	err = s.revokeOtherSessions(ctx.Request().Context(), user.ID, "")
+	if err != nil {
+		log.Errorf("Error When revokeOtherSessions: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	user.Password = hashedPassword
+	user.PasswordChangedAt = time.Now()
+	user.TokenVersion = tokenVersion
+	return s.completeLogin(ctx, user)
+}
+
+// completeLogin starts a session for the user once every login step passed
+// and answers with its tokens, or asks for a new password first when the
+// password expired.
+func (s *Server) completeLogin(ctx echo.Context, user repository.User) error {
+	var response generated.LoginResponse
+
+	if s.passwordExpired(user) {
+		changeToken, err := s.generatePasswordChangeToken(user)
+		if err != nil {
+			log.Errorf("Error When generatePasswordChangeToken: %s with user id: %d", err.Error(), user.ID)
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
 		}
 
-		user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), phoneNumber)
+		err = s.recordLoginEvent(ctx, user.ID, user.PhoneNumber, repository.LoginOutcomePasswordChangeRequired, "")
 		if err != nil {
-			log.Errorf("Error When GetUserByPhoneNumber: %s", err.Error())
+			log.Errorf("Error When recordLoginEvent: %s with user id: %d", err.Error(), user.ID)
 			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 			return ctx.JSON(http.StatusInternalServerError, response)
 		}
-		if user.ID != 0 && user.ID != sessionClaims.UserID {
-			response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is already registered"}, false)
-			return ctx.JSON(http.StatusConflict, response)
+
+		response.Header = createResponseHeader(200, []string{"Password has expired, a new password is required"}, true)
+		response.PasswordChangeRequired = &generated.PasswordChangeRequired{
+			ChangeToken: changeToken,
+			ExpiresIn:   int(passwordChangeDuration.Seconds()),
 		}
+		return ctx.JSON(http.StatusOK, response)
+	}
 
-		updated = true;
+	sessionID, err := s.createSession(ctx, user.ID)
+	if err == repository.ErrSessionLimitReached {
+		err = s.recordLoginEvent(ctx, user.ID, user.PhoneNumber, repository.LoginOutcomeFailure, errSessionLimit.Error())
+		if err != nil {
+			log.Errorf("Error When recordLoginEvent: %s with user id: %d", err.Error(), user.ID)
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{errSessionLimit.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
 	}
-	if updated == false {
-		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"No update"}, false)
-		return ctx.JSON(http.StatusBadRequest, response)
+	if err != nil {
+		log.Errorf("Error When createSession: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	err = s.Repository.UpdateUser(ctx.Request().Context(), repository.User{
-		ID:          sessionClaims.UserID,
-		PhoneNumber: phoneNumber,
-		FullName:    fullName,
-	})
+	jwtToken, err := s.generateToken(user, sessionID)
 	if err != nil {
-		log.Errorf("Error When UpdateUser: %s", err.Error())
+		log.Errorf("Error When generateToken: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx.Request().Context(), user.ID, sessionID)
+	if err != nil {
+		log.Errorf("Error When issueRefreshToken: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	err = s.recordLoginEvent(ctx, user.ID, user.PhoneNumber, repository.LoginOutcomeSuccess, "")
+	if err != nil {
+		log.Errorf("Error When recordLoginEvent: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if s.CookieSessions {
+		err = s.setSessionCookies(ctx, jwtToken, refreshToken)
+		if err != nil {
+			log.Errorf("Error When setSessionCookies: %s with user id: %d", err.Error(), user.ID)
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Login!"}, true)
+	response.Data = s.loginResponseData(ctx, user.ID, jwtToken, refreshToken, false)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+const (
+	loginFailurePhoneNumberNotFound = "Phone number is not found"
+	loginFailureWrongPassword       = "Wrong password"
+)
+
+// loginFailureReason hides which of the phone number or the password was
+// wrong in enumeration safe mode.
+func (s *Server) loginFailureReason(reason string) string {
+	if s.EnumerationSafe && (reason == loginFailurePhoneNumberNotFound || reason == loginFailureWrongPassword) {
+		return "Wrong phone number or password"
+	}
+	return reason
+}
+
+// rejectLogin counts the failed login, records it in the login history and
+// answers with the reason followed by the lockout state of the account.
+func (s *Server) rejectLogin(ctx echo.Context, userID int64, phoneNumber string, reason string) error {
+	var response generated.LoginResponse
+
+	lockoutMessages, err := s.recordLoginFailure(ctx.Request().Context(), phoneNumber, ctx.RealIP())
+	if err != nil {
+		log.Errorf("Error When recordLoginFailure: %s with phone number: %s", err.Error(), phoneNumber)
 		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
 		return ctx.JSON(http.StatusInternalServerError, response)
 	}
 
-	response.Header = createResponseHeader(200, []string{"Successfully Update User!"}, true)
+	err = s.recordLoginEvent(ctx, userID, phoneNumber, repository.LoginOutcomeFailure, reason)
+	if err != nil {
+		log.Errorf("Error When recordLoginEvent: %s with phone number: %s", err.Error(), phoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, append([]string{s.loginFailureReason(reason)}, lockoutMessages...), false)
+	return ctx.JSON(http.StatusBadRequest, response)
+}
+
+// rejectLockedLogin records a login refused by the lockout and answers with
+// when it may be tried again.
+func (s *Server) rejectLockedLogin(ctx echo.Context, userID int64, phoneNumber string, retryAfter time.Duration, lockoutMessages []string) error {
+	var response generated.LoginResponse
+
+	err := s.recordLoginEvent(ctx, userID, phoneNumber, repository.LoginOutcomeLocked, "")
+	if err != nil {
+		log.Errorf("Error When recordLoginEvent: %s with phone number: %s", err.Error(), phoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	ctx.Response().Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(retryAfter), 10))
+	response.Header = createResponseHeader(utilsHelper.TooManyRequestsErrorCode, lockoutMessages, false)
+	return ctx.JSON(http.StatusTooManyRequests, response)
+}
+
+func (s *Server) AdminUnlock(ctx echo.Context) error {
+	var (
+		request  generated.AdminUnlockRequest
+		response generated.AdminUnlockResponse
+	)
+
+	if !s.isAdmin(ctx) {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Invalid admin key"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	var keys []string
+	if request.PhoneNumber != nil && *request.PhoneNumber != "" {
+		keys = append(keys, phoneAttemptKeyPrefix+*request.PhoneNumber)
+	}
+	if request.IpAddress != nil && *request.IpAddress != "" {
+		keys = append(keys, ipAttemptKeyPrefix+*request.IpAddress)
+	}
+	if len(keys) == 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number or IP address is required"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	err = s.Repository.ResetLoginAttempts(ctx.Request().Context(), keys)
+	if err != nil {
+		log.Errorf("Error When ResetLoginAttempts: %s with keys: %+v", err.Error(), keys)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Unlock!"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) GetMetrics(ctx echo.Context) error {
+	var response generated.MetricsResponse
+
+	if !s.isAdmin(ctx) {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Invalid admin key"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// Without a pool hashing is unbounded and nothing is counted.
+	var stats password.PoolStats
+	if s.HashingPool != nil {
+		stats = s.HashingPool.Stats()
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Get Metrics!"}, true)
+	response.Data = &generated.Metrics{
+		PasswordHashing: generated.PasswordHashingMetrics{
+			Concurrency:      stats.Concurrency,
+			QueueDepth:       stats.QueueDepth,
+			Running:          stats.Running,
+			Queued:           stats.Queued,
+			Completed:        stats.Completed,
+			Rejected:         stats.Rejected,
+			Canceled:         stats.Canceled,
+			WaitSecondsTotal: stats.WaitTotal.Seconds(),
+			WaitSecondsMax:   stats.WaitMax.Seconds(),
+		},
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) RefreshToken(ctx echo.Context) error {
+	var (
+		request  generated.RefreshTokenRequest
+		response generated.RefreshTokenResponse
+	)
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	var (
+		plainToken string
+		fromCookie bool
+	)
+	if request.RefreshToken != nil {
+		plainToken = *request.RefreshToken
+	}
+	if plainToken == "" {
+		plainToken = s.cookieValue(ctx, refreshTokenCookie)
+		fromCookie = plainToken != ""
+		if fromCookie {
+			err = checkCSRFToken(ctx)
+			if err != nil {
+				response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+				return ctx.JSON(http.StatusForbidden, response)
+			}
+		}
+	}
+	if plainToken == "" {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Refresh token is required"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	refreshToken, err := s.Repository.GetRefreshTokenByHash(ctx.Request().Context(), hashRefreshToken(plainToken))
+	if err != nil {
+		log.Errorf("Error When GetRefreshTokenByHash: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if refreshToken.ID == 0 {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Invalid refresh token"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	if !refreshToken.RevokedAt.IsZero() {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Refresh token is revoked"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	if !refreshToken.RotatedAt.IsZero() {
+		return s.rejectRefreshTokenReuse(ctx, refreshToken)
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Refresh token is expired"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	// A refresh counts as activity, so it extends a sliding session, but it
+	// cannot bring back one that went idle or reached its maximum lifetime.
+	session, err := s.lookupSession(ctx.Request().Context(), refreshToken.FamilyID, ctx.RealIP())
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if session.err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{session.err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	rotated, err := s.Repository.RotateRefreshToken(ctx.Request().Context(), refreshToken.ID)
+	if err != nil {
+		log.Errorf("Error When RotateRefreshToken: %s with refresh token id: %d", err.Error(), refreshToken.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if !rotated {
+		// Another request rotated the same token first.
+		return s.rejectRefreshTokenReuse(ctx, refreshToken)
+	}
+
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), refreshToken.UserID)
+	if err != nil {
+		log.Errorf("Error When GetUserByID: %s with user id: %d", err.Error(), refreshToken.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if user.ID == 0 {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Invalid refresh token"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	jwtToken, err := s.generateToken(user, refreshToken.FamilyID)
+	if err != nil {
+		log.Errorf("Error When generateToken: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	newRefreshToken, err := s.issueRefreshToken(ctx.Request().Context(), user.ID, refreshToken.FamilyID)
+	if err != nil {
+		log.Errorf("Error When issueRefreshToken: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if s.CookieSessions {
+		err = s.setSessionCookies(ctx, jwtToken, newRefreshToken)
+		if err != nil {
+			log.Errorf("Error When setSessionCookies: %s with user id: %d", err.Error(), user.ID)
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Refresh Token!"}, true)
+	response.Data = s.loginResponseData(ctx, user.ID, jwtToken, newRefreshToken, fromCookie)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// rejectRefreshTokenReuse revokes the session of a refresh token that was
+// presented after it had already been rotated, together with its whole token
+// family, since either the legitimate client or an attacker is holding a
+// stolen copy.
+func (s *Server) rejectRefreshTokenReuse(ctx echo.Context, refreshToken repository.RefreshToken) error {
+	var response generated.RefreshTokenResponse
+
+	log.Warnf("Refresh token reuse detected for user id: %d family: %s", refreshToken.UserID, refreshToken.FamilyID)
+	err := s.revokeSession(ctx.Request().Context(), refreshToken.FamilyID)
+	if err != nil {
+		log.Errorf("Error When revokeSession: %s with session id: %s", err.Error(), refreshToken.FamilyID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"Refresh token has already been used"}, false)
+	return ctx.JSON(http.StatusForbidden, response)
+}
+
+func (s *Server) Logout(ctx echo.Context) error {
+	var (
+		response generated.LogoutResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err = s.revokeSession(ctx.Request().Context(), sessionClaims.Id)
+	if err != nil {
+		log.Errorf("Error When revokeSession: %s with session id: %s", err.Error(), sessionClaims.Id)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if s.CookieSessions {
+		clearSessionCookies(ctx)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Logout!"}, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// ListSessions lists the devices the user is logged in on.
+func (s *Server) ListSessions(ctx echo.Context) error {
+	var (
+		response generated.ListSessionsResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	sessions, err := s.Repository.GetActiveSessions(ctx.Request().Context(), sessionClaims.UserID, s.Sessions.withDefaults().idleSince(time.Now()))
+	if err != nil {
+		log.Errorf("Error When GetActiveSessions: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Data = &generated.ListSessionsResponseData{
+		Sessions: []generated.ActiveSession{},
+	}
+	for _, session := range sessions {
+		activeSession := generated.ActiveSession{
+			Id:         session.ID,
+			UserAgent:  session.UserAgent,
+			IpAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == sessionClaims.Id,
+		}
+		if session.DeviceName != "" {
+			deviceName := session.DeviceName
+			activeSession.DeviceName = &deviceName
+		}
+		response.Data.Sessions = append(response.Data.Sessions, activeSession)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully List Sessions!"}, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// RevokeOtherSessions logs the user out everywhere but on the device making
+// the request.
+func (s *Server) RevokeOtherSessions(ctx echo.Context) error {
+	var (
+		response generated.RevokeOtherSessionsResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err = s.revokeOtherSessions(ctx.Request().Context(), sessionClaims.UserID, sessionClaims.Id)
+	if err != nil {
+		log.Errorf("Error When revokeOtherSessions: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Revoke Other Sessions!"}, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) RevokeSession(ctx echo.Context, id string) error {
+	var (
+		response generated.RevokeSessionResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	session, err := s.Repository.GetSessionByID(ctx.Request().Context(), id)
+	if err != nil {
+		log.Errorf("Error When GetSessionByID: %s with session id: %s", err.Error(), id)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// Sessions of other users are reported as missing so their ids cannot be probed.
+	if session.ID == "" || session.UserID != sessionClaims.UserID {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Session is not found"}, false)
+		return ctx.JSON(http.StatusNotFound, response)
+	}
+
+	err = s.revokeSession(ctx.Request().Context(), session.ID)
+	if err != nil {
+		log.Errorf("Error When revokeSession: %s with session id: %s", err.Error(), session.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Revoke Session!"}, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) Register(ctx echo.Context) error {
+	var (
+		request  generated.RegistrationRequest
+		response generated.RegistrationResponse
+	)
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	requestValidationErrors := s.validateRegistration(request)
+	if len(requestValidationErrors) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, requestValidationErrors, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// Hashed before the phone number is looked up, so that a number that is
+	// taken is not answered faster.
+	hashedPassword, err := s.createHashPassword(ctx.Request().Context(), request.Password)
+	if err != nil {
+		log.Errorf("Error when createHashPassword: %s", err.Error())
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	phoneNumber, err := ValidatePhoneNumber(ctx.Request().Context(), s, request.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error when ValidatePhoneNumber: %s with phoneNumber: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if !phoneNumber {
+		statusCode, header := s.registrationTaken(ctx.Request().Context(), request.PhoneNumber)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	user := repository.User{
+		PhoneNumber: request.PhoneNumber,
+		Password:    hashedPassword,
+		FullName:    request.FullName,
+	}
+	// Users who may log in unverified can be using the account already, so
+	// their registration does not expire.
+	if !s.AllowUnverifiedLogin {
+		user.RegistrationExpiresAt = time.Now().Add(pendingRegistrationDuration)
+	}
+	id, err := s.Repository.InsertUser(ctx.Request().Context(), user)
+	// Someone registered the number since it was looked up.
+	if err == repository.ErrPhoneNumberTaken {
+		statusCode, header := s.registrationTaken(ctx.Request().Context(), request.PhoneNumber)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+	if err != nil {
+		log.Errorf("Error when InsertUser: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error when register"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	messages := []string{"Successfully Create User Register!", "A verification code has been sent to your phone number"}
+	err = s.sendOneTimeCode(ctx.Request().Context(), id, repository.OneTimeCodePurposeRegistration, request.PhoneNumber, registrationCodeMessage)
+	if err != nil {
+		// The user is created either way; they can ask for a new code.
+		log.Errorf("Error When sendOneTimeCode: %s with user id: %d", err.Error(), id)
+		messages = []string{"Successfully Create User Register!", "The verification code could not be sent, please request a new one"}
+	}
+
+	// The id would tell a new user apart from a number that is taken.
+	if s.EnumerationSafe {
+		response.Header = createResponseHeader(200, []string{registrationReceivedMessage}, true)
+		return ctx.JSON(http.StatusOK, response)
+	}
+
+	response.Header = createResponseHeader(200, messages, true)
+	response.Data = &generated.RegistrationResponseData{
+		Id: id,
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) VerifyRegistration(ctx echo.Context) error {
+	var (
+		request  generated.VerifyRegistrationRequest
+		response generated.VerifyRegistrationResponse
+	)
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When GetUserByPhoneNumber: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if user.ID == 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{errInvalidOneTimeCode.Error()}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if !user.PhoneVerifiedAt.IsZero() && s.EnumerationSafe {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{errInvalidOneTimeCode.Error()}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if !user.PhoneVerifiedAt.IsZero() {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is already verified"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	_, err = s.verifyOneTimeCode(ctx.Request().Context(), user.ID, repository.OneTimeCodePurposeRegistration, request.Code)
+	if err == errInvalidOneTimeCode {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		log.Errorf("Error When verifyOneTimeCode: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	err = s.Repository.MarkPhoneNumberVerified(ctx.Request().Context(), user.ID)
+	if err != nil {
+		log.Errorf("Error When MarkPhoneNumberVerified: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Verify Phone Number!"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) ResendRegistrationCode(ctx echo.Context) error {
+	var (
+		request  generated.ResendRegistrationCodeRequest
+		response generated.ResendRegistrationCodeResponse
+	)
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When GetUserByPhoneNumber: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// Like password reset requests, the answer does not tell whether the
+	// phone number is registered or already verified, nor whether it was
+	// sent too many codes already.
+	if user.ID != 0 && user.PhoneVerifiedAt.IsZero() {
+		err = s.sendOneTimeCode(ctx.Request().Context(), user.ID, repository.OneTimeCodePurposeRegistration, user.PhoneNumber, registrationCodeMessage)
+		if err == errTooManyOneTimeCodes {
+			log.Warnf("Too many registration codes requested for user id: %d", user.ID)
+		} else if err != nil {
+			log.Errorf("Error When sendOneTimeCode: %s with user id: %d", err.Error(), user.ID)
+			statusCode, header := hashingFailure(ctx, err)
+			response.Header = header
+			return ctx.JSON(statusCode, response)
+		}
+	}
+
+	response.Header = createResponseHeader(200, []string{"If the phone number is waiting for verification, a new code has been sent to it"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) GetJwks(ctx echo.Context) error {
+	// Let verifiers cache the keys, but not for so long that a rotation
+	// takes ages to propagate.
+	ctx.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return ctx.JSON(http.StatusOK, s.keySet().jwks())
+}
+
+func (s *Server) GetProfile(ctx echo.Context) error {
+	var (
+		response generated.GetProfileResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		log.Errorf("Error When GetUserByID: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	recoveryCodesRemaining, err := s.Repository.CountRecoveryCodes(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		log.Errorf("Error When CountRecoveryCodes: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Get User Profile!"}, true)
+	response.Data = &generated.GetProfileResponseData{
+		FullName:               user.FullName,
+		PhoneNumber:            user.PhoneNumber,
+		PhoneVerified:          !user.PhoneVerifiedAt.IsZero(),
+		RecoveryCodesRemaining: recoveryCodesRemaining,
+	}
+	if !user.LastLoginAt.IsZero() {
+		response.Data.LastLoginAt = &user.LastLoginAt
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// GetProfileLogins lists the login attempts on the account, newest first,
+// a page at a time.
+func (s *Server) GetProfileLogins(ctx echo.Context, params generated.GetProfileLoginsParams) error {
+	var (
+		response generated.GetProfileLoginsResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	limit := defaultLoginEventsLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > maxLoginEventsLimit {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{fmt.Sprintf("Limit must be between 1 and %d", maxLoginEventsLimit)}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	var beforeID int64
+	if params.Cursor != nil && *params.Cursor != "" {
+		beforeID, err = decodeLoginCursor(*params.Cursor)
+		if err != nil {
+			response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{err.Error()}, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+	}
+
+	// One more than asked for tells whether there is a next page.
+	events, err := s.Repository.GetLoginEvents(ctx.Request().Context(), sessionClaims.UserID, beforeID, limit+1)
+	if err != nil {
+		log.Errorf("Error When GetLoginEvents: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Data = &generated.GetProfileLoginsResponseData{
+		Logins: []generated.LoginEvent{},
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor := encodeLoginCursor(events[limit-1].ID)
+		response.Data.NextCursor = &nextCursor
+	}
+	for _, event := range events {
+		login := generated.LoginEvent{
+			CreatedAt: event.CreatedAt,
+			IpAddress: event.IPAddress,
+			UserAgent: event.UserAgent,
+			Outcome:   generated.LoginEventOutcome(event.Outcome),
+		}
+		if event.FailureReason != "" {
+			failureReason := event.FailureReason
+			login.FailureReason = &failureReason
+		}
+		response.Data.Logins = append(response.Data.Logins, login)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Get Logins!"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) UpdateProfile(ctx echo.Context) error {
+	var (
+		request  generated.UpdateProfileRequest
+		response generated.UpdateProfileResponse
+	)
+
+	var (
+		fullName     string
+		phoneNumber  string
+		updated bool
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if request.FullName != nil && *request.FullName != "" {
+		fullName = *request.FullName
+
+		if errorMessages := validateFullName(fullName); len(errorMessages) != 0 {
+			response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+
+		updated = true;
+	}
+
+	if request.PhoneNumber != nil && *request.PhoneNumber != "" {
+		phoneNumber = *request.PhoneNumber
+
+		if errorMessages := checkPhoneNumber(phoneNumber); len(errorMessages) != 0 {
+			response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+			return ctx.JSON(http.StatusBadRequest, response)
+		}
+
+		user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), phoneNumber)
+		if err != nil {
+			log.Errorf("Error When GetUserByPhoneNumber: %s", err.Error())
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+		if user.ID != 0 && user.ID != sessionClaims.UserID {
+			response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is already registered"}, false)
+			return ctx.JSON(http.StatusConflict, response)
+		}
+		// The number already belongs to the user, there is nothing to change.
+		if user.ID == sessionClaims.UserID {
+			phoneNumber = ""
+		}
+
+		updated = true;
+	}
+	if updated == false {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"No update"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if fullName != "" {
+		err = s.Repository.UpdateUser(ctx.Request().Context(), repository.User{
+			ID:       sessionClaims.UserID,
+			FullName: fullName,
+		})
+		if err != nil {
+			log.Errorf("Error When UpdateUser: %s", err.Error())
+			response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+			return ctx.JSON(http.StatusInternalServerError, response)
+		}
+	}
+
+	messages := []string{"Successfully Update User!"}
+
+	// A new phone number only replaces the current one once the code sent
+	// to it is verified with VerifyPhoneNumberChange.
+	if phoneNumber != "" {
+		err = s.requestPhoneNumberChange(ctx.Request().Context(), sessionClaims.UserID, phoneNumber)
+		// The other fields are saved already, only the code is refused.
+		if err == errTooManyOneTimeCodes {
+			ctx.Response().Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(oneTimeCodeDuration), 10))
+			response.Header = createResponseHeader(utilsHelper.TooManyRequestsErrorCode, []string{err.Error()}, false)
+			return ctx.JSON(http.StatusTooManyRequests, response)
+		}
+		if err != nil {
+			log.Errorf("Error When requestPhoneNumberChange: %s with user id: %d", err.Error(), sessionClaims.UserID)
+			statusCode, header := hashingFailure(ctx, err)
+			response.Header = header
+			return ctx.JSON(statusCode, response)
+		}
+		messages = append(messages, "A verification code has been sent to the new phone number, it replaces the current one once verified")
+	}
+
+	response.Header = createResponseHeader(200, messages, true)
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) VerifyPhoneNumberChange(ctx echo.Context) error {
+	var (
+		request  generated.VerifyPhoneNumberChangeRequest
+		response generated.VerifyPhoneNumberChangeResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	oneTimeCode, err := s.verifyOneTimeCode(ctx.Request().Context(), sessionClaims.UserID, repository.OneTimeCodePurposePhoneChange, request.Code)
+	if err == errInvalidOneTimeCode {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		log.Errorf("Error When verifyOneTimeCode: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	// The number may have been registered by someone else while the change
+	// was pending.
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), oneTimeCode.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When GetUserByPhoneNumber: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if user.ID != 0 && user.ID != sessionClaims.UserID {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is already registered"}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
+
+	err = s.Repository.UpdateUser(ctx.Request().Context(), repository.User{
+		ID:              sessionClaims.UserID,
+		PhoneNumber:     oneTimeCode.PhoneNumber,
+		PhoneVerifiedAt: time.Now(),
+	})
+	if err == repository.ErrPhoneNumberTaken {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is already registered"}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
+	if err != nil {
+		log.Errorf("Error When UpdateUser: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	// Tokens carry the phone number, so the ones issued before are outdated
+	// now, including the one of this request.
+	s.sessionCache.deleteUser(sessionClaims.UserID)
+
+	response.Header = createResponseHeader(200, []string{"Successfully Update Phone Number!"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) EnrollTotp(ctx echo.Context) error {
+	var response generated.EnrollTotpResponse
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Errorf("Error When GenerateSecret: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	encryptedSecret, err := s.encryptTOTPSecret(secret)
+	if err != nil {
+		log.Errorf("Error When encryptTOTPSecret: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// Enrolling again before confirming replaces the pending secret, but an
+	// enabled one is kept.
+	saved, err := s.Repository.SaveTOTPSecret(ctx.Request().Context(), repository.TOTPSecret{
+		UserID:          sessionClaims.UserID,
+		EncryptedSecret: encryptedSecret,
+	})
+	if err != nil {
+		log.Errorf("Error When SaveTOTPSecret: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if !saved {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Two-factor authentication is already enabled"}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Add the secret to an authenticator app and confirm it with a code"}, true)
+	response.Data = &generated.EnrollTotpResponseData{
+		Secret: secret,
+		Uri:    totp.URI(totpIssuer, sessionClaims.PhoneNumber, secret),
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) ConfirmTotp(ctx echo.Context) error {
+	var (
+		request  generated.ConfirmTotpRequest
+		response generated.ConfirmTotpResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	totpSecret, err := s.Repository.GetTOTPSecret(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		log.Errorf("Error When GetTOTPSecret: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if totpSecret.UserID == 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Two-factor authentication enrollment is not started"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if !totpSecret.ConfirmedAt.IsZero() {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Two-factor authentication is already enabled"}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
+
+	secret, err := s.decryptTOTPSecret(totpSecret.EncryptedSecret)
+	if err != nil {
+		log.Errorf("Error When decryptTOTPSecret: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	step, valid, err := totp.Validate(secret, request.Code, time.Now(), totpSkew)
+	if err != nil {
+		log.Errorf("Error When Validate: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if !valid {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Invalid code"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	confirmed, err := s.Repository.ConfirmTOTPSecret(ctx.Request().Context(), sessionClaims.UserID, step)
+	if err != nil {
+		log.Errorf("Error When ConfirmTOTPSecret: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if !confirmed {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Two-factor authentication is already enabled"}, false)
+		return ctx.JSON(http.StatusConflict, response)
+	}
+
+	recoveryCodes, err := s.issueRecoveryCodes(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		log.Errorf("Error When issueRecoveryCodes: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Enable Two-Factor Authentication!", "Store the recovery codes somewhere safe, they are only shown once"}, true)
+	response.Data = &generated.RecoveryCodesResponseData{
+		RecoveryCodes: recoveryCodes,
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) RegenerateRecoveryCodes(ctx echo.Context) error {
+	var response generated.RegenerateRecoveryCodesResponse
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	totpSecret, err := s.Repository.GetTOTPSecret(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		log.Errorf("Error When GetTOTPSecret: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if totpSecret.ConfirmedAt.IsZero() {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Two-factor authentication is not enabled"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	recoveryCodes, err := s.issueRecoveryCodes(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		log.Errorf("Error When issueRecoveryCodes: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Regenerate Recovery Codes!", "The previous recovery codes no longer work"}, true)
+	response.Data = &generated.RecoveryCodesResponseData{
+		RecoveryCodes: recoveryCodes,
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) UpdatePassword(ctx echo.Context) error {
+	var (
+		request  generated.UpdatePasswordRequest
+		response generated.UpdatePasswordResponse
+	)
+
+	sessionClaims, err := s.getSessionClaims(ctx)
+	if err != nil {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	err = json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	user, err := s.Repository.GetUserByID(ctx.Request().Context(), sessionClaims.UserID)
+	if err != nil {
+		log.Errorf("Error When GetUserByID: %s with user id: %d", err.Error(), sessionClaims.UserID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if user.ID == 0 {
+		response.Header = createResponseHeader(utilsHelper.AuthorizationErrorCode, []string{"User is not found"}, false)
+		return ctx.JSON(http.StatusForbidden, response)
+	}
+
+	ok, err := s.comparePasswords(ctx.Request().Context(), user.Password, request.CurrentPassword)
+	if err != nil {
+		log.Errorf("Error When comparePasswords: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+	if !ok {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Wrong current password"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if !validatePassword(request.NewPassword) {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{passwordRequirementMessage}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if request.NewPassword == request.CurrentPassword {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{"New password must be different from the current password"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if errorMessages := s.checkPasswordStrength(request.NewPassword, user); len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	errorMessages, err := s.checkPasswordReuse(ctx.Request().Context(), user.ID, request.NewPassword)
+	if err != nil {
+		log.Errorf("Error When checkPasswordReuse: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+	if len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	hashedPassword, err := s.createHashPassword(ctx.Request().Context(), request.NewPassword)
+	if err != nil {
+		log.Errorf("Error When createHashPassword: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	tokenVersion, err := s.Repository.UpdatePassword(ctx.Request().Context(), user.ID, hashedPassword)
+	if err != nil {
+		log.Errorf("Error When UpdatePassword: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	// The token version went up, tokens issued before are outdated now.
+	s.sessionCache.deleteUser(user.ID)
+
+	err = s.revokeOtherSessions(ctx.Request().Context(), user.ID, sessionClaims.Id)
+	if err != nil {
+		log.Errorf("Error When revokeOtherSessions: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// The token of this request is outdated as well, so the session that
+	// changed the password gets a new one right away.
+	user.TokenVersion = tokenVersion
+	jwtToken, err := s.generateToken(user, sessionClaims.Id)
+	if err != nil {
+		log.Errorf("Error When generateToken: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	response.Data = &generated.UpdatePasswordResponseData{}
+	if ctx.Request().Header.Get("Authorization") == "" {
+		// Authenticated by the cookie, which keeps the token from scripts.
+		s.setAccessTokenCookie(ctx, jwtToken)
+	} else {
+		response.Data.Jwt = &jwtToken
+	}
+
+	response.Header = createResponseHeader(200, []string{"Successfully Update Password!"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) RequestPasswordReset(ctx echo.Context) error {
+	var (
+		request  generated.RequestPasswordResetRequest
+		response generated.RequestPasswordResetResponse
+	)
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s with request: %+v", err.Error(), request)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if errorMessages := checkPhoneNumber(request.PhoneNumber); len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When GetUserByPhoneNumber: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// The answer is the same whether the phone number is registered or not,
+	// so that this endpoint cannot be used to look up phone numbers. It does
+	// not tell either when the number was sent too many codes already.
+	if user.ID != 0 {
+		err = s.sendOneTimeCode(ctx.Request().Context(), user.ID, repository.OneTimeCodePurposePasswordReset, user.PhoneNumber,
+			"Your password reset code is %s. It expires in 10 minutes. Do not share it with anyone.")
+		if err == errTooManyOneTimeCodes {
+			log.Warnf("Too many password reset codes requested for user id: %d", user.ID)
+		} else if err != nil {
+			log.Errorf("Error When sendOneTimeCode: %s with user id: %d", err.Error(), user.ID)
+			statusCode, header := hashingFailure(ctx, err)
+			response.Header = header
+			return ctx.JSON(statusCode, response)
+		}
+	}
+
+	response.Header = createResponseHeader(200, []string{"If the phone number is registered, a reset code has been sent to it"}, true)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) ResetPassword(ctx echo.Context) error {
+	var (
+		request  generated.ResetPasswordRequest
+		response generated.ResetPasswordResponse
+	)
+
+	err := json.NewDecoder(ctx.Request().Body).Decode(&request)
+	if err != nil {
+		log.Errorf("Error When Decode Request: %s", err.Error())
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Bad request"}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	if !validatePassword(request.NewPassword) {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{passwordRequirementMessage}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), request.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When GetUserByPhoneNumber: %s with phone number: %s", err.Error(), request.PhoneNumber)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	if user.ID == 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{errInvalidOneTimeCode.Error()}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	// Checked before the code is used up, so that the user can try another
+	// password with the same code.
+	if errorMessages := s.checkPasswordStrength(request.NewPassword, user); len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	_, err = s.verifyOneTimeCode(ctx.Request().Context(), user.ID, repository.OneTimeCodePurposePasswordReset, request.Code)
+	if err == errInvalidOneTimeCode {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, []string{err.Error()}, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+	if err != nil {
+		log.Errorf("Error When verifyOneTimeCode: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	// Unlike the strength check this one needs the code, otherwise it would
+	// tell anyone whether a password was used before.
+	errorMessages, err := s.checkPasswordReuse(ctx.Request().Context(), user.ID, request.NewPassword)
+	if err != nil {
+		log.Errorf("Error When checkPasswordReuse: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+	if len(errorMessages) != 0 {
+		response.Header = createResponseHeader(utilsHelper.ValidationErrorCode, errorMessages, false)
+		return ctx.JSON(http.StatusBadRequest, response)
+	}
+
+	hashedPassword, err := s.createHashPassword(ctx.Request().Context(), request.NewPassword)
+	if err != nil {
+		log.Errorf("Error When createHashPassword: %s with user id: %d", err.Error(), user.ID)
+		statusCode, header := hashingFailure(ctx, err)
+		response.Header = header
+		return ctx.JSON(statusCode, response)
+	}
+
+	_, err = s.Repository.UpdatePassword(ctx.Request().Context(), user.ID, hashedPassword)
+	if err != nil {
+		log.Errorf("Error When UpdatePassword: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+	// The token version went up, tokens issued before are outdated now.
+	s.sessionCache.deleteUser(user.ID)
+
+	err = s.revokeOtherSessions(ctx.Request().Context(), user.ID, "")
+	if err != nil {
+		log.Errorf("Error When revokeOtherSessions: %s with user id: %d", err.Error(), user.ID)
+		response.Header = createResponseHeader(utilsHelper.HttpErrorCode, []string{"Internal Server Error"}, false)
+		return ctx.JSON(http.StatusInternalServerError, response)
+	}
+
+	// The password is already changed at this point, so a failure to lift a
+	// lockout does not fail the request.
+	err = s.resetLoginFailures(ctx.Request().Context(), user.PhoneNumber)
+	if err != nil {
+		log.Errorf("Error When resetLoginFailures: %s with user id: %d", err.Error(), user.ID)
+	}
 
+	response.Header = createResponseHeader(200, []string{"Successfully Reset Password!"}, true)
 	return ctx.JSON(http.StatusOK, response)
 }
@@ -3,22 +3,31 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	"github.com/Richthonio10/requirement-swtpro/generated"
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
+	"github.com/Richthonio10/requirement-swtpro/pkg/totp"
 	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/Richthonio10/requirement-swtpro/sms"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func Test_Register(t *testing.T) {
 	type fields struct {
 		mockCtrl   *gomock.Controller
 		Repository *repository.MockRepositoryInterface
+		SMSSender  *sms.MockSMSSender
 	}
 	type args struct {
 		ctx echo.Context
@@ -172,6 +181,86 @@ func Test_Register(t *testing.T) {
 			statusCode: http.StatusInternalServerError,
 			detailErr:        nil,
 		},
+		{
+			name: "phone number registered concurrently",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID: 0,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+					Return(int64(0), repository.ErrPhoneNumberTaken).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:        nil,
+		},
+		{
+			name: "error sendOneTimeCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID: 0,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(errors.New("expected Send error")).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
 		{
 			name: "passed",
 			fields: func() fields {
@@ -179,6 +268,7 @@ func Test_Register(t *testing.T) {
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
 				}
 			}(),
 			args: args{
@@ -203,6 +293,14 @@ func Test_Register(t *testing.T) {
 				fields.Repository.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
 					Return(int64(1), nil).
 					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(nil).
+					Times(1)
 			},
 			statusCode: http.StatusOK,
 			detailErr:        nil,
@@ -213,6 +311,9 @@ func Test_Register(t *testing.T) {
 			s := &Server{
 				Repository: tt.fields.Repository,
 			}
+			if tt.fields.SMSSender != nil {
+				s.SMSSender = tt.fields.SMSSender
+			}
 			tt.mock(&tt.fields)
 			err := s.Register(tt.args.ctx)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
@@ -228,7 +329,133 @@ func Test_Register(t *testing.T) {
 	}
 }
 
-func Test_Login(t *testing.T) {
+// Test_Register_allowUnverifiedLogin checks that a registration only expires
+// while users have to verify their phone number before they can log in.
+func Test_Register_allowUnverifiedLogin(t *testing.T) {
+	body := `{"phone_number": "+62821232342", "full_name": "Some Full Name", "password": "SawitPro123$"}`
+	for _, allowUnverifiedLogin := range []bool{false, true} {
+		mockCtrl := gomock.NewController(t)
+		repo := repository.NewMockRepositoryInterface(mockCtrl)
+		smsSender := sms.NewMockSMSSender(mockCtrl)
+		repo.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+			Return(repository.User{}, nil).
+			Times(1)
+		repo.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+			DoAndReturn(func(ctx context.Context, data repository.User) (int64, error) {
+				if data.RegistrationExpiresAt.IsZero() != allowUnverifiedLogin {
+					t.Errorf("Result When InsertUser() RegistrationExpiresAt = %s, allowUnverifiedLogin = %v", data.RegistrationExpiresAt, allowUnverifiedLogin)
+				}
+				return 1, nil
+			}).
+			Times(1)
+		repo.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+			Return(int64(1), nil).
+			Times(1)
+		smsSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		s := &Server{
+			Repository:           repo,
+			SMSSender:            smsSender,
+			PasswordHasher:       password.NewBcryptHasher(password.BcryptOptions{Cost: bcrypt.MinCost}),
+			AllowUnverifiedLogin: allowUnverifiedLogin,
+		}
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		err := s.Register(echo.New().NewContext(req, res))
+		if err != nil {
+			t.Errorf("Error When Register() %s", err.Error())
+		}
+		if res.Code != http.StatusOK {
+			t.Errorf("Result When Register() %d, statusCode = %d", res.Code, http.StatusOK)
+		}
+		mockCtrl.Finish()
+	}
+}
+
+// Test_Register_enumerationSafe checks that a taken phone number gets the
+// same answer as a new registration, and a notice by SMS.
+func Test_Register_enumerationSafe(t *testing.T) {
+	body := `{"phone_number": "+62821232342", "full_name": "Some Full Name", "password": "SawitPro123$"}`
+	tests := []struct {
+		name string
+		mock func(repo *repository.MockRepositoryInterface, smsSender *sms.MockSMSSender)
+	}{
+		{
+			name: "new phone number",
+			mock: func(repo *repository.MockRepositoryInterface, smsSender *sms.MockSMSSender) {
+				repo.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+				repo.EXPECT().InsertUser(context.Background(), gomock.AssignableToTypeOf(repository.User{})).
+					Return(int64(1), nil).
+					Times(1)
+				repo.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				smsSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "phone number already registered",
+			mock: func(repo *repository.MockRepositoryInterface, smsSender *sms.MockSMSSender) {
+				repo.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{ID: 1, PhoneNumber: "+62821232342"}, nil).
+					Times(1)
+				smsSender.EXPECT().Send(context.Background(), "+62821232342", registrationNotice).
+					Return(nil).
+					Times(1)
+			},
+		},
+		{
+			name: "notice not sent",
+			mock: func(repo *repository.MockRepositoryInterface, smsSender *sms.MockSMSSender) {
+				repo.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{ID: 1, PhoneNumber: "+62821232342"}, nil).
+					Times(1)
+				smsSender.EXPECT().Send(context.Background(), "+62821232342", registrationNotice).
+					Return(errors.New("expected Send error")).
+					Times(1)
+			},
+		},
+	}
+	var firstBody string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			repo := repository.NewMockRepositoryInterface(mockCtrl)
+			smsSender := sms.NewMockSMSSender(mockCtrl)
+			tt.mock(repo, smsSender)
+
+			s := &Server{
+				Repository:      repo,
+				SMSSender:       smsSender,
+				PasswordHasher:  password.NewBcryptHasher(password.BcryptOptions{Cost: bcrypt.MinCost}),
+				EnumerationSafe: true,
+			}
+			req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+			res := httptest.NewRecorder()
+			err := s.Register(echo.New().NewContext(req, res))
+			if err != nil {
+				t.Fatalf("Error When Register() %s", err.Error())
+			}
+			if res.Code != http.StatusOK {
+				t.Errorf("Result When Register() %d, statusCode = %d", res.Code, http.StatusOK)
+			}
+			if firstBody == "" {
+				firstBody = res.Body.String()
+			} else if res.Body.String() != firstBody {
+				t.Errorf("Result When Register() %s, want = %s", res.Body.String(), firstBody)
+			}
+		})
+	}
+}
+
+func Test_VerifyRegistration(t *testing.T) {
 	type fields struct {
 		mockCtrl   *gomock.Controller
 		Repository *repository.MockRepositoryInterface
@@ -236,13 +463,27 @@ func Test_Login(t *testing.T) {
 	type args struct {
 		ctx echo.Context
 	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	codeHash, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	activeCode := repository.OneTimeCode{
+		ID:          1,
+		UserID:      1,
+		Purpose:     repository.OneTimeCodePurposeRegistration,
+		PhoneNumber: "+62821232342",
+		CodeHash:    string(codeHash),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
 	tests := []struct {
-		name           string
-		fields         fields
-		args           args
-		mock           func(fields *fields)
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
 		statusCode int
-		detailErr        error
+		detailErr  error
 	}{
 		{
 			name: "no request body",
@@ -254,16 +495,11 @@ func Test_Login(t *testing.T) {
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(``),
 			},
-			mock:           func(fields *fields) {},
+			mock:       func(fields *fields) {},
 			statusCode: http.StatusBadRequest,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 		{
 			name: "error GetUserByPhoneNumber",
@@ -275,15 +511,7 @@ func Test_Login(t *testing.T) {
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+62821232342",
-						"password": "SawitPro123$"
-					}`)))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
 			},
 			mock: func(fields *fields) {
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
@@ -291,10 +519,10 @@ func Test_Login(t *testing.T) {
 					Times(1)
 			},
 			statusCode: http.StatusInternalServerError,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 		{
-			name: "user not found",
+			name: "unknown phone number",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -303,28 +531,42 @@ func Test_Login(t *testing.T) {
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+62821232342",
-						"password": "SawitPro123$"
-					}`)))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "already verified",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
 			},
 			mock: func(fields *fields) {
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
 					Return(repository.User{
-						ID: 0,
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
 					}, nil).
 					Times(1)
 			},
 			statusCode: http.StatusBadRequest,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 		{
-			name: "wrong password",
+			name: "wrong code",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -333,29 +575,28 @@ func Test_Login(t *testing.T) {
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+62821232342",
-						"password": "Random@123"
-					}`)))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "654321"}`),
 			},
 			mock: func(fields *fields) {
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
 					Return(repository.User{
-						ID:       1,
-						Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						ID:          1,
+						PhoneNumber: "+62821232342",
 					}, nil).
 					Times(1)
+
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeRegistration).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
 			},
 			statusCode: http.StatusBadRequest,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 		{
-			name: "error CreateLoginCount",
+			name: "error MarkPhoneNumberVerified",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -364,30 +605,32 @@ func Test_Login(t *testing.T) {
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+62821232342",
-						"password": "SawitPro123$"
-					}`)))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
 			},
 			mock: func(fields *fields) {
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
 					Return(repository.User{
-						ID:       1,
-						Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						ID:          1,
+						PhoneNumber: "+62821232342",
 					}, nil).
 					Times(1)
 
-				fields.Repository.EXPECT().CreateLoginCount(context.Background(), int64(1)).
-					Return(errors.New("expected CreateLoginCount error")).
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeRegistration).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkPhoneNumberVerified(context.Background(), int64(1)).
+					Return(errors.New("expected MarkPhoneNumberVerified error")).
 					Times(1)
 			},
 			statusCode: http.StatusInternalServerError,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 		{
 			name: "passed",
@@ -399,30 +642,32 @@ func Test_Login(t *testing.T) {
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+62821232342",
-						"password": "SawitPro123$"
-					}`)))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
 			},
 			mock: func(fields *fields) {
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
 					Return(repository.User{
-						ID:       1,
-						Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						ID:          1,
+						PhoneNumber: "+62821232342",
 					}, nil).
 					Times(1)
 
-				fields.Repository.EXPECT().CreateLoginCount(context.Background(), int64(1)).
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeRegistration).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().MarkPhoneNumberVerified(context.Background(), int64(1)).
 					Return(nil).
 					Times(1)
 			},
 			statusCode: http.StatusOK,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 	}
 	for _, tt := range tests {
@@ -431,13 +676,13 @@ func Test_Login(t *testing.T) {
 				Repository: tt.fields.Repository,
 			}
 			tt.mock(&tt.fields)
-			err := s.Login(tt.args.ctx)
+			err := s.VerifyRegistration(tt.args.ctx)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
-				t.Errorf("Error When Login() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+				t.Errorf("Error When VerifyRegistration() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
 			}
 			if err == nil {
 				if tt.args.ctx.Response().Status != tt.statusCode {
-					t.Errorf("Result When Register() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+					t.Errorf("Result When VerifyRegistration() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
 				}
 			}
 			tt.fields.mockCtrl.Finish()
@@ -445,71 +690,146 @@ func Test_Login(t *testing.T) {
 	}
 }
 
-func Test_GetProfile(t *testing.T) {
+func Test_ResendRegistrationCode(t *testing.T) {
 	type fields struct {
 		mockCtrl   *gomock.Controller
 		Repository *repository.MockRepositoryInterface
+		SMSSender  *sms.MockSMSSender
 	}
 	type args struct {
 		ctx echo.Context
 	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
 	tests := []struct {
-		name           string
-		fields         fields
-		args           args
-		mock           func(fields *fields)
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
 		statusCode int
-		detailErr        error
+		detailErr  error
 	}{
 		{
-			name: "invalid authorization",
+			name: "no request body",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodGet, "url", nil)
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(``),
 			},
-			mock:           func(fields *fields) {},
-			statusCode: http.StatusForbidden,
-			detailErr:        nil,
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
 		},
 		{
-			name: "error GetUserByID",
+			name: "error GetUserByPhoneNumber",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodGet, "url", nil)
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
-					Return(repository.User{}, errors.New("expected GetUserByID error")).
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
 					Times(1)
 			},
 			statusCode: http.StatusInternalServerError,
-			detailErr:        nil,
+			detailErr:  nil,
+		},
+		{
+			name: "already verified",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+		{
+			name: "error sendOneTimeCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertOneTimeCode error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "too many codes",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), repository.ErrOneTimeCodeLimitReached).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
 		},
 		{
 			name: "passed",
@@ -518,42 +838,50 @@ func Test_GetProfile(t *testing.T) {
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodGet, "url", nil)
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
-					Return(repository.User{}, nil).
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, code repository.OneTimeCode, limit repository.OneTimeCodeLimit) (int64, error) {
+						if code.Purpose != repository.OneTimeCodePurposeRegistration {
+							t.Errorf("Result When InsertOneTimeCode() %+v", code)
+						}
+						return int64(1), nil
+					}).
+					Times(1)
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(nil).
 					Times(1)
 			},
 			statusCode: http.StatusOK,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
 				Repository: tt.fields.Repository,
+				SMSSender:  tt.fields.SMSSender,
 			}
 			tt.mock(&tt.fields)
-			err := s.GetProfile(tt.args.ctx)
+			err := s.ResendRegistrationCode(tt.args.ctx)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
-				t.Errorf("Error When GetProfile() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+				t.Errorf("Error When ResendRegistrationCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
 			}
 			if err == nil {
 				if tt.args.ctx.Response().Status != tt.statusCode {
-					t.Errorf("Result When Register() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+					t.Errorf("Result When ResendRegistrationCode() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
 				}
 			}
 			tt.fields.mockCtrl.Finish()
@@ -561,7 +889,7 @@ func Test_GetProfile(t *testing.T) {
 	}
 }
 
-func Test_UpdateProfile(t *testing.T) {
+func Test_Login(t *testing.T) {
 	type fields struct {
 		mockCtrl   *gomock.Controller
 		Repository *repository.MockRepositoryInterface
@@ -569,6 +897,8 @@ func Test_UpdateProfile(t *testing.T) {
 	type args struct {
 		ctx echo.Context
 	}
+	outdatedHash, _ := bcrypt.GenerateFromPassword([]byte("SawitPro123$"), bcrypt.MinCost+1)
+	maxPasswordAge := time.Duration(90) * 24 * time.Hour
 	tests := []struct {
 		name           string
 		fields         fields
@@ -578,7 +908,7 @@ func Test_UpdateProfile(t *testing.T) {
 		detailErr        error
 	}{
 		{
-			name: "invalid authorization",
+			name: "no request body",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -588,18 +918,18 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", nil)
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(``)))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
 			mock:           func(fields *fields) {},
-			statusCode: http.StatusForbidden,
+			statusCode: http.StatusBadRequest,
 			detailErr:        nil,
 		},
 		{
-			name: "no request body",
+			name: "error GetUserByPhoneNumber",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -609,22 +939,29 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(``)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
-			mock:           func(fields *fields) {},
-			statusCode: http.StatusBadRequest,
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
 			detailErr:        nil,
 		},
 		{
-			name: "no params at all",
+			name: "user not found",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -634,22 +971,38 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
-			mock:           func(fields *fields) {},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID: 0,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{{Key: "phone:+62821232342", FailedCount: 1, LastFailedAt: time.Now()}}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
 			statusCode: http.StatusBadRequest,
 			detailErr:        nil,
 		},
 		{
-			name: "all params have empty value",
+			name: "wrong password",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -659,25 +1012,39 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "",
-						"full_name": ""
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "Random@123"
 					}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
-			mock:           func(fields *fields) {},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:       1,
+						Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{{Key: "phone:+62821232342", FailedCount: 1, LastFailedAt: time.Now()}}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
 			statusCode: http.StatusBadRequest,
 			detailErr:        nil,
 		},
 		{
-			name: "all params have empty value",
+			name: "error GetLoginAttempts",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -687,25 +1054,25 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "",
-						"full_name": ""
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "Random@123"
 					}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
-			mock:           func(fields *fields) {},
-			statusCode: http.StatusBadRequest,
-			detailErr:        nil,
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, errors.New("expected GetLoginAttempts error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
 		},
 		{
-			name: "invalid phone number",
+			name: "account locked",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -715,25 +1082,33 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "123",
-						"full_name": "Some Full Name"
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "Random@123"
 					}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
-			mock:           func(fields *fields) {},
-			statusCode: http.StatusBadRequest,
-			detailErr:        nil,
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return([]repository.LoginAttempt{{
+						Key:          "phone:+62821232342",
+						FailedCount:  5,
+						LastFailedAt: time.Now(),
+						LockedUntil:  time.Now().Add(time.Minute),
+					}}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusTooManyRequests,
+			detailErr:  nil,
 		},
 		{
-			name: "error GetUserByPhoneNumber",
+			name: "delayed after recent failure",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -743,29 +1118,32 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
 						"phone_number": "+62821232342",
-						"full_name": "Some Full Name"
+						"password": "Random@123"
 					}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
 			mock: func(fields *fields) {
-				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
-					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return([]repository.LoginAttempt{{
+						Key:          "phone:+62821232342",
+						FailedCount:  3,
+						LastFailedAt: time.Now(),
+					}}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
 					Times(1)
 			},
-			statusCode: http.StatusInternalServerError,
-			detailErr:        nil,
+			statusCode: http.StatusTooManyRequests,
+			detailErr:  nil,
 		},
 		{
-			name: "phone number is already registered",
+			name: "error IncrementLoginAttempts",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -775,31 +1153,36 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
 						"phone_number": "+62821232342",
-						"full_name": "Some Full Name"
+						"password": "Random@123"
 					}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
 			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
 					Return(repository.User{
-						ID: 2,
+						ID:       1,
+						Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
 					}, nil).
 					Times(1)
+
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return(nil, errors.New("expected IncrementLoginAttempts error")).
+					Times(1)
 			},
-			statusCode: http.StatusConflict,
-			detailErr:        nil,
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
 		},
 		{
-			name: "error UpdateUser",
+			name: "wrong password locks account",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
@@ -809,91 +1192,6258 @@ func Test_UpdateProfile(t *testing.T) {
 			}(),
 			args: args{
 				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
 						"phone_number": "+62821232342",
-						"full_name": "Some Full Name"
+						"password": "Random@123"
 					}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
 					res := httptest.NewRecorder()
 					c := echo.New().NewContext(req, res)
 					return c
 				}(),
 			},
 			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
-					Return(repository.User{}, nil).
+					Return(repository.User{
+						ID:       1,
+						Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+					}, nil).
 					Times(1)
 
-				fields.Repository.EXPECT().UpdateUser(context.Background(),
-					repository.User{
-						ID:          1,
-						PhoneNumber: "+62821232342",
-						FullName:    "Some Full Name",
-					}).
-					Return(errors.New("expected UpdateUser error")).
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{{Key: "phone:+62821232342", FailedCount: 5, LastFailedAt: time.Now()}}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().LockLoginAttempt(context.Background(), "phone:+62821232342", gomock.Any()).
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetTOTPSecret",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, errors.New("expected GetTOTPSecret error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "two-factor authentication enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{
+						UserID:          1,
+						EncryptedSecret: "<encrypted>",
+						ConfirmedAt:     time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+		{
+			name: "error ResetLoginAttempts",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(errors.New("expected ResetLoginAttempts error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "phone number not verified",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:       1,
+						Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error InsertSession",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, errors.New("expected InsertSession error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "session limit reached",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), gomock.Any()).
+					Return(nil, repository.ErrSessionLimitReached).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.LoginEvent) error {
+						if data.Outcome != repository.LoginOutcomeFailure || data.FailureReason != errSessionLimit.Error() {
+							t.Errorf("Result When InsertLoginEvent() %+v", data)
+						}
+						return nil
+					}).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error InsertRefreshToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertRefreshToken error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "error InsertLoginEvent",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(errors.New("expected InsertLoginEvent error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.LoginEvent) error {
+						if data.UserID != 1 || data.Outcome != repository.LoginOutcomeSuccess {
+							t.Errorf("Result When InsertLoginEvent() %+v", data)
+						}
+						return nil
+					}).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+		{
+			name: "outdated password hash",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        string(outdatedHash),
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().RehashPassword(context.Background(), int64(1), string(outdatedHash), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, userID int64, oldHash string, newHash string) (bool, error) {
+						if cost, _ := bcrypt.Cost([]byte(newHash)); cost != bcrypt.MinCost {
+							t.Errorf("Result When RehashPassword() cost %d", cost)
+						}
+						return true, nil
+					}).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+		{
+			name: "error RehashPassword",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						Password:        string(outdatedHash),
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().RehashPassword(context.Background(), int64(1), string(outdatedHash), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, userID int64, oldHash string, newHash string) (bool, error) {
+						if cost, _ := bcrypt.Cost([]byte(newHash)); cost != bcrypt.MinCost {
+							t.Errorf("Result When RehashPassword() cost %d", cost)
+						}
+						return false, errors.New("expected RehashPassword error")
+					}).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+		{
+			name: "expired password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"password": "SawitPro123$"
+					}`)))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:                1,
+						PhoneNumber:       "+62821232342",
+						Password:          "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+						PhoneVerifiedAt:   time.Now(),
+						PasswordChangedAt: time.Now().Add(-maxPasswordAge - time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository:     tt.fields.Repository,
+				PasswordHasher: password.NewBcryptHasher(password.BcryptOptions{Cost: bcrypt.MinCost}),
+				MaxPasswordAge: maxPasswordAge,
+			}
+			tt.mock(&tt.fields)
+			err := s.Login(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When Login() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When Register() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+// Test_Login_enumerationSafe checks that an unknown phone number and a wrong
+// password get the same answer.
+func Test_Login_enumerationSafe(t *testing.T) {
+	body := `{"phone_number": "+62821232342", "password": "SawitPro123$"}`
+	otherHash, _ := bcrypt.GenerateFromPassword([]byte("SawitPro123%"), bcrypt.MinCost)
+	tests := []struct {
+		name   string
+		user   repository.User
+		reason string
+	}{
+		{
+			name:   "unknown phone number",
+			user:   repository.User{},
+			reason: "Phone number is not found",
+		},
+		{
+			name:   "wrong password",
+			reason: "Wrong password",
+			user: repository.User{
+				ID:          1,
+				PhoneNumber: "+62821232342",
+				Password:    string(otherHash),
+			},
+		},
+	}
+	var firstBody string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			repo := repository.NewMockRepositoryInterface(mockCtrl)
+			repo.EXPECT().GetLoginAttempts(context.Background(), gomock.Any()).
+				Return(nil, nil).
+				Times(1)
+			repo.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+				Return(tt.user, nil).
+				Times(1)
+			repo.EXPECT().IncrementLoginAttempts(context.Background(), gomock.Any(), gomock.Any()).
+				Return(nil, nil).
+				Times(1)
+			// The login history keeps the actual reason.
+			repo.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, data repository.LoginEvent) error {
+					if data.FailureReason != tt.reason {
+						t.Errorf("Result When InsertLoginEvent() %s, reason = %s", data.FailureReason, tt.reason)
+					}
+					return nil
+				}).
+				Times(1)
+
+			s := &Server{
+				Repository:      repo,
+				PasswordHasher:  password.NewBcryptHasher(password.BcryptOptions{Cost: bcrypt.MinCost}),
+				EnumerationSafe: true,
+			}
+			req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+			res := httptest.NewRecorder()
+			err := s.Login(echo.New().NewContext(req, res))
+			if err != nil {
+				t.Fatalf("Error When Login() %s", err.Error())
+			}
+			if res.Code != http.StatusBadRequest {
+				t.Errorf("Result When Login() %d, statusCode = %d", res.Code, http.StatusBadRequest)
+			}
+			if firstBody == "" {
+				firstBody = res.Body.String()
+			} else if res.Body.String() != firstBody {
+				t.Errorf("Result When Login() %s, want = %s", res.Body.String(), firstBody)
+			}
+		})
+	}
+}
+
+func Test_Login_hashingPoolSaturated(t *testing.T) {
+	body := `{"phone_number": "+62821232342", "password": "SawitPro123$"}`
+	hash, _ := bcrypt.GenerateFromPassword([]byte("SawitPro123$"), bcrypt.MinCost)
+	tests := []struct {
+		name string
+		user repository.User
+	}{
+		{
+			name: "unknown phone number",
+			user: repository.User{},
+		},
+		{
+			name: "registered phone number",
+			user: repository.User{
+				ID:          1,
+				PhoneNumber: "+62821232342",
+				Password:    string(hash),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			repo := repository.NewMockRepositoryInterface(mockCtrl)
+			repo.EXPECT().GetLoginAttempts(context.Background(), gomock.Any()).
+				Return(nil, nil).
+				Times(1)
+			repo.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+				Return(tt.user, nil).
+				Times(1)
+
+			s := &Server{
+				Repository:     repo,
+				PasswordHasher: password.NewBcryptHasher(password.BcryptOptions{Cost: bcrypt.MinCost}),
+				HashingPool:    saturatedPool(t),
+			}
+			req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+			res := httptest.NewRecorder()
+			err := s.Login(echo.New().NewContext(req, res))
+			if err != nil {
+				t.Fatalf("Error When Login() %s", err.Error())
+			}
+			if res.Code != http.StatusServiceUnavailable {
+				t.Errorf("Result When Login() %d, statusCode = %d", res.Code, http.StatusServiceUnavailable)
+			}
+			if res.Header().Get("Retry-After") != "1" {
+				t.Errorf("Result When Login() Retry-After = %q", res.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func Test_LoginMfa(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	user := repository.User{
+		ID:              1,
+		PhoneNumber:     "+62821232342",
+		PhoneVerifiedAt: time.Now(),
+	}
+	challengeToken, _ := (&Server{}).generateMFAChallengeToken(user)
+	accessToken, _ := (&Server{}).generateToken(user, "some-session")
+	encryptedSecret, _ := (&Server{}).encryptTOTPSecret("JBSWY3DPEHPK3PXP")
+	code, _ := totp.Code("JBSWY3DPEHPK3PXP", totp.Step(time.Now()))
+	body := func(token string, code string) string {
+		return fmt.Sprintf(`{"challenge_token": %q, "code": %q}`, token, code)
+	}
+	expectUser := func(fields *fields) {
+		fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+			Return(user, nil).
+			Times(1)
+		fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+			Return(nil, nil).
+			Times(1)
+	}
+	expectSecret := func(fields *fields) {
+		fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+			Return(repository.TOTPSecret{
+				UserID:          1,
+				EncryptedSecret: encryptedSecret,
+				ConfirmedAt:     time.Now(),
+			}, nil).
+			Times(1)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "access token instead of challenge token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(accessToken, code)),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, code)),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "locked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, code)),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(user, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  5,
+							LastFailedAt: time.Now(),
+							LockedUntil:  time.Now().Add(time.Minute),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusTooManyRequests,
+			detailErr:  nil,
+		},
+		{
+			name: "two-factor authentication disabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, code)),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "wrong code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, "000000")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectSecret(fields)
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  1,
+							LastFailedAt: time.Now(),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "code already used",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, code)),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectSecret(fields)
+				fields.Repository.EXPECT().UseTOTPStep(context.Background(), int64(1), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  1,
+							LastFailedAt: time.Now(),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "used recovery code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, "abcde-fghjk")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectSecret(fields)
+				fields.Repository.EXPECT().UseRecoveryCode(context.Background(), int64(1), hashRecoveryCode("abcdefghjk")).
+					Return(false, nil).
+					Times(1)
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  1,
+							LastFailedAt: time.Now(),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "recovery code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, "ABCDE-FGHJK")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectSecret(fields)
+				fields.Repository.EXPECT().UseRecoveryCode(context.Background(), int64(1), hashRecoveryCode("abcdefghjk")).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(challengeToken, code)),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectSecret(fields)
+				fields.Repository.EXPECT().UseTOTPStep(context.Background(), int64(1), gomock.Any()).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.LoginMfa(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When LoginMfa() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When LoginMfa() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_LoginPasswordChange(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	hasher := password.NewBcryptHasher(password.BcryptOptions{Cost: bcrypt.MinCost})
+	maxPasswordAge := time.Duration(90) * 24 * time.Hour
+	currentHash, _ := hasher.Hash("SawitPro123$")
+	previousHash, _ := hasher.Hash("OldPassword123$")
+	user := repository.User{
+		ID:                1,
+		PhoneNumber:       "+62821232342",
+		Password:          currentHash,
+		PhoneVerifiedAt:   time.Now(),
+		PasswordChangedAt: time.Now().Add(-maxPasswordAge - time.Hour),
+	}
+	changeToken, _ := (&Server{}).generatePasswordChangeToken(user)
+	accessToken, _ := (&Server{}).generateToken(user, "some-session")
+	body := func(token string, newPassword string) string {
+		return fmt.Sprintf(`{"change_token": %q, "new_password": %q}`, token, newPassword)
+	}
+	expectUser := func(fields *fields) {
+		fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+			Return(user, nil).
+			Times(1)
+	}
+	expectHistory := func(fields *fields) {
+		fields.Repository.EXPECT().GetPasswordHistory(context.Background(), int64(1), 3).
+			Return([]string{currentHash, previousHash}, nil).
+			Times(1)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "access token instead of change token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(accessToken, "NewPassword123$")),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "NewPassword123$")),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "password already changed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "NewPassword123$")),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:                1,
+						PhoneNumber:       "+62821232342",
+						Password:          currentHash,
+						PasswordChangedAt: time.Now(),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "invalid new password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "weak")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "same password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "SawitPro123$")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "weak new password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "Password1!")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetPasswordHistory",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "NewPassword123$")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				fields.Repository.EXPECT().GetPasswordHistory(context.Background(), int64(1), 3).
+					Return(nil, errors.New("expected GetPasswordHistory error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "reused password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "OldPassword123$")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectHistory(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error UpdatePassword",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "NewPassword123$")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectHistory(fields)
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					Return(int64(0), errors.New("expected UpdatePassword error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(body(changeToken, "NewPassword123$")),
+			},
+			mock: func(fields *fields) {
+				expectUser(fields)
+				expectHistory(fields)
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, userID int64, passwordHash string) (int64, error) {
+						if ok, _ := hasher.Verify(passwordHash, "NewPassword123$"); !ok {
+							t.Errorf("Result When UpdatePassword() %s", passwordHash)
+						}
+						return 1, nil
+					}).
+					Times(1)
+				fields.Repository.EXPECT().RevokeOtherSessions(context.Background(), int64(1), "").
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository:          tt.fields.Repository,
+				PasswordHasher:      hasher,
+				PasswordHistorySize: 3,
+				MaxPasswordAge:      maxPasswordAge,
+			}
+			tt.mock(&tt.fields)
+			err := s.LoginPasswordChange(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When LoginPasswordChange() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When LoginPasswordChange() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_RequestLoginOtp(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		SMSSender  *sms.MockSMSSender
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "invalid phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "0821"}`),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "locked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  5,
+							LastFailedAt: time.Now(),
+							LockedUntil:  time.Now().Add(time.Minute),
+						},
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusTooManyRequests,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "unknown phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+		{
+			name: "error sendOneTimeCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertOneTimeCode error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.OneTimeCode, limit repository.OneTimeCodeLimit) (int64, error) {
+						if data.UserID != 1 || data.Purpose != repository.OneTimeCodePurposeLogin || data.PhoneNumber != "+62821232342" {
+							t.Errorf("Result When InsertOneTimeCode() %+v", data)
+						}
+						return 1, nil
+					}).
+					Times(1)
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+				SMSSender:  tt.fields.SMSSender,
+			}
+			tt.mock(&tt.fields)
+			err := s.RequestLoginOtp(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RequestLoginOtp() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When RequestLoginOtp() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_LoginOtp(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	codeHash, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	activeCode := repository.OneTimeCode{
+		ID:          1,
+		UserID:      1,
+		Purpose:     repository.OneTimeCodePurposeLogin,
+		PhoneNumber: "+62821232342",
+		CodeHash:    string(codeHash),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "locked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  5,
+							LastFailedAt: time.Now(),
+							LockedUntil:  time.Now().Add(time.Minute),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusTooManyRequests,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "unknown phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  1,
+							LastFailedAt: time.Now(),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetActiveOneTimeCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeLogin).
+					Return(repository.OneTimeCode{}, errors.New("expected GetActiveOneTimeCode error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "wrong code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "654321"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeLogin).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  1,
+							LastFailedAt: time.Now(),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "too many attempts",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeLogin).
+					Return(repository.OneTimeCode{
+						ID:        1,
+						UserID:    1,
+						CodeHash:  string(codeHash),
+						Attempts:  maxOneTimeCodeAttempts,
+						ExpiresAt: time.Now().Add(time.Minute),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342"}, gomock.Any()).
+					Return([]repository.LoginAttempt{
+						{
+							Key:          "phone:+62821232342",
+							FailedCount:  1,
+							LastFailedAt: time.Now(),
+						},
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "two-factor authentication enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeLogin).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{
+						UserID:          1,
+						EncryptedSecret: "some-secret",
+						ConfirmedAt:     time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:              1,
+						PhoneNumber:     "+62821232342",
+						PhoneVerifiedAt: time.Now(),
+					}, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposeLogin).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertSession(context.Background(), gomock.Any(), repository.SessionLimit{EvictOldest: true}).
+					Return(nil, nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.Repository.EXPECT().InsertLoginEvent(context.Background(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.LoginOtp(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When LoginOtp() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When LoginOtp() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_AdminUnlock(t *testing.T) {
+	type fields struct {
+		mockCtrl    *gomock.Controller
+		Repository  *repository.MockRepositoryInterface
+		AdminAPIKey string
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(adminKey string, body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		if adminKey != "" {
+			req.Header.Set("X-Admin-Key", adminKey)
+		}
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "admin api disabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext("", `{"phone_number": "+62821232342"}`),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "wrong admin key",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:    mockCtrl,
+					Repository:  repository.NewMockRepositoryInterface(mockCtrl),
+					AdminAPIKey: "admin-key",
+				}
+			}(),
+			args: args{
+				ctx: newContext("other-key", `{"phone_number": "+62821232342"}`),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:    mockCtrl,
+					Repository:  repository.NewMockRepositoryInterface(mockCtrl),
+					AdminAPIKey: "admin-key",
+				}
+			}(),
+			args: args{
+				ctx: newContext("admin-key", ``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "nothing to unlock",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:    mockCtrl,
+					Repository:  repository.NewMockRepositoryInterface(mockCtrl),
+					AdminAPIKey: "admin-key",
+				}
+			}(),
+			args: args{
+				ctx: newContext("admin-key", `{}`),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error ResetLoginAttempts",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:    mockCtrl,
+					Repository:  repository.NewMockRepositoryInterface(mockCtrl),
+					AdminAPIKey: "admin-key",
+				}
+			}(),
+			args: args{
+				ctx: newContext("admin-key", `{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
+					Return(errors.New("expected ResetLoginAttempts error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:    mockCtrl,
+					Repository:  repository.NewMockRepositoryInterface(mockCtrl),
+					AdminAPIKey: "admin-key",
+				}
+			}(),
+			args: args{
+				ctx: newContext("admin-key", `{"phone_number": "+62821232342", "ip_address": "10.0.0.1"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342", "ip:10.0.0.1"}).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository:  tt.fields.Repository,
+				AdminAPIKey: tt.fields.AdminAPIKey,
+			}
+			tt.mock(&tt.fields)
+			err := s.AdminUnlock(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When AdminUnlock() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When AdminUnlock() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_GetMetrics(t *testing.T) {
+	pool := password.NewPool(password.PoolOptions{Concurrency: 2, QueueDepth: 3})
+	_ = pool.Do(context.Background(), func() {})
+	tests := []struct {
+		name        string
+		server      *Server
+		adminKey    string
+		statusCode  int
+		concurrency int
+		completed   int64
+	}{
+		{
+			name:       "admin api disabled",
+			server:     &Server{HashingPool: pool},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "wrong admin key",
+			server:     &Server{AdminAPIKey: "admin-key", HashingPool: pool},
+			adminKey:   "other-key",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "no hashing pool",
+			server:     &Server{AdminAPIKey: "admin-key"},
+			adminKey:   "admin-key",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:        "passed",
+			server:      &Server{AdminAPIKey: "admin-key", HashingPool: pool},
+			adminKey:    "admin-key",
+			statusCode:  http.StatusOK,
+			concurrency: 2,
+			completed:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "url", nil)
+			if tt.adminKey != "" {
+				req.Header.Set("X-Admin-Key", tt.adminKey)
+			}
+			res := httptest.NewRecorder()
+			err := tt.server.GetMetrics(echo.New().NewContext(req, res))
+			if err != nil {
+				t.Fatalf("Error When GetMetrics() %s", err.Error())
+			}
+			if res.Code != tt.statusCode {
+				t.Errorf("Result When GetMetrics() %d, statusCode = %d", res.Code, tt.statusCode)
+			}
+			if res.Code != http.StatusOK {
+				return
+			}
+			var body generated.MetricsResponse
+			if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Error When Unmarshal() %s", err.Error())
+			}
+			metrics := body.Data.PasswordHashing
+			if metrics.Concurrency != tt.concurrency || metrics.Completed != tt.completed {
+				t.Errorf("Result When GetMetrics() %+v, concurrency = %d, completed = %d", metrics, tt.concurrency, tt.completed)
+			}
+		})
+	}
+}
+
+func Test_RefreshToken(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	tokenHash := hashRefreshToken("some-refresh-token")
+	activeToken := repository.RefreshToken{
+		ID:        1,
+		UserID:    1,
+		FamilyID:  "some-family",
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	expectActiveSession := func(fields *fields) {
+		fields.Repository.EXPECT().TouchSession(context.Background(), "some-family", gomock.Any(), time.Time{}).
+			Return(repository.Session{
+				ID:        "some-family",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "empty refresh token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": ""}`),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetRefreshTokenByHash",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(repository.RefreshToken{}, errors.New("expected GetRefreshTokenByHash error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "refresh token not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(repository.RefreshToken{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "refresh token revoked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				revokedToken := activeToken
+				revokedToken.RevokedAt = time.Now()
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(revokedToken, nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "reused refresh token revokes session",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				rotatedToken := activeToken
+				rotatedToken.RotatedAt = time.Now()
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(rotatedToken, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeSession(context.Background(), "some-family").
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error RevokeSession",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				rotatedToken := activeToken
+				rotatedToken.RotatedAt = time.Now()
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(rotatedToken, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeSession(context.Background(), "some-family").
+					Return(errors.New("expected RevokeSession error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "refresh token expired",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				expiredToken := activeToken
+				expiredToken.ExpiresAt = time.Now().Add(-time.Hour)
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(expiredToken, nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error TouchSession",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(activeToken, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-family", gomock.Any(), time.Time{}).
+					Return(repository.Session{}, errors.New("expected TouchSession error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "session reached its maximum lifetime",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(activeToken, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-family", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-family",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(-time.Hour),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error RotateRefreshToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(activeToken, nil).
+					Times(1)
+
+				expectActiveSession(fields)
+
+				fields.Repository.EXPECT().RotateRefreshToken(context.Background(), int64(1)).
+					Return(false, errors.New("expected RotateRefreshToken error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "concurrent rotation revokes session",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(activeToken, nil).
+					Times(1)
+
+				expectActiveSession(fields)
+
+				fields.Repository.EXPECT().RotateRefreshToken(context.Background(), int64(1)).
+					Return(false, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeSession(context.Background(), "some-family").
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(activeToken, nil).
+					Times(1)
+
+				expectActiveSession(fields)
+
+				fields.Repository.EXPECT().RotateRefreshToken(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "error InsertRefreshToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(activeToken, nil).
+					Times(1)
+
+				expectActiveSession(fields)
+
+				fields.Repository.EXPECT().RotateRefreshToken(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{ID: 1, PhoneNumber: "+62821232342"}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertRefreshToken error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"refresh_token": "some-refresh-token"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(activeToken, nil).
+					Times(1)
+
+				expectActiveSession(fields)
+
+				fields.Repository.EXPECT().RotateRefreshToken(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{ID: 1, PhoneNumber: "+62821232342"}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.RefreshToken) (int64, error) {
+						if data.FamilyID != "some-family" || data.UserID != 1 {
+							t.Errorf("InsertRefreshToken() got unexpected data %+v", data)
+						}
+						return 2, nil
+					}).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.RefreshToken(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RefreshToken() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When RefreshToken() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_RefreshToken_cookie(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(csrfToken string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{}`)))
+		req.AddCookie(&http.Cookie{Name: refreshTokenCookie, Value: "some-refresh-token"})
+		req.AddCookie(&http.Cookie{Name: csrfTokenCookie, Value: "some-csrf-token"})
+		if csrfToken != "" {
+			req.Header.Set(csrfTokenHeader, csrfToken)
+		}
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	tokenHash := hashRefreshToken("some-refresh-token")
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no CSRF token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(""),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext("some-csrf-token"),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetRefreshTokenByHash(context.Background(), tokenHash).
+					Return(repository.RefreshToken{
+						ID:        1,
+						UserID:    1,
+						FamilyID:  "some-family",
+						TokenHash: tokenHash,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-family", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-family",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RotateRefreshToken(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{ID: 1, PhoneNumber: "+62821232342"}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(2), nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository:     tt.fields.Repository,
+				CookieSessions: true,
+			}
+			tt.mock(&tt.fields)
+			err := s.RefreshToken(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RefreshToken() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When RefreshToken() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			// A refresh replaces the cookies, a rejected one leaves them.
+			cookies := tt.args.ctx.Response().Header().Values("Set-Cookie")
+			if (len(cookies) != 0) != (tt.statusCode == http.StatusOK) {
+				t.Errorf("Result When RefreshToken() cookies = %v", cookies)
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_GetProfile(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		statusCode int
+		detailErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:        nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:        nil,
+		},
+		{
+			name: "error CountRecoveryCodes",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().CountRecoveryCodes(context.Background(), int64(1)).
+					Return(0, errors.New("expected CountRecoveryCodes error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().CountRecoveryCodes(context.Background(), int64(1)).
+					Return(10, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.GetProfile(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetProfile() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When Register() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_GetProfileLogins(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx    echo.Context
+		params generated.GetProfileLoginsParams
+	}
+	newContext := func(authorized bool) echo.Context {
+		req, _ := http.NewRequest(http.MethodGet, "url", nil)
+		if authorized {
+			jwt, _ := (&Server{}).generateToken(repository.User{
+				ID: 1,
+			}, "some-session")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		}
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	expectSession := func(fields *fields) {
+		fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+			Return(repository.Session{
+				ID:        "some-session",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1)
+	}
+	limit, tooLarge := 2, 101
+	cursor, invalidCursor := encodeLoginCursor(9), "not-a-cursor"
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		logins     int
+		nextCursor string
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:    newContext(false),
+				params: generated.GetProfileLoginsParams{},
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			logins:     0,
+			nextCursor: "",
+		},
+		{
+			name: "invalid limit",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:    newContext(true),
+				params: generated.GetProfileLoginsParams{Limit: &tooLarge},
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			logins:     0,
+			nextCursor: "",
+		},
+		{
+			name: "invalid cursor",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:    newContext(true),
+				params: generated.GetProfileLoginsParams{Cursor: &invalidCursor},
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			logins:     0,
+			nextCursor: "",
+		},
+		{
+			name: "error GetLoginEvents",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:    newContext(true),
+				params: generated.GetProfileLoginsParams{},
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				fields.Repository.EXPECT().GetLoginEvents(context.Background(), int64(1), int64(0), 21).
+					Return(nil, errors.New("expected GetLoginEvents error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			logins:     0,
+			nextCursor: "",
+		},
+		{
+			name: "no logins",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:    newContext(true),
+				params: generated.GetProfileLoginsParams{},
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				fields.Repository.EXPECT().GetLoginEvents(context.Background(), int64(1), int64(0), 21).
+					Return(nil, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			logins:     0,
+			nextCursor: "",
+		},
+		{
+			name: "first page",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:    newContext(true),
+				params: generated.GetProfileLoginsParams{Limit: &limit},
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				fields.Repository.EXPECT().GetLoginEvents(context.Background(), int64(1), int64(0), 3).
+					Return([]repository.LoginEvent{repository.LoginEvent{ID: 10, UserID: 1, IPAddress: "192.0.2.1", UserAgent: "curl/8.0", Outcome: repository.LoginOutcomeSuccess, CreatedAt: time.Now()}, repository.LoginEvent{ID: 9, UserID: 1, IPAddress: "192.0.2.1", UserAgent: "curl/8.0", Outcome: repository.LoginOutcomeSuccess, CreatedAt: time.Now()}, repository.LoginEvent{ID: 8, UserID: 1, IPAddress: "192.0.2.1", UserAgent: "curl/8.0", Outcome: repository.LoginOutcomeSuccess, CreatedAt: time.Now()}}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			logins:     2,
+			nextCursor: "OQ",
+		},
+		{
+			name: "last page",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx:    newContext(true),
+				params: generated.GetProfileLoginsParams{Limit: &limit, Cursor: &cursor},
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				fields.Repository.EXPECT().GetLoginEvents(context.Background(), int64(1), int64(9), 3).
+					Return([]repository.LoginEvent{repository.LoginEvent{ID: 10, UserID: 1, IPAddress: "192.0.2.1", UserAgent: "curl/8.0", Outcome: repository.LoginOutcomeSuccess, CreatedAt: time.Now()}, repository.LoginEvent{ID: 9, UserID: 1, IPAddress: "192.0.2.1", UserAgent: "curl/8.0", Outcome: repository.LoginOutcomeSuccess, CreatedAt: time.Now()}}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			logins:     2,
+			nextCursor: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.GetProfileLogins(tt.args.ctx, tt.args.params)
+			if err != nil {
+				t.Errorf("Error When GetProfileLogins() %s", err.Error())
+			}
+			if tt.args.ctx.Response().Status != tt.statusCode {
+				t.Errorf("Result When GetProfileLogins() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+			}
+			if tt.statusCode == http.StatusOK {
+				var body generated.GetProfileLoginsResponse
+				_ = json.Unmarshal(tt.args.ctx.Response().Writer.(*httptest.ResponseRecorder).Body.Bytes(), &body)
+				var nextCursor string
+				if body.Data.NextCursor != nil {
+					nextCursor = *body.Data.NextCursor
+				}
+				if len(body.Data.Logins) != tt.logins || nextCursor != tt.nextCursor {
+					t.Errorf("Result When GetProfileLogins() %d logins, next cursor %q, logins = %d, nextCursor = %q", len(body.Data.Logins), nextCursor, tt.logins, tt.nextCursor)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_UpdateProfile(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		SMSSender  *sms.MockSMSSender
+
+		NotifyPhoneNumberChange bool
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	tests := []struct {
+		name           string
+		fields         fields
+		args           args
+		mock           func(fields *fields)
+		statusCode int
+		detailErr        error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", nil)
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock:           func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:        nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(``)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:        nil,
+		},
+		{
+			name: "no params at all",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:        nil,
+		},
+		{
+			name: "all params have empty value",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "",
+						"full_name": ""
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:        nil,
+		},
+		{
+			name: "all params have empty value",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "",
+						"full_name": ""
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:        nil,
+		},
+		{
+			name: "invalid phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "123",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:        nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:        nil,
+		},
+		{
+			name: "phone number is already registered",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID: 2,
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusConflict,
+			detailErr:        nil,
+		},
+		{
+			name: "error UpdateUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Some Full Name",
+					}).
+					Return(errors.New("expected UpdateUser error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:        nil,
+		},
+		{
+			name: "own phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Some Full Name",
+					}).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+		{
+			name: "error requestPhoneNumberChange",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Some Full Name",
+					}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertOneTimeCode error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:        nil,
+		},
+		{
+			name: "too many codes",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Some Full Name",
+					}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), repository.ErrOneTimeCodeLimitReached).
+					Times(1)
+			},
+			statusCode: http.StatusTooManyRequests,
+			detailErr:        nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Some Full Name",
+					}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, code repository.OneTimeCode, limit repository.OneTimeCodeLimit) (int64, error) {
+						if code.UserID != 1 || code.Purpose != repository.OneTimeCodePurposePhoneChange || code.PhoneNumber != "+62821232342" {
+							t.Errorf("Result When InsertOneTimeCode() %+v", code)
+						}
+						return int64(1), nil
+					}).
+					Times(1)
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+		{
+			name: "notify current phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+
+					NotifyPhoneNumberChange: true,
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
+						"phone_number": "+62821232342",
+						"full_name": "Some Full Name"
+					}`)))
+					jwt, _ := (&Server{}).generateToken(repository.User{
+						ID: 1,
+					}, "some-session")
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+					res := httptest.NewRecorder()
+					c := echo.New().NewContext(req, res)
+					return c
+				}(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdateUser(context.Background(),
+					repository.User{
+						ID:       1,
+						FullName: "Some Full Name",
+					}).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821111111",
+					}, nil).
+					Times(1)
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821111111", phoneChangeNotice).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+				SMSSender:  tt.fields.SMSSender,
+
+				NotifyPhoneNumberChange: tt.fields.NotifyPhoneNumberChange,
+			}
+			tt.mock(&tt.fields)
+			err := s.UpdateProfile(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When UpdateProfile() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When Register() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_VerifyPhoneNumberChange(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		jwt, _ := (&Server{}).generateToken(repository.User{
+			ID: 1,
+		}, "some-session")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	expectSession := func(fields *fields) {
+		fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+			Return(repository.Session{
+				ID:        "some-session",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1)
+	}
+	codeHash, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	expectCode := func(fields *fields) {
+		fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePhoneChange).
+			Return(repository.OneTimeCode{
+				ID:          1,
+				UserID:      1,
+				Purpose:     repository.OneTimeCodePurposePhoneChange,
+				PhoneNumber: "+62821232342",
+				CodeHash:    string(codeHash),
+				ExpiresAt:   time.Now().Add(time.Minute),
+			}, nil).
+			Times(1)
+		fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+			Return(true, nil).
+			Times(1)
+		fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+			Return(true, nil).
+			Times(1)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(`{"code": "123456"}`)))
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       expectSession,
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "no pending change",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePhoneChange).
+					Return(repository.OneTimeCode{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetActiveOneTimeCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePhoneChange).
+					Return(repository.OneTimeCode{}, errors.New("expected GetActiveOneTimeCode error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectCode(fields)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "phone number registered meanwhile",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectCode(fields)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          2,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusConflict,
+			detailErr:  nil,
+		},
+		{
+			name: "error UpdateUser",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectCode(fields)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().UpdateUser(context.Background(), gomock.Any()).
+					Return(errors.New("expected UpdateUser error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "phone number registered concurrently",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectCode(fields)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().UpdateUser(context.Background(), gomock.Any()).
+					Return(repository.ErrPhoneNumberTaken).
+					Times(1)
+			},
+			statusCode: http.StatusConflict,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "123456"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectCode(fields)
+
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+				fields.Repository.EXPECT().UpdateUser(context.Background(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.User) error {
+						if data.ID != 1 || data.PhoneNumber != "+62821232342" || data.PhoneVerifiedAt.IsZero() {
+							t.Errorf("Result When UpdateUser() %+v", data)
+						}
+						return nil
+					}).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.VerifyPhoneNumberChange(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When VerifyPhoneNumberChange() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When VerifyPhoneNumberChange() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_EnrollTotp(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		jwt, _ := (&Server{}).generateToken(repository.User{
+			ID:          1,
+			PhoneNumber: "+62821232342",
+		}, "some-session")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	expectSession := func(fields *fields) {
+		fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+			Return(repository.Session{
+				ID:        "some-session",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error SaveTOTPSecret",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().SaveTOTPSecret(context.Background(), gomock.Any()).
+					Return(false, errors.New("expected SaveTOTPSecret error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "already enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().SaveTOTPSecret(context.Background(), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+			},
+			statusCode: http.StatusConflict,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().SaveTOTPSecret(context.Background(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.TOTPSecret) (bool, error) {
+						secret, err := (&Server{}).decryptTOTPSecret(data.EncryptedSecret)
+						if data.UserID != 1 || err != nil || len(secret) == 0 {
+							t.Errorf("Result When SaveTOTPSecret() %+v", data)
+						}
+						return true, nil
+					}).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.EnrollTotp(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When EnrollTotp() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When EnrollTotp() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_ConfirmTotp(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		jwt, _ := (&Server{}).generateToken(repository.User{
+			ID:          1,
+			PhoneNumber: "+62821232342",
+		}, "some-session")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	expectSession := func(fields *fields) {
+		fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+			Return(repository.Session{
+				ID:        "some-session",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1)
+	}
+	encryptedSecret, _ := (&Server{}).encryptTOTPSecret("JBSWY3DPEHPK3PXP")
+	code, _ := totp.Code("JBSWY3DPEHPK3PXP", totp.Step(time.Now()))
+	pendingSecret := repository.TOTPSecret{
+		UserID:          1,
+		EncryptedSecret: encryptedSecret,
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       expectSession,
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetTOTPSecret",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(fmt.Sprintf(`{"code": %q}`, code)),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, errors.New("expected GetTOTPSecret error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "enrollment not started",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(fmt.Sprintf(`{"code": %q}`, code)),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "already enabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(fmt.Sprintf(`{"code": %q}`, code)),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{
+						UserID:          1,
+						EncryptedSecret: encryptedSecret,
+						ConfirmedAt:     time.Now(),
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusConflict,
+			detailErr:  nil,
+		},
+		{
+			name: "wrong code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"code": "abcdef"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(pendingSecret, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error ConfirmTOTPSecret",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(fmt.Sprintf(`{"code": %q}`, code)),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(pendingSecret, nil).
+					Times(1)
+				fields.Repository.EXPECT().ConfirmTOTPSecret(context.Background(), int64(1), totp.Step(time.Now())).
+					Return(false, errors.New("expected ConfirmTOTPSecret error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "error ReplaceRecoveryCodes",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(fmt.Sprintf(`{"code": %q}`, code)),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(pendingSecret, nil).
+					Times(1)
+				fields.Repository.EXPECT().ConfirmTOTPSecret(context.Background(), int64(1), totp.Step(time.Now())).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReplaceRecoveryCodes(context.Background(), int64(1), gomock.Any()).
+					Return(errors.New("expected ReplaceRecoveryCodes error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(fmt.Sprintf(`{"code": %q}`, code)),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(pendingSecret, nil).
+					Times(1)
+				fields.Repository.EXPECT().ConfirmTOTPSecret(context.Background(), int64(1), totp.Step(time.Now())).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReplaceRecoveryCodes(context.Background(), int64(1), gomock.Len(recoveryCodeCount)).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.ConfirmTotp(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When ConfirmTotp() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When ConfirmTotp() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_RegenerateRecoveryCodes(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(authorized bool) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", nil)
+		if authorized {
+			jwt, _ := (&Server{}).generateToken(repository.User{
+				ID:          1,
+				PhoneNumber: "+62821232342",
+			}, "some-session")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		}
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	expectSession := func(fields *fields) {
+		fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+			Return(repository.Session{
+				ID:        "some-session",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1)
+	}
+	confirmedSecret := repository.TOTPSecret{
+		UserID:          1,
+		EncryptedSecret: "some-secret",
+		ConfirmedAt:     time.Now(),
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(false),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetTOTPSecret",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{}, errors.New("expected GetTOTPSecret error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "two-factor authentication disabled",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(repository.TOTPSecret{UserID: 1, EncryptedSecret: "some-secret"}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error ReplaceRecoveryCodes",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(confirmedSecret, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReplaceRecoveryCodes(context.Background(), int64(1), gomock.Any()).
+					Return(errors.New("expected ReplaceRecoveryCodes error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+
+				fields.Repository.EXPECT().GetTOTPSecret(context.Background(), int64(1)).
+					Return(confirmedSecret, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReplaceRecoveryCodes(context.Background(), int64(1), gomock.Len(recoveryCodeCount)).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.RegenerateRecoveryCodes(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RegenerateRecoveryCodes() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When RegenerateRecoveryCodes() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_Logout(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func() echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", nil)
+		jwt, _ := (&Server{}).generateToken(repository.User{
+			ID: 1,
+		}, "some-session")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPost, "url", nil)
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error RevokeSession",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeSession(context.Background(), "some-session").
+					Return(errors.New("expected RevokeSession error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeSession(context.Background(), "some-session").
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.Logout(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When Logout() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When Logout() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_RevokeSession(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+		id  string
+	}
+	newContext := func() echo.Context {
+		req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+		jwt, _ := (&Server{}).generateToken(repository.User{
+			ID: 1,
+		}, "some-session")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	currentSession := repository.Session{
+		ID:        "some-session",
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+				id: "other-session",
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetSessionByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(),
+				id:  "other-session",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetSessionByID(context.Background(), "other-session").
+					Return(repository.Session{}, errors.New("expected GetSessionByID error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "session not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(),
+				id:  "other-session",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetSessionByID(context.Background(), "other-session").
+					Return(repository.Session{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusNotFound,
+			detailErr:  nil,
+		},
+		{
+			name: "session of another user",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(),
+				id:  "other-session",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetSessionByID(context.Background(), "other-session").
+					Return(repository.Session{
+						ID:     "other-session",
+						UserID: 2,
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusNotFound,
+			detailErr:  nil,
+		},
+		{
+			name: "error RevokeSession",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(),
+				id:  "other-session",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetSessionByID(context.Background(), "other-session").
+					Return(repository.Session{
+						ID:     "other-session",
+						UserID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeSession(context.Background(), "other-session").
+					Return(errors.New("expected RevokeSession error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(),
+				id:  "other-session",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetSessionByID(context.Background(), "other-session").
+					Return(repository.Session{
+						ID:     "other-session",
+						UserID: 1,
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().RevokeSession(context.Background(), "other-session").
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.RevokeSession(tt.args.ctx, tt.args.id)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RevokeSession() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When RevokeSession() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_ListSessions(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(authorized bool) echo.Context {
+		req, _ := http.NewRequest(http.MethodGet, "url", nil)
+		if authorized {
+			jwt, _ := (&Server{}).generateToken(repository.User{
+				ID: 1,
+			}, "some-session")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		}
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	currentSession := repository.Session{
+		ID:        "some-session",
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	deviceName := "Work laptop"
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		sessions   []generated.ActiveSession
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(false),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "error GetActiveSessions",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetActiveSessions(context.Background(), int64(1), time.Time{}).
+					Return(nil, errors.New("expected GetActiveSessions error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+				fields.Repository.EXPECT().GetActiveSessions(context.Background(), int64(1), time.Time{}).
+					Return([]repository.Session{
+						{
+							ID:         "some-session",
+							UserID:     1,
+							UserAgent:  "curl/8.0",
+							IPAddress:  "192.0.2.1",
+							CreatedAt:  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+							LastSeenAt: time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+						},
+						{
+							ID:         "other-session",
+							UserID:     1,
+							DeviceName: "Work laptop",
+							UserAgent:  "Mozilla/5.0",
+							IPAddress:  "192.0.2.2",
+							CreatedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+							LastSeenAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+						},
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			sessions: []generated.ActiveSession{
+				{
+					Id:         "some-session",
+					UserAgent:  "curl/8.0",
+					IpAddress:  "192.0.2.1",
+					CreatedAt:  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+					LastSeenAt: time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+					Current:    true,
+				},
+				{
+					Id:         "other-session",
+					DeviceName: &deviceName,
+					UserAgent:  "Mozilla/5.0",
+					IpAddress:  "192.0.2.2",
+					CreatedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					LastSeenAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.ListSessions(tt.args.ctx)
+			if err != nil {
+				t.Errorf("Error When ListSessions() %s", err.Error())
+			}
+			if tt.args.ctx.Response().Status != tt.statusCode {
+				t.Errorf("Result When ListSessions() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+			}
+			if tt.statusCode == http.StatusOK {
+				var body generated.ListSessionsResponse
+				_ = json.Unmarshal(tt.args.ctx.Response().Writer.(*httptest.ResponseRecorder).Body.Bytes(), &body)
+				if !reflect.DeepEqual(body.Data.Sessions, tt.sessions) {
+					t.Errorf("Result When ListSessions() %+v, sessions = %+v", body.Data.Sessions, tt.sessions)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_RevokeOtherSessions(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(authorized bool) echo.Context {
+		req, _ := http.NewRequest(http.MethodDelete, "url", nil)
+		if authorized {
+			jwt, _ := (&Server{}).generateToken(repository.User{
+				ID: 1,
+			}, "some-session")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		}
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	currentSession := repository.Session{
+		ID:        "some-session",
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(false),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "error RevokeOtherSessions",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+				fields.Repository.EXPECT().RevokeOtherSessions(context.Background(), int64(1), "some-session").
+					Return(nil, errors.New("expected RevokeOtherSessions error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(true),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(currentSession, nil).
+					Times(1)
+				fields.Repository.EXPECT().RevokeOtherSessions(context.Background(), int64(1), "some-session").
+					Return([]string{"other-session"}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.RevokeOtherSessions(tt.args.ctx)
+			if err != nil {
+				t.Errorf("Error When RevokeOtherSessions() %s", err.Error())
+			}
+			if tt.args.ctx.Response().Status != tt.statusCode {
+				t.Errorf("Result When RevokeOtherSessions() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+			}
+			if tt.statusCode == http.StatusOK {
+				if entry, found := s.sessionCache.get("other-session"); !found || entry.err != errSessionRevoked {
+					t.Errorf("Result When RevokeOtherSessions() other-session is not revoked in the cache")
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_GetJwks(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "url", nil)
+	res := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, res)
+
+	s := &Server{}
+	err := s.GetJwks(ctx)
+	if err != nil {
+		t.Errorf("Error When GetJwks() %s", err.Error())
+	}
+	if ctx.Response().Status != http.StatusOK {
+		t.Errorf("Result When GetJwks() %d, statusCode = %d", ctx.Response().Status, http.StatusOK)
+	}
+
+	var body generated.JsonWebKeySet
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Errorf("Error When GetJwks() body %s", err.Error())
+	}
+	if len(body.Keys) != 1 || body.Keys[0].Kid != defaultKeyID {
+		t.Errorf("Result When GetJwks() %+v", body)
+	}
+}
+
+func Test_UpdatePassword(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(body)))
+		jwt, _ := (&Server{}).generateToken(repository.User{
+			ID: 1,
+		}, "some-session")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	expectSession := func(fields *fields) {
+		fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+			Return(repository.Session{
+				ID:        "some-session",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1)
+	}
+	expectUser := func(fields *fields) {
+		fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+			Return(repository.User{
+				ID:       1,
+				Password: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+			}, nil).
+			Times(1)
+	}
+	validBody := `{"current_password": "SawitPro123$", "new_password": "NewPassword123$"}`
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "invalid authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodPut, "url", bytes.NewBuffer([]byte(validBody)))
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusForbidden,
+			detailErr:  nil,
+		},
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByID",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(validBody),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				fields.Repository.EXPECT().GetUserByID(context.Background(), int64(1)).
+					Return(repository.User{}, errors.New("expected GetUserByID error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "wrong current password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"current_password": "Random@123", "new_password": "NewPassword123$"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectUser(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "invalid new password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"current_password": "SawitPro123$", "new_password": "weak"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectUser(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "same password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"current_password": "SawitPro123$", "new_password": "SawitPro123$"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectUser(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "weak new password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"current_password": "SawitPro123$", "new_password": "Password1!"}`),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectUser(fields)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error UpdatePassword",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(validBody),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectUser(fields)
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					Return(int64(0), errors.New("expected UpdatePassword error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "error RevokeOtherSessions",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(validBody),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectUser(fields)
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.Repository.EXPECT().RevokeOtherSessions(context.Background(), int64(1), "some-session").
+					Return(nil, errors.New("expected RevokeOtherSessions error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(validBody),
+			},
+			mock: func(fields *fields) {
+				expectSession(fields)
+				expectUser(fields)
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, userID int64, passwordHash string) (int64, error) {
+						if ok, _ := (&Server{}).comparePasswords(ctx, passwordHash, "NewPassword123$"); !ok {
+							t.Errorf("Result When UpdatePassword() %s", passwordHash)
+						}
+						return 1, nil
+					}).
+					Times(1)
+				fields.Repository.EXPECT().RevokeOtherSessions(context.Background(), int64(1), "some-session").
+					Return([]string{"other-session"}, nil).
+					Times(1)
+				// The next request of the session, with the new token.
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:           "some-session",
+						UserID:       1,
+						ExpiresAt:    time.Now().Add(time.Hour),
+						TokenVersion: 1,
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			err := s.UpdatePassword(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When UpdatePassword() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When UpdatePassword() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			if entry, found := s.sessionCache.get("other-session"); tt.statusCode == http.StatusOK && (!found || entry.err != errSessionRevoked) {
+				t.Errorf("Result When UpdatePassword() other session is not marked as revoked")
+			}
+			if tt.statusCode == http.StatusOK {
+				// The session must go on with the token of the response.
+				var body generated.UpdatePasswordResponse
+				_ = json.Unmarshal(tt.args.ctx.Response().Writer.(*httptest.ResponseRecorder).Body.Bytes(), &body)
+				if body.Data == nil || body.Data.Jwt == nil {
+					t.Fatalf("Result When UpdatePassword() no new token")
+				}
+				req, _ := http.NewRequest(http.MethodGet, "url", nil)
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *body.Data.Jwt))
+				claims, err := s.getSessionClaims(echo.New().NewContext(req, httptest.NewRecorder()))
+				if err != nil || claims.TokenVersion != 1 {
+					t.Errorf("Error When getSessionClaims() with the new token %v, %+v", err, claims)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_RequestPasswordReset(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		SMSSender  *sms.MockSMSSender
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "invalid phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "0821"}`),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "unknown phone number",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+		{
+			name: "error sendOneTimeCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertOneTimeCode error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "too many codes",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), repository.ErrOneTimeCodeLimitReached).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.SMSSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			statusCode: http.StatusOK,
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+				SMSSender:  tt.fields.SMSSender,
+			}
+			tt.mock(&tt.fields)
+			err := s.RequestPasswordReset(tt.args.ctx)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RequestPasswordReset() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil {
+				if tt.args.ctx.Response().Status != tt.statusCode {
+					t.Errorf("Result When RequestPasswordReset() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_ResetPassword(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+		SMSSender  *sms.MockSMSSender
+	}
+	type args struct {
+		ctx echo.Context
+	}
+	newContext := func(body string) echo.Context {
+		req, _ := http.NewRequest(http.MethodPost, "url", bytes.NewBuffer([]byte(body)))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
+	codeHash, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	activeCode := repository.OneTimeCode{
+		ID:          1,
+		UserID:      1,
+		Purpose:     repository.OneTimeCodePurposePasswordReset,
+		PhoneNumber: "+62821232342",
+		CodeHash:    string(codeHash),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	tests := []struct {
+		name       string
+		fields     fields
+		args       args
+		mock       func(fields *fields)
+		statusCode int
+		detailErr  error
+	}{
+		{
+			name: "no request body",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(``),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "invalid new password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "weak"}`),
+			},
+			mock:       func(fields *fields) {},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetUserByPhoneNumber",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "NewPassword123$"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{}, errors.New("expected GetUserByPhoneNumber error")).
 					Times(1)
 			},
 			statusCode: http.StatusInternalServerError,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 		{
-			name: "passed",
+			name: "unknown phone number",
 			fields: func() fields {
 				mockCtrl := gomock.NewController(t)
 				return fields{
 					mockCtrl:   mockCtrl,
 					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
 				}
 			}(),
 			args: args{
-				ctx: func() echo.Context {
-					req, _ := http.NewRequest(http.MethodPatch, "url", bytes.NewBuffer([]byte(`{
-						"phone_number": "+62821232342",
-						"full_name": "Some Full Name"
-					}`)))
-					jwt, _ := generateToken(repository.User{
-						ID: 1,
-					})
-					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
-					res := httptest.NewRecorder()
-					c := echo.New().NewContext(req, res)
-					return c
-				}(),
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "NewPassword123$"}`),
 			},
 			mock: func(fields *fields) {
 				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
 					Return(repository.User{}, nil).
 					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "weak new password",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "Password1!"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "wrong code",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "654321", "new_password": "NewPassword123$"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
 
-				fields.Repository.EXPECT().UpdateUser(context.Background(),
-					repository.User{
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+			},
+			statusCode: http.StatusBadRequest,
+			detailErr:  nil,
+		},
+		{
+			name: "error GetActiveOneTimeCode",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "NewPassword123$"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(repository.OneTimeCode{}, errors.New("expected GetActiveOneTimeCode error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "error UpdatePassword",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "NewPassword123$"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					Return(int64(0), errors.New("expected UpdatePassword error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "error RevokeOtherSessions",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "NewPassword123$"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
+						ID:          1,
+						PhoneNumber: "+62821232342",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				fields.Repository.EXPECT().RevokeOtherSessions(context.Background(), int64(1), "").
+					Return(nil, errors.New("expected RevokeOtherSessions error")).
+					Times(1)
+			},
+			statusCode: http.StatusInternalServerError,
+			detailErr:  nil,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+					SMSSender:  sms.NewMockSMSSender(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(`{"phone_number": "+62821232342", "code": "123456", "new_password": "NewPassword123$"}`),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().GetUserByPhoneNumber(context.Background(), "+62821232342").
+					Return(repository.User{
 						ID:          1,
 						PhoneNumber: "+62821232342",
-						FullName:    "Some Full Name",
+					}, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				fields.Repository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				fields.Repository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+
+				fields.Repository.EXPECT().UpdatePassword(context.Background(), int64(1), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, userID int64, passwordHash string) (int64, error) {
+						if ok, _ := (&Server{}).comparePasswords(ctx, passwordHash, "NewPassword123$"); !ok {
+							t.Errorf("Result When UpdatePassword() %s", passwordHash)
+						}
+						return 1, nil
 					}).
+					Times(1)
+				fields.Repository.EXPECT().RevokeOtherSessions(context.Background(), int64(1), "").
+					Return([]string{"some-session"}, nil).
+					Times(1)
+				fields.Repository.EXPECT().ResetLoginAttempts(context.Background(), []string{"phone:+62821232342"}).
 					Return(nil).
 					Times(1)
 			},
 			statusCode: http.StatusOK,
-			detailErr:        nil,
+			detailErr:  nil,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Server{
 				Repository: tt.fields.Repository,
+				SMSSender:  tt.fields.SMSSender,
 			}
 			tt.mock(&tt.fields)
-			err := s.UpdateProfile(tt.args.ctx)
+			err := s.ResetPassword(tt.args.ctx)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
-				t.Errorf("Error When UpdateProfile() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+				t.Errorf("Error When ResetPassword() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
 			}
 			if err == nil {
 				if tt.args.ctx.Response().Status != tt.statusCode {
-					t.Errorf("Result When Register() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
+					t.Errorf("Result When ResetPassword() %d, statusCode = %d", tt.args.ctx.Response().Status, tt.statusCode)
 				}
 			}
 			tt.fields.mockCtrl.Finish()
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/Richthonio10/requirement-swtpro/generated"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	defaultKeys     *KeySet
+	defaultKeysOnce sync.Once
+)
+
+// KeySet holds the key used to sign new tokens and every public key tokens
+// may still be verified with. Keeping retired public keys around lets the
+// signing key be rotated without invalidating tokens that are still in use.
+type KeySet struct {
+	signingKeyID string
+	signingKey   *rsa.PrivateKey
+	verifyKeys   map[string]*rsa.PublicKey
+}
+
+type NewKeySetOptions struct {
+	SigningKeyID  string
+	SigningKeyPEM []byte
+	// VerifyKeyPEMs are additional public keys indexed by key id, usually
+	// the keys that signed tokens before the last rotation.
+	VerifyKeyPEMs map[string][]byte
+}
+
+func NewKeySet(opts NewKeySetOptions) (*KeySet, error) {
+	if opts.SigningKeyID == "" {
+		return nil, errors.New("signing key id is required")
+	}
+
+	signingKey, err := jwt.ParseRSAPrivateKeyFromPEM(opts.SigningKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key %s: %w", opts.SigningKeyID, err)
+	}
+
+	verifyKeys := map[string]*rsa.PublicKey{
+		opts.SigningKeyID: &signingKey.PublicKey,
+	}
+	for keyID, keyPEM := range opts.VerifyKeyPEMs {
+		if _, found := verifyKeys[keyID]; found {
+			return nil, fmt.Errorf("duplicate key id %s", keyID)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("parse verify key %s: %w", keyID, err)
+		}
+		verifyKeys[keyID] = key
+	}
+
+	return &KeySet{
+		signingKeyID: opts.SigningKeyID,
+		signingKey:   signingKey,
+		verifyKeys:   verifyKeys,
+	}, nil
+}
+
+func getDefaultKeySet() *KeySet {
+	defaultKeysOnce.Do(func() {
+		keys, err := NewKeySet(NewKeySetOptions{
+			SigningKeyID:  defaultKeyID,
+			SigningKeyPEM: []byte(privateRSA),
+		})
+		if err != nil {
+			panic(err)
+		}
+		defaultKeys = keys
+	})
+	return defaultKeys
+}
+
+func (s *Server) keySet() *KeySet {
+	if s.Keys != nil {
+		return s.Keys
+	}
+	return getDefaultKeySet()
+}
+
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = k.signingKeyID
+	return t.SignedString(k.signingKey)
+}
+
+// verifyKey is a jwt.Keyfunc picking the public key named by the kid header.
+// Tokens issued before key ids were introduced carry no kid and are checked
+// against the current signing key.
+func (k *KeySet) verifyKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+
+	keyID, _ := token.Header["kid"].(string)
+	if keyID == "" {
+		return &k.signingKey.PublicKey, nil
+	}
+
+	key, found := k.verifyKeys[keyID]
+	if !found {
+		return nil, fmt.Errorf("unknown key id %s", keyID)
+	}
+	return key, nil
+}
+
+func (k *KeySet) jwks() generated.JsonWebKeySet {
+	keyIDs := make([]string, 0, len(k.verifyKeys))
+	for keyID := range k.verifyKeys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	keys := make([]generated.JsonWebKey, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		key := k.verifyKeys[keyID]
+		keys = append(keys, generated.JsonWebKey{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: keyID,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+
+	return generated.JsonWebKeySet{
+		Keys: keys,
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func generateTestKeyPEM(t *testing.T) (privatePEM []byte, publicPEM []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("[generateTestKeyPEM] %s", err.Error())
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("[generateTestKeyPEM] %s", err.Error())
+	}
+	privatePEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	return privatePEM, publicPEM
+}
+
+func Test_NewKeySet(t *testing.T) {
+	_, publicPEM := generateTestKeyPEM(t)
+	type args struct {
+		opts NewKeySetOptions
+	}
+	tests := []struct {
+		name      string
+		args      args
+		detailErr error
+	}{
+		{
+			name: "missing key id",
+			args: args{
+				opts: NewKeySetOptions{
+					SigningKeyPEM: []byte(privateRSA),
+				},
+			},
+			detailErr: errors.New("signing key id is required"),
+		},
+		{
+			name: "invalid signing key",
+			args: args{
+				opts: NewKeySetOptions{
+					SigningKeyID:  "current",
+					SigningKeyPEM: []byte("not a key"),
+				},
+			},
+			detailErr: errors.New("parse signing key current: invalid key: Key must be a PEM encoded PKCS1 or PKCS8 key"),
+		},
+		{
+			name: "invalid verify key",
+			args: args{
+				opts: NewKeySetOptions{
+					SigningKeyID:  "current",
+					SigningKeyPEM: []byte(privateRSA),
+					VerifyKeyPEMs: map[string][]byte{
+						"previous": []byte("not a key"),
+					},
+				},
+			},
+			detailErr: errors.New("parse verify key previous: invalid key: Key must be a PEM encoded PKCS1 or PKCS8 key"),
+		},
+		{
+			name: "duplicate key id",
+			args: args{
+				opts: NewKeySetOptions{
+					SigningKeyID:  "current",
+					SigningKeyPEM: []byte(privateRSA),
+					VerifyKeyPEMs: map[string][]byte{
+						"current": publicPEM,
+					},
+				},
+			},
+			detailErr: errors.New("duplicate key id current"),
+		},
+		{
+			name: "passed",
+			args: args{
+				opts: NewKeySetOptions{
+					SigningKeyID:  "current",
+					SigningKeyPEM: []byte(privateRSA),
+					VerifyKeyPEMs: map[string][]byte{
+						"previous": publicPEM,
+					},
+				},
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewKeySet(tt.args.opts)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When NewKeySet() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if (res != nil) != (tt.detailErr == nil) {
+				t.Errorf("Result When NewKeySet() %+v", res)
+			}
+		})
+	}
+}
+
+func Test_KeySet_rotation(t *testing.T) {
+	previousPrivatePEM, previousPublicPEM := generateTestKeyPEM(t)
+	previousKeys, err := NewKeySet(NewKeySetOptions{
+		SigningKeyID:  "previous",
+		SigningKeyPEM: previousPrivatePEM,
+	})
+	if err != nil {
+		t.Fatalf("[Test_KeySet_rotation] %s", err.Error())
+	}
+	currentKeys, err := NewKeySet(NewKeySetOptions{
+		SigningKeyID:  "current",
+		SigningKeyPEM: []byte(privateRSA),
+		VerifyKeyPEMs: map[string][]byte{
+			"previous": previousPublicPEM,
+		},
+	})
+	if err != nil {
+		t.Fatalf("[Test_KeySet_rotation] %s", err.Error())
+	}
+	unknownPrivatePEM, _ := generateTestKeyPEM(t)
+	unknownKeys, err := NewKeySet(NewKeySetOptions{
+		SigningKeyID:  "unknown",
+		SigningKeyPEM: unknownPrivatePEM,
+	})
+	if err != nil {
+		t.Fatalf("[Test_KeySet_rotation] %s", err.Error())
+	}
+
+	tests := []struct {
+		name      string
+		signer    *KeySet
+		detailErr bool
+	}{
+		{
+			name:      "signed with current key",
+			signer:    currentKeys,
+			detailErr: false,
+		},
+		{
+			name:      "signed with retired key",
+			signer:    previousKeys,
+			detailErr: false,
+		},
+		{
+			name:      "signed with unknown key",
+			signer:    unknownKeys,
+			detailErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := &Server{Keys: tt.signer}
+			tokenString, err := signer.generateToken(repository.User{ID: 1}, "some-session")
+			if err != nil {
+				t.Fatalf("Error When generateToken() %s", err.Error())
+			}
+			_, err = jwt.ParseWithClaims(tokenString, &SessionClaims{}, currentKeys.verifyKey)
+			if (err != nil) != tt.detailErr {
+				t.Errorf("Error When verifyKey() %s, detailErr = %t", utilsHelper.ErrorMessage(err), tt.detailErr)
+			}
+		})
+	}
+}
+
+func Test_KeySet_verifyKey_signingMethod(t *testing.T) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, SessionClaims{UserID: 1})
+	tokenString, err := token.SignedString([]byte("shared-secret"))
+	if err != nil {
+		t.Fatalf("[Test_KeySet_verifyKey_signingMethod] %s", err.Error())
+	}
+	_, err = jwt.ParseWithClaims(tokenString, &SessionClaims{}, getDefaultKeySet().verifyKey)
+	if err == nil {
+		t.Errorf("Error When verifyKey() accepted a HS256 token")
+	}
+}
+
+func Test_KeySet_jwks(t *testing.T) {
+	_, previousPublicPEM := generateTestKeyPEM(t)
+	keys, err := NewKeySet(NewKeySetOptions{
+		SigningKeyID:  "current",
+		SigningKeyPEM: []byte(privateRSA),
+		VerifyKeyPEMs: map[string][]byte{
+			"previous": previousPublicPEM,
+		},
+	})
+	if err != nil {
+		t.Fatalf("[Test_KeySet_jwks] %s", err.Error())
+	}
+
+	res := keys.jwks()
+	if len(res.Keys) != 2 {
+		t.Fatalf("Result When jwks() %d keys, detailRes = 2", len(res.Keys))
+	}
+	if res.Keys[0].Kid != "current" || res.Keys[1].Kid != "previous" {
+		t.Errorf("Result When jwks() kids %s, %s", res.Keys[0].Kid, res.Keys[1].Kid)
+	}
+	for _, key := range res.Keys {
+		if key.Kty != "RSA" || key.Alg != "RS256" || key.Use != "sig" || key.E != "AQAB" || key.N == "" {
+			t.Errorf("Result When jwks() %+v", key)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultMaxAccountFailures = 5
+	defaultMaxIPFailures      = 50
+	defaultFailureWindow      = time.Duration(15) * time.Minute
+	defaultLockoutDuration    = time.Duration(15) * time.Minute
+	defaultBaseFailureDelay   = time.Duration(1) * time.Second
+	defaultMaxFailureDelay    = time.Duration(30) * time.Second
+
+	phoneAttemptKeyPrefix = "phone:"
+	ipAttemptKeyPrefix    = "ip:"
+
+	adminKeyHeader = "X-Admin-Key"
+)
+
+// LockoutOptions configures how failed logins are throttled. Zero fields fall
+// back to the defaults above.
+type LockoutOptions struct {
+	// MaxAccountFailures is the number of failed logins for one phone number
+	// within FailureWindow after which the account is locked.
+	MaxAccountFailures int
+	// MaxIPFailures is the same limit for one client IP address, across all
+	// phone numbers it tried.
+	MaxIPFailures int
+	// FailureWindow is how long a failure is remembered. A failure after a
+	// quiet period longer than this starts the counter over.
+	FailureWindow time.Duration
+	// LockoutDuration is how long a phone number or IP address stays locked.
+	LockoutDuration time.Duration
+	// BaseFailureDelay is the wait imposed on a phone number after its first
+	// failure. It doubles with every further failure up to MaxFailureDelay.
+	BaseFailureDelay time.Duration
+	MaxFailureDelay  time.Duration
+}
+
+func (o LockoutOptions) withDefaults() LockoutOptions {
+	if o.MaxAccountFailures <= 0 {
+		o.MaxAccountFailures = defaultMaxAccountFailures
+	}
+	if o.MaxIPFailures <= 0 {
+		o.MaxIPFailures = defaultMaxIPFailures
+	}
+	if o.FailureWindow <= 0 {
+		o.FailureWindow = defaultFailureWindow
+	}
+	if o.LockoutDuration <= 0 {
+		o.LockoutDuration = defaultLockoutDuration
+	}
+	if o.BaseFailureDelay <= 0 {
+		o.BaseFailureDelay = defaultBaseFailureDelay
+	}
+	if o.MaxFailureDelay <= 0 {
+		o.MaxFailureDelay = defaultMaxFailureDelay
+	}
+	return o
+}
+
+// failureDelay returns how long a phone number has to wait after its
+// failedCount-th failure before it may try again.
+func (o LockoutOptions) failureDelay(failedCount int) time.Duration {
+	if failedCount <= 0 {
+		return 0
+	}
+	delay := float64(o.BaseFailureDelay) * math.Pow(2, float64(failedCount-1))
+	if delay > float64(o.MaxFailureDelay) {
+		return o.MaxFailureDelay
+	}
+	return time.Duration(delay)
+}
+
+func loginAttemptKeys(phoneNumber string, ipAddress string) []string {
+	keys := []string{phoneAttemptKeyPrefix + phoneNumber}
+	if ipAddress != "" {
+		keys = append(keys, ipAttemptKeyPrefix+ipAddress)
+	}
+	return keys
+}
+
+// checkLoginLockout reports whether a login for the phone number from the IP
+// address has to be refused, and for how long. Locks apply to both keys; the
+// progressive delay only applies to the phone number so that users behind a
+// shared address are not slowed down by each other.
+func (s *Server) checkLoginLockout(ctx context.Context, phoneNumber string, ipAddress string) (retryAfter time.Duration, messages []string, err error) {
+	attempts, err := s.Repository.GetLoginAttempts(ctx, loginAttemptKeys(phoneNumber, ipAddress))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	opts := s.Lockout.withDefaults()
+	now := time.Now()
+	for _, attempt := range attempts {
+		if attempt.LockedUntil.After(now) {
+			if wait := attempt.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+			messages = append(messages, lockedMessage(attempt))
+			continue
+		}
+		if !strings.HasPrefix(attempt.Key, phoneAttemptKeyPrefix) || attempt.LastFailedAt.Before(now.Add(-opts.FailureWindow)) {
+			continue
+		}
+		allowedAt := attempt.LastFailedAt.Add(opts.failureDelay(attempt.FailedCount))
+		if allowedAt.After(now) {
+			if wait := allowedAt.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+			messages = append(messages, fmt.Sprintf("Too many failed login attempts, try again in %d seconds", ceilSeconds(allowedAt.Sub(now))))
+		}
+	}
+
+	return retryAfter, messages, nil
+}
+
+// recordLoginFailure counts a failed login for the phone number and the IP
+// address, locks whichever reached its limit, and returns the messages that
+// tell the caller how close the account is to being locked.
+func (s *Server) recordLoginFailure(ctx context.Context, phoneNumber string, ipAddress string) (messages []string, err error) {
+	opts := s.Lockout.withDefaults()
+	now := time.Now()
+	attempts, err := s.Repository.IncrementLoginAttempts(ctx, loginAttemptKeys(phoneNumber, ipAddress), now.Add(-opts.FailureWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attempt := range attempts {
+		isAccount := strings.HasPrefix(attempt.Key, phoneAttemptKeyPrefix)
+		maxFailures := opts.MaxIPFailures
+		if isAccount {
+			maxFailures = opts.MaxAccountFailures
+		}
+
+		if attempt.FailedCount < maxFailures {
+			if isAccount {
+				messages = append(messages, fmt.Sprintf("%d attempts remaining before the account is locked", maxFailures-attempt.FailedCount))
+			}
+			continue
+		}
+
+		attempt.LockedUntil = now.Add(opts.LockoutDuration)
+		err = s.Repository.LockLoginAttempt(ctx, attempt.Key, attempt.LockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, lockedMessage(attempt))
+	}
+
+	return messages, nil
+}
+
+// resetLoginFailures clears the counter of the phone number after a
+// successful login. The IP address counter is left alone, otherwise an
+// attacker could reset it by logging in to an account of their own.
+func (s *Server) resetLoginFailures(ctx context.Context, phoneNumber string) (err error) {
+	return s.Repository.ResetLoginAttempts(ctx, []string{phoneAttemptKeyPrefix + phoneNumber})
+}
+
+func lockedMessage(attempt repository.LoginAttempt) string {
+	until := attempt.LockedUntil.UTC().Format(time.RFC3339)
+	if strings.HasPrefix(attempt.Key, ipAttemptKeyPrefix) {
+		return "Too many failed login attempts from this address, locked until " + until
+	}
+	return "Account is locked until " + until
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// isAdmin reports whether the request carries the configured admin API key.
+// The admin endpoints are disabled while no key is configured.
+func (s *Server) isAdmin(ctx echo.Context) bool {
+	if s.AdminAPIKey == "" {
+		return false
+	}
+	key := ctx.Request().Header.Get(adminKeyHeader)
+	return subtle.ConstantTimeCompare([]byte(key), []byte(s.AdminAPIKey)) == 1
+}
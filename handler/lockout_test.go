@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	"github.com/golang/mock/gomock"
+)
+
+func Test_LockoutOptions_failureDelay(t *testing.T) {
+	opts := LockoutOptions{}.withDefaults()
+	tests := []struct {
+		name        string
+		failedCount int
+		want        time.Duration
+	}{
+		{
+			name:        "no failure",
+			failedCount: 0,
+			want:        0,
+		},
+		{
+			name:        "first failure",
+			failedCount: 1,
+			want:        time.Second,
+		},
+		{
+			name:        "doubles",
+			failedCount: 4,
+			want:        8 * time.Second,
+		},
+		{
+			name:        "capped",
+			failedCount: 40,
+			want:        30 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := opts.failureDelay(tt.failedCount)
+			if got != tt.want {
+				t.Errorf("Result When failureDelay() %s, detailRes = %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkLoginLockout(t *testing.T) {
+	keys := []string{"phone:+62821232342", "ip:10.0.0.1"}
+	tests := []struct {
+		name      string
+		attempts  []repository.LoginAttempt
+		repoErr   error
+		locked    bool
+		detailErr error
+	}{
+		{
+			name:      "error GetLoginAttempts",
+			repoErr:   errors.New("expected GetLoginAttempts error"),
+			locked:    false,
+			detailErr: errors.New("expected GetLoginAttempts error"),
+		},
+		{
+			name:      "no failures",
+			locked:    false,
+			detailErr: nil,
+		},
+		{
+			name: "delay elapsed",
+			attempts: []repository.LoginAttempt{
+				{Key: "phone:+62821232342", FailedCount: 2, LastFailedAt: time.Now().Add(-time.Minute)},
+			},
+			locked:    false,
+			detailErr: nil,
+		},
+		{
+			name: "delay pending",
+			attempts: []repository.LoginAttempt{
+				{Key: "phone:+62821232342", FailedCount: 2, LastFailedAt: time.Now()},
+			},
+			locked:    true,
+			detailErr: nil,
+		},
+		{
+			name: "ip failures are not delayed",
+			attempts: []repository.LoginAttempt{
+				{Key: "ip:10.0.0.1", FailedCount: 10, LastFailedAt: time.Now()},
+			},
+			locked:    false,
+			detailErr: nil,
+		},
+		{
+			name: "ip locked",
+			attempts: []repository.LoginAttempt{
+				{Key: "ip:10.0.0.1", FailedCount: 50, LastFailedAt: time.Now(), LockedUntil: time.Now().Add(time.Minute)},
+			},
+			locked:    true,
+			detailErr: nil,
+		},
+		{
+			name: "lock expired",
+			attempts: []repository.LoginAttempt{
+				{Key: "phone:+62821232342", FailedCount: 5, LastFailedAt: time.Now().Add(-time.Hour), LockedUntil: time.Now().Add(-time.Minute)},
+			},
+			locked:    false,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+			mockRepository.EXPECT().GetLoginAttempts(context.Background(), keys).
+				Return(tt.attempts, tt.repoErr).
+				Times(1)
+
+			s := &Server{Repository: mockRepository}
+			retryAfter, messages, err := s.checkLoginLockout(context.Background(), "+62821232342", "10.0.0.1")
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When checkLoginLockout() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if (len(messages) > 0) != tt.locked || (retryAfter > 0) != tt.locked {
+				t.Errorf("Result When checkLoginLockout() %s %v, locked = %t", retryAfter, messages, tt.locked)
+			}
+		})
+	}
+}
+
+func Test_recordLoginFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+	mockRepository.EXPECT().IncrementLoginAttempts(context.Background(), []string{"phone:+62821232342", "ip:10.0.0.1"}, gomock.Any()).
+		Return([]repository.LoginAttempt{
+			{Key: "phone:+62821232342", FailedCount: 3, LastFailedAt: time.Now()},
+			{Key: "ip:10.0.0.1", FailedCount: 2, LastFailedAt: time.Now()},
+		}, nil).
+		Times(1)
+
+	s := &Server{Repository: mockRepository}
+	messages, err := s.recordLoginFailure(context.Background(), "+62821232342", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Error When recordLoginFailure() %s", err.Error())
+	}
+	want := []string{"2 attempts remaining before the account is locked"}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("Result When recordLoginFailure() %v, detailRes = %v", messages, want)
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultLoginEventsLimit = 20
+	maxLoginEventsLimit     = 100
+
+	// maxUserAgentLength keeps arbitrary headers from bloating the login
+	// history and the sessions.
+	maxUserAgentLength = 512
+)
+
+var errInvalidLoginCursor = errors.New("Invalid cursor")
+
+// recordLoginEvent adds a login attempt for the phone number to the login
+// history, with the IP address and user agent of the request. userID is the
+// user logging in when known, 0 to find them by phone number.
+func (s *Server) recordLoginEvent(ctx echo.Context, userID int64, phoneNumber string, outcome string, failureReason string) error {
+	return s.Repository.InsertLoginEvent(ctx.Request().Context(), repository.LoginEvent{
+		UserID:        userID,
+		PhoneNumber:   phoneNumber,
+		IPAddress:     ctx.RealIP(),
+		UserAgent:     truncate(ctx.Request().UserAgent(), maxUserAgentLength),
+		Outcome:       outcome,
+		FailureReason: failureReason,
+	})
+}
+
+// encodeLoginCursor returns the cursor of the page that follows the login
+// event. Clients are meant to pass it back as is.
+func encodeLoginCursor(eventID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(eventID, 10)))
+}
+
+func decodeLoginCursor(cursor string) (eventID int64, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidLoginCursor
+	}
+	eventID, err = strconv.ParseInt(string(decoded), 10, 64)
+	if err != nil || eventID <= 0 {
+		return 0, errInvalidLoginCursor
+	}
+	return eventID, nil
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+)
+
+func Test_decodeLoginCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		eventID int64
+		wantErr bool
+	}{
+		{
+			name:    "encoded cursor",
+			cursor:  encodeLoginCursor(42),
+			eventID: 42,
+		},
+		{
+			name:    "not base64",
+			cursor:  "not a cursor",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			cursor:  "YWJj",
+			wantErr: true,
+		},
+		{
+			name:    "zero",
+			cursor:  encodeLoginCursor(0),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventID, err := decodeLoginCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Error When decodeLoginCursor() %v, wantErr = %v", err, tt.wantErr)
+			}
+			if eventID != tt.eventID {
+				t.Errorf("Result When decodeLoginCursor() %d, eventID = %d", eventID, tt.eventID)
+			}
+		})
+	}
+}
+
+func Test_recordLoginEvent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	repo := repository.NewMockRepositoryInterface(mockCtrl)
+
+	req, _ := http.NewRequest(http.MethodPost, "url", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", strings.Repeat("a", maxUserAgentLength-1)+"é")
+	repo.EXPECT().InsertLoginEvent(context.Background(), repository.LoginEvent{
+		UserID:        1,
+		PhoneNumber:   "+62821232342",
+		IPAddress:     "192.0.2.1",
+		UserAgent:     strings.Repeat("a", maxUserAgentLength-1),
+		Outcome:       repository.LoginOutcomeFailure,
+		FailureReason: "Wrong password",
+	}).
+		Return(nil).
+		Times(1)
+
+	s := &Server{Repository: repo}
+	err := s.recordLoginEvent(echo.New().NewContext(req, httptest.NewRecorder()), 1, "+62821232342", repository.LoginOutcomeFailure, "Wrong password")
+	if err != nil {
+		t.Errorf("Error When recordLoginEvent() %s", err.Error())
+	}
+}
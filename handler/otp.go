@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/Richthonio10/requirement-swtpro/sms"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	oneTimeCodeDigits      = 6
+	oneTimeCodeDuration    = time.Duration(10) * time.Minute
+	maxOneTimeCodeAttempts = 5
+	// maxOneTimeCodes is how many codes a user is sent for one purpose within
+	// oneTimeCodeDuration. Every code comes with attempts of its own, so this
+	// is what bounds the guesses for a purpose.
+	maxOneTimeCodes = 3
+
+	// pendingRegistrationDuration is how long a new user has to verify their
+	// phone number before they are deleted and the number is free again.
+	// New codes do not extend it.
+	pendingRegistrationDuration = time.Duration(1) * time.Hour
+)
+
+const (
+	registrationCodeMessage = "Your verification code is %s. It expires in 10 minutes. Do not share it with anyone."
+	phoneChangeCodeMessage  = "Your code to confirm this phone number for your account is %s. It expires in 10 minutes. Do not share it with anyone."
+	loginCodeMessage        = "Your login code is %s. It expires in 10 minutes. Do not share it with anyone."
+	phoneChangeNotice       = "A change of the phone number of your account to another number was requested. If this was not you, change your password now."
+	registrationNotice      = "Someone tried to register with this phone number, which already has an account. If this was you, log in or reset your password instead."
+)
+
+// registrationReceivedMessage answers every registration in enumeration
+// safe mode, whether a code or a notice was sent.
+const registrationReceivedMessage = "If the phone number can be registered, a verification code has been sent to it"
+
+var (
+	errInvalidOneTimeCode  = errors.New("Invalid or expired code")
+	errTooManyOneTimeCodes = errors.New("Too many codes requested, try again later")
+)
+
+var defaultSMSSender sms.SMSSender = sms.NewFileSender(sms.NewFileSenderOptions{})
+
+// smsSender returns the configured sender, or one that logs the messages.
+func (s *Server) smsSender() sms.SMSSender {
+	if s.SMSSender == nil {
+		return defaultSMSSender
+	}
+	return s.SMSSender
+}
+
+func generateOneTimeCode() (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(oneTimeCodeDigits), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", oneTimeCodeDigits, n), nil
+}
+
+// sendOneTimeCode creates a code for the purpose, replacing any earlier one,
+// and sends it to the phone number. messageFormat gets the code as its only
+// argument. The code is hashed like a password, in the hashing pool. It
+// returns errTooManyOneTimeCodes once the user was sent maxOneTimeCodes
+// codes for the purpose within oneTimeCodeDuration.
+func (s *Server) sendOneTimeCode(ctx context.Context, userID int64, purpose string, phoneNumber string, messageFormat string) error {
+	code, err := generateOneTimeCode()
+	if err != nil {
+		return err
+	}
+
+	codeHash, err := s.createHashPassword(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = s.Repository.InsertOneTimeCode(ctx, repository.OneTimeCode{
+		UserID:      userID,
+		Purpose:     purpose,
+		PhoneNumber: phoneNumber,
+		CodeHash:    codeHash,
+		ExpiresAt:   now.Add(oneTimeCodeDuration),
+	}, repository.OneTimeCodeLimit{
+		MaxCodes: maxOneTimeCodes,
+		Since:    now.Add(-oneTimeCodeDuration),
+	})
+	if err == repository.ErrOneTimeCodeLimitReached {
+		return errTooManyOneTimeCodes
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.smsSender().Send(ctx, phoneNumber, fmt.Sprintf(messageFormat, code))
+}
+
+// verifyOneTimeCode checks the code against the latest code of the user for
+// the purpose and uses it up. It returns errInvalidOneTimeCode when the code
+// is wrong, expired, already used or tried too many times.
+func (s *Server) verifyOneTimeCode(ctx context.Context, userID int64, purpose string, code string) (oneTimeCode repository.OneTimeCode, err error) {
+	oneTimeCode, err = s.Repository.GetActiveOneTimeCode(ctx, userID, purpose)
+	if err != nil {
+		return oneTimeCode, err
+	}
+
+	if oneTimeCode.ID == 0 || time.Now().After(oneTimeCode.ExpiresAt) || oneTimeCode.Attempts >= maxOneTimeCodeAttempts {
+		return repository.OneTimeCode{}, errInvalidOneTimeCode
+	}
+
+	// The attempt is counted before the comparison so that parallel guesses
+	// cannot all slip in under the limit.
+	reserved, err := s.Repository.ReserveOneTimeCodeAttempt(ctx, oneTimeCode.ID, maxOneTimeCodeAttempts)
+	if err != nil {
+		return repository.OneTimeCode{}, err
+	}
+	if !reserved {
+		return repository.OneTimeCode{}, errInvalidOneTimeCode
+	}
+
+	matched, err := s.comparePasswords(ctx, oneTimeCode.CodeHash, code)
+	if err != nil {
+		return repository.OneTimeCode{}, err
+	}
+	if !matched {
+		return repository.OneTimeCode{}, errInvalidOneTimeCode
+	}
+
+	used, err := s.Repository.UseOneTimeCode(ctx, oneTimeCode.ID)
+	if err != nil {
+		return repository.OneTimeCode{}, err
+	}
+	if !used {
+		return repository.OneTimeCode{}, errInvalidOneTimeCode
+	}
+
+	return oneTimeCode, nil
+}
+
+// requestPhoneNumberChange starts a pending change to the phone number by
+// sending a code to it. The pending change expires together with the code.
+// When enabled, the current number is told about the request; failing to do
+// so does not stop the change.
+func (s *Server) requestPhoneNumberChange(ctx context.Context, userID int64, phoneNumber string) error {
+	err := s.sendOneTimeCode(ctx, userID, repository.OneTimeCodePurposePhoneChange, phoneNumber, phoneChangeCodeMessage)
+	if err != nil {
+		return err
+	}
+
+	if !s.NotifyPhoneNumberChange {
+		return nil
+	}
+
+	user, err := s.Repository.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Errorf("Error When GetUserByID: %s with user id: %d", err.Error(), userID)
+		return nil
+	}
+	err = s.smsSender().Send(ctx, user.PhoneNumber, phoneChangeNotice)
+	if err != nil {
+		log.Errorf("Error When Send: %s with user id: %d", err.Error(), userID)
+	}
+
+	return nil
+}
+
+// notifyRegistrationAttempt tells the owner of a registered phone number
+// that it was used to register again, in place of the error that would
+// confirm the number is registered. Failures are only logged.
+func (s *Server) notifyRegistrationAttempt(ctx context.Context, phoneNumber string) {
+	err := s.smsSender().Send(ctx, phoneNumber, registrationNotice)
+	if err != nil {
+		log.Errorf("Error When Send: %s with phone number: %s", err.Error(), phoneNumber)
+	}
+}
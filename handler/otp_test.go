@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/Richthonio10/requirement-swtpro/sms"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	"github.com/golang/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_generateOneTimeCode(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		code, err := generateOneTimeCode()
+		if err != nil {
+			t.Fatalf("Error When generateOneTimeCode() %s", err.Error())
+		}
+		if !regexp.MustCompile(`^[0-9]{6}$`).MatchString(code) {
+			t.Errorf("Result When generateOneTimeCode() %s", code)
+		}
+	}
+}
+
+func Test_sendOneTimeCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		mock      func(mockRepository *repository.MockRepositoryInterface, mockSender *sms.MockSMSSender)
+		detailErr error
+	}{
+		{
+			name: "error InsertOneTimeCode",
+			mock: func(mockRepository *repository.MockRepositoryInterface, mockSender *sms.MockSMSSender) {
+				mockRepository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertOneTimeCode error")).
+					Times(1)
+			},
+			detailErr: errors.New("expected InsertOneTimeCode error"),
+		},
+		{
+			name: "too many codes",
+			mock: func(mockRepository *repository.MockRepositoryInterface, mockSender *sms.MockSMSSender) {
+				mockRepository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(0), repository.ErrOneTimeCodeLimitReached).
+					Times(1)
+			},
+			detailErr: errTooManyOneTimeCodes,
+		},
+		{
+			name: "error Send",
+			mock: func(mockRepository *repository.MockRepositoryInterface, mockSender *sms.MockSMSSender) {
+				mockRepository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					Return(int64(1), nil).
+					Times(1)
+				mockSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					Return(errors.New("expected Send error")).
+					Times(1)
+			},
+			detailErr: errors.New("expected Send error"),
+		},
+		{
+			name: "passed",
+			mock: func(mockRepository *repository.MockRepositoryInterface, mockSender *sms.MockSMSSender) {
+				var codeHash string
+				mockRepository.EXPECT().InsertOneTimeCode(context.Background(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.OneTimeCode, limit repository.OneTimeCodeLimit) (int64, error) {
+						if data.UserID != 1 || data.Purpose != repository.OneTimeCodePurposePasswordReset || data.PhoneNumber != "+62821232342" || time.Until(data.ExpiresAt) <= 0 {
+							t.Errorf("Result When InsertOneTimeCode() %+v", data)
+						}
+						if limit.MaxCodes != maxOneTimeCodes || time.Since(limit.Since) < oneTimeCodeDuration {
+							t.Errorf("Result When InsertOneTimeCode() limit %+v", limit)
+						}
+						codeHash = data.CodeHash
+						return 1, nil
+					}).
+					Times(1)
+				mockSender.EXPECT().Send(context.Background(), "+62821232342", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, phoneNumber string, message string) error {
+						code := regexp.MustCompile(`[0-9]{6}`).FindString(message)
+						if bcrypt.CompareHashAndPassword([]byte(codeHash), []byte(code)) != nil {
+							t.Errorf("Result When Send() message %q does not match the stored code", message)
+						}
+						return nil
+					}).
+					Times(1)
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+			mockSender := sms.NewMockSMSSender(mockCtrl)
+			tt.mock(mockRepository, mockSender)
+
+			s := &Server{
+				Repository: mockRepository,
+				SMSSender:  mockSender,
+			}
+			err := s.sendOneTimeCode(context.Background(), 1, repository.OneTimeCodePurposePasswordReset, "+62821232342", "Your code is %s")
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When sendOneTimeCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+// Test_oneTimeCode_saturated checks that codes are hashed and compared in
+// the hashing pool like passwords.
+func Test_oneTimeCode_saturated(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+	s := &Server{
+		Repository:  mockRepository,
+		HashingPool: saturatedPool(t),
+	}
+
+	err := s.sendOneTimeCode(context.Background(), 1, repository.OneTimeCodePurposePasswordReset, "+62821232342", "Your code is %s")
+	if !errors.Is(err, password.ErrPoolSaturated) {
+		t.Errorf("Error When sendOneTimeCode() %v, Err = %v", err, password.ErrPoolSaturated)
+	}
+
+	codeHash, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+		Return(repository.OneTimeCode{
+			ID:        1,
+			UserID:    1,
+			CodeHash:  string(codeHash),
+			ExpiresAt: time.Now().Add(time.Minute),
+		}, nil).
+		Times(1)
+	mockRepository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+		Return(true, nil).
+		Times(1)
+	_, err = s.verifyOneTimeCode(context.Background(), 1, repository.OneTimeCodePurposePasswordReset, "123456")
+	if !errors.Is(err, password.ErrPoolSaturated) {
+		t.Errorf("Error When verifyOneTimeCode() %v, Err = %v", err, password.ErrPoolSaturated)
+	}
+}
+
+func Test_verifyOneTimeCode(t *testing.T) {
+	codeHash, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.MinCost)
+	activeCode := repository.OneTimeCode{
+		ID:          1,
+		UserID:      1,
+		Purpose:     repository.OneTimeCodePurposePasswordReset,
+		PhoneNumber: "+62821232342",
+		CodeHash:    string(codeHash),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	expiredCode := activeCode
+	expiredCode.ExpiresAt = time.Now().Add(-time.Minute)
+	exhaustedCode := activeCode
+	exhaustedCode.Attempts = maxOneTimeCodeAttempts
+	tests := []struct {
+		name      string
+		code      string
+		mock      func(mockRepository *repository.MockRepositoryInterface)
+		detailErr error
+	}{
+		{
+			name: "error GetActiveOneTimeCode",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(repository.OneTimeCode{}, errors.New("expected GetActiveOneTimeCode error")).
+					Times(1)
+			},
+			detailErr: errors.New("expected GetActiveOneTimeCode error"),
+		},
+		{
+			name: "no code",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(repository.OneTimeCode{}, nil).
+					Times(1)
+			},
+			detailErr: errInvalidOneTimeCode,
+		},
+		{
+			name: "expired",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(expiredCode, nil).
+					Times(1)
+			},
+			detailErr: errInvalidOneTimeCode,
+		},
+		{
+			name: "too many attempts",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(exhaustedCode, nil).
+					Times(1)
+			},
+			detailErr: errInvalidOneTimeCode,
+		},
+		{
+			name: "error ReserveOneTimeCodeAttempt",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				mockRepository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(false, errors.New("expected ReserveOneTimeCodeAttempt error")).
+					Times(1)
+			},
+			detailErr: errors.New("expected ReserveOneTimeCodeAttempt error"),
+		},
+		{
+			name: "last attempt taken concurrently",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				mockRepository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(false, nil).
+					Times(1)
+			},
+			detailErr: errInvalidOneTimeCode,
+		},
+		{
+			name: "wrong code",
+			code: "654321",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				mockRepository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+			},
+			detailErr: errInvalidOneTimeCode,
+		},
+		{
+			name: "used concurrently",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				mockRepository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				mockRepository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(false, nil).
+					Times(1)
+			},
+			detailErr: errInvalidOneTimeCode,
+		},
+		{
+			name: "passed",
+			code: "123456",
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetActiveOneTimeCode(context.Background(), int64(1), repository.OneTimeCodePurposePasswordReset).
+					Return(activeCode, nil).
+					Times(1)
+				mockRepository.EXPECT().ReserveOneTimeCodeAttempt(context.Background(), int64(1), maxOneTimeCodeAttempts).
+					Return(true, nil).
+					Times(1)
+				mockRepository.EXPECT().UseOneTimeCode(context.Background(), int64(1)).
+					Return(true, nil).
+					Times(1)
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+			tt.mock(mockRepository)
+
+			s := &Server{Repository: mockRepository}
+			res, err := s.verifyOneTimeCode(context.Background(), 1, repository.OneTimeCodePurposePasswordReset, tt.code)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When verifyOneTimeCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if err == nil && res.ID != activeCode.ID {
+				t.Errorf("Result When verifyOneTimeCode() %+v", res)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	passwordChangePurpose  = "password_change"
+	passwordChangeDuration = time.Duration(10) * time.Minute
+)
+
+var errInvalidPasswordChange = errors.New("Invalid or expired change token")
+
+// dummyPasswordHash is the hash of a random password, made once with the
+// configured hasher.
+type dummyPasswordHash struct {
+	once sync.Once
+	hash string
+}
+
+// compareDummyPassword spends the time of a password comparison when there
+// is no user to compare with, so that an unknown phone number cannot be
+// told apart from a wrong password by the response time. The dummy hash is
+// made outside of the hashing pool so that a saturated pool cannot leave it
+// empty for good.
+func (s *Server) compareDummyPassword(ctx context.Context, plainPassword string) error {
+	s.dummyHash.once.Do(func() {
+		randomPassword := make([]byte, 16)
+		_, err := rand.Read(randomPassword)
+		if err != nil {
+			log.Errorf("Error When Read: %s", err.Error())
+			return
+		}
+		s.dummyHash.hash, err = s.passwordHasher().Hash(hex.EncodeToString(randomPassword))
+		if err != nil {
+			log.Errorf("Error When Hash: %s", err.Error())
+		}
+	})
+	if s.dummyHash.hash == "" {
+		return nil
+	}
+	_, err := s.comparePasswords(ctx, s.dummyHash.hash, plainPassword)
+	return err
+}
+
+// passwordExpired reports whether the password of the user is older than
+// MaxPasswordAge.
+func (s *Server) passwordExpired(user repository.User) bool {
+	if s.MaxPasswordAge <= 0 || user.PasswordChangedAt.IsZero() {
+		return false
+	}
+	return time.Since(user.PasswordChangedAt) > s.MaxPasswordAge
+}
+
+// checkPasswordReuse returns why the password is rejected when it is one of
+// the last PasswordHistorySize passwords of the user.
+func (s *Server) checkPasswordReuse(ctx context.Context, userID int64, plainPassword string) (errorMessages []string, err error) {
+	if s.PasswordHistorySize <= 0 {
+		return nil, nil
+	}
+
+	passwordHashes, err := s.Repository.GetPasswordHistory(ctx, userID, s.PasswordHistorySize)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, passwordHash := range passwordHashes {
+		reused, err := s.comparePasswords(ctx, passwordHash, plainPassword)
+		if err != nil {
+			return nil, err
+		}
+		if reused {
+			return []string{fmt.Sprintf("New password must be different from your last %d passwords", s.PasswordHistorySize)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// generatePasswordChangeToken returns the short lived token that proves
+// every login step passed for a user whose password expired. Like the MFA
+// challenge token it carries no session, it is only good for setting a new
+// password.
+func (s *Server) generatePasswordChangeToken(user repository.User) (signedToken string, err error) {
+	return s.keySet().sign(SessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "some-issuer",
+			ExpiresAt: time.Now().Add(passwordChangeDuration).Unix(),
+		},
+		UserID:      user.ID,
+		PhoneNumber: user.PhoneNumber,
+		Purpose:     passwordChangePurpose,
+	})
+}
+
+func (s *Server) parsePasswordChangeToken(tokenString string) (sc SessionClaims, err error) {
+	_, err = jwt.ParseWithClaims(tokenString, &sc, s.keySet().verifyKey)
+	if err != nil || sc.Purpose != passwordChangePurpose || sc.UserID == 0 {
+		return SessionClaims{}, errInvalidPasswordChange
+	}
+	return sc, nil
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_passwordExpired(t *testing.T) {
+	maxPasswordAge := time.Duration(90) * 24 * time.Hour
+	tests := []struct {
+		name              string
+		maxPasswordAge    time.Duration
+		passwordChangedAt time.Time
+		want              bool
+	}{
+		{name: "no maximum age", maxPasswordAge: 0, passwordChangedAt: time.Now().Add(-10 * maxPasswordAge), want: false},
+		{name: "unknown change time", maxPasswordAge: maxPasswordAge, passwordChangedAt: time.Time{}, want: false},
+		{name: "recent", maxPasswordAge: maxPasswordAge, passwordChangedAt: time.Now().Add(-time.Hour), want: false},
+		{name: "expired", maxPasswordAge: maxPasswordAge, passwordChangedAt: time.Now().Add(-maxPasswordAge - time.Hour), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{MaxPasswordAge: tt.maxPasswordAge}
+			if got := s.passwordExpired(repository.User{PasswordChangedAt: tt.passwordChangedAt}); got != tt.want {
+				t.Errorf("Result When passwordExpired() %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parsePasswordChangeToken(t *testing.T) {
+	s := &Server{}
+	user := repository.User{ID: 1, PhoneNumber: "+62821232342"}
+
+	changeToken, _ := s.generatePasswordChangeToken(user)
+	challengeToken, _ := s.generateMFAChallengeToken(user)
+	expiredToken, _ := s.keySet().sign(SessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+		},
+		UserID:  1,
+		Purpose: passwordChangePurpose,
+	})
+
+	tests := []struct {
+		name      string
+		token     string
+		detailErr error
+	}{
+		{name: "passed", token: changeToken, detailErr: nil},
+		{name: "challenge token", token: challengeToken, detailErr: errInvalidPasswordChange},
+		{name: "expired", token: expiredToken, detailErr: errInvalidPasswordChange},
+		{name: "malformed", token: "not-a-token", detailErr: errInvalidPasswordChange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.parsePasswordChangeToken(tt.token)
+			if err != tt.detailErr {
+				t.Errorf("Error When parsePasswordChangeToken() %v, detailErr = %v", err, tt.detailErr)
+			}
+			if err == nil && (res.UserID != 1 || res.PhoneNumber != "+62821232342") {
+				t.Errorf("Result When parsePasswordChangeToken() %+v", res)
+			}
+		})
+	}
+}
+
+func Test_compareDummyPassword(t *testing.T) {
+	s := &Server{
+		PasswordHasher: password.NewBcryptHasher(password.BcryptOptions{Cost: bcrypt.MinCost}),
+	}
+
+	err := s.compareDummyPassword(context.Background(), "SawitPro123$")
+	if err != nil {
+		t.Fatalf("Error When compareDummyPassword() %s", err.Error())
+	}
+	hash := s.dummyHash.hash
+	if !strings.HasPrefix(hash, "$2a$04$") {
+		t.Errorf("Result When compareDummyPassword() hash %s is not made with the configured hasher", hash)
+	}
+
+	_ = s.compareDummyPassword(context.Background(), "SawitPro123$")
+	if s.dummyHash.hash != hash {
+		t.Errorf("Result When compareDummyPassword() made a new hash")
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Richthonio10/requirement-swtpro/generated"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+// UserIDFromToken returns the user id of the bearer token of the request. It
+// only checks the signature and expiry of the token, not whether its session
+// was revoked, which is enough to pick the rate limiting bucket of a request.
+func (s *Server) UserIDFromToken(ctx echo.Context) (int64, bool) {
+	tokenString := strings.TrimPrefix(ctx.Request().Header.Get("Authorization"), "Bearer ")
+	if tokenString == "" {
+		tokenString = s.cookieValue(ctx, accessTokenCookie)
+	}
+	if tokenString == "" {
+		return 0, false
+	}
+
+	var sc SessionClaims
+	_, err := jwt.ParseWithClaims(tokenString, &sc, s.keySet().verifyKey)
+	if err != nil || sc.UserID == 0 {
+		return 0, false
+	}
+	return sc.UserID, true
+}
+
+// RateLimitExceeded answers a request rejected by the rate limiter.
+func (s *Server) RateLimitExceeded(ctx echo.Context) error {
+	var response struct {
+		Header generated.ResponseHeader `json:"header"`
+	}
+	response.Header = createResponseHeader(utilsHelper.TooManyRequestsErrorCode, []string{"Too many requests"}, false)
+	return ctx.JSON(http.StatusTooManyRequests, response)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+func Test_UserIDFromToken(t *testing.T) {
+	s := &Server{}
+	validToken, _ := s.generateToken(repository.User{ID: 1}, "some-session")
+	expiredToken, _ := s.keySet().sign(SessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+		},
+		UserID: 1,
+	})
+	tests := []struct {
+		name          string
+		authorization string
+		wantID        int64
+		wantOK        bool
+	}{
+		{
+			name:          "no authorization",
+			authorization: "",
+			wantID:        0,
+			wantOK:        false,
+		},
+		{
+			name:          "invalid token",
+			authorization: "Bearer not-a-token",
+			wantID:        0,
+			wantOK:        false,
+		},
+		{
+			name:          "expired token",
+			authorization: "Bearer " + expiredToken,
+			wantID:        0,
+			wantOK:        false,
+		},
+		{
+			name:          "passed",
+			authorization: "Bearer " + validToken,
+			wantID:        1,
+			wantOK:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			ctx := echo.New().NewContext(req, httptest.NewRecorder())
+			id, ok := s.UserIDFromToken(ctx)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("Result When UserIDFromToken() %d %t, detailRes = %d %t", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
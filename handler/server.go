@@ -1,21 +1,102 @@
 package handler
 
 import (
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
 	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/Richthonio10/requirement-swtpro/sms"
 )
 
 type Server struct {
-	Repository repository.RepositoryInterface
+	Repository  repository.RepositoryInterface
+	Keys        *KeySet
+	Lockout     LockoutOptions
+	Sessions    SessionOptions
+	AdminAPIKey string
+	SMSSender   sms.SMSSender
+	// AllowUnverifiedLogin lets users log in before they verified their
+	// phone number.
+	AllowUnverifiedLogin bool
+	// NotifyPhoneNumberChange sends a notice to the current phone number
+	// when a change to another number is requested.
+	NotifyPhoneNumberChange bool
+	// TOTPEncryptionKey is the 32 byte AES key the authenticator app
+	// secrets are stored with.
+	TOTPEncryptionKey []byte
+	// PasswordHasher hashes new passwords; stored hashes made with other
+	// settings are upgraded on login. Defaults to bcrypt at its default
+	// cost.
+	PasswordHasher password.PasswordHasher
+	// BreachedPasswords, when set, rejects new passwords found in it.
+	BreachedPasswords password.BreachedList
+	// MinPasswordScore is the lowest password.Score new passwords need,
+	// 2 when unset.
+	MinPasswordScore int
+	// PasswordHistorySize is how many of the last passwords, the current
+	// one included, a new password must differ from. 0 disables the check.
+	PasswordHistorySize int
+	// MaxPasswordAge makes login ask for a new password once the password
+	// is older. 0 lets passwords never expire.
+	MaxPasswordAge time.Duration
+	// EnumerationSafe answers login and registration the same way whether
+	// the phone number is registered or not. Registering a number that is
+	// taken sends a notice to it instead of failing.
+	EnumerationSafe bool
+	// HashingPool bounds how many password hashes are computed at once.
+	// Hashing is unbounded when it is nil.
+	HashingPool *password.Pool
+	// CookieSessions also hands the tokens to browsers in HttpOnly cookies
+	// and accepts the access token cookie in place of the Authorization
+	// header, with a double-submit CSRF token on state changing requests.
+	CookieSessions bool
+
+	sessionCache sessionCache
+	dummyHash    dummyPasswordHash
 }
 
 type NewServerOptions struct {
-	Repository repository.RepositoryInterface
+	Repository  repository.RepositoryInterface
+	Keys        *KeySet
+	Lockout     LockoutOptions
+	Sessions    SessionOptions
+	AdminAPIKey string
+	SMSSender   sms.SMSSender
+
+	AllowUnverifiedLogin    bool
+	NotifyPhoneNumberChange bool
+	TOTPEncryptionKey       []byte
+	PasswordHasher          password.PasswordHasher
+	BreachedPasswords       password.BreachedList
+	MinPasswordScore        int
+	PasswordHistorySize     int
+	MaxPasswordAge          time.Duration
+	EnumerationSafe         bool
+	HashingPool             *password.Pool
+	CookieSessions          bool
 }
 
 func NewServer(
 	opts NewServerOptions,
 ) *Server {
 	return &Server{
-		Repository: opts.Repository,
+		Repository:  opts.Repository,
+		Keys:        opts.Keys,
+		Lockout:     opts.Lockout,
+		Sessions:    opts.Sessions,
+		AdminAPIKey: opts.AdminAPIKey,
+		SMSSender:   opts.SMSSender,
+
+		AllowUnverifiedLogin:    opts.AllowUnverifiedLogin,
+		NotifyPhoneNumberChange: opts.NotifyPhoneNumberChange,
+		TOTPEncryptionKey:       opts.TOTPEncryptionKey,
+		PasswordHasher:          opts.PasswordHasher,
+		BreachedPasswords:       opts.BreachedPasswords,
+		MinPasswordScore:        opts.MinPasswordScore,
+		PasswordHistorySize:     opts.PasswordHistorySize,
+		MaxPasswordAge:          opts.MaxPasswordAge,
+		EnumerationSafe:         opts.EnumerationSafe,
+		HashingPool:             opts.HashingPool,
+		CookieSessions:          opts.CookieSessions,
 	}
 }
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	sessionIDSize        = 16
+	sessionCacheDuration = time.Duration(30) * time.Second
+	// sessionCachePruneSize is how many entries the session cache holds
+	// before set looks for expired ones to drop.
+	sessionCachePruneSize = 10000
+
+	defaultSessionMaxLifetime = time.Duration(30*24) * time.Hour
+
+	// deviceNameHeader lets clients name the device a login is made from,
+	// e.g. "Work laptop", for the session list.
+	deviceNameHeader    = "X-Device-Name"
+	maxDeviceNameLength = 100
+)
+
+var (
+	errSessionRevoked = errors.New("Session is revoked")
+	errSessionIdle    = errors.New("Session has expired after a period of inactivity")
+	errSessionEnded   = errors.New("Session has reached its maximum lifetime")
+	errSessionLimit   = errors.New("Too many active sessions, log out on another device first")
+
+	// errOutdatedToken is returned for access tokens issued before the phone
+	// number or the password of the user changed. Refreshing the token gives
+	// one with the current claims.
+	errOutdatedToken = errors.New("Token is outdated, please refresh it")
+)
+
+// SessionLimitPolicy is what a login does when the user already has the
+// maximum number of sessions.
+type SessionLimitPolicy string
+
+const (
+	// SessionLimitEvictOldest revokes the oldest sessions to make room for
+	// the new one.
+	SessionLimitEvictOldest SessionLimitPolicy = "evict_oldest"
+	// SessionLimitReject turns the login down until a session ends.
+	SessionLimitReject SessionLimitPolicy = "reject"
+)
+
+// SessionOptions configures how long sessions last and how many a user may
+// have. Zero fields fall back to the defaults above.
+type SessionOptions struct {
+	// IdleTimeout ends a session that made no request for that long; every
+	// request pushes the end further. Sessions do not go idle when it is 0.
+	IdleTimeout time.Duration
+	// MaxLifetime ends a session that long after the login, however active
+	// it is.
+	MaxLifetime time.Duration
+	// MaxConcurrent is how many active sessions a user may have at once.
+	// There is no limit when it is 0.
+	MaxConcurrent int
+	// LimitPolicy applies to logins beyond MaxConcurrent, evicting the
+	// oldest session by default.
+	LimitPolicy SessionLimitPolicy
+}
+
+func (o SessionOptions) withDefaults() SessionOptions {
+	if o.IdleTimeout < 0 {
+		o.IdleTimeout = 0
+	}
+	if o.MaxLifetime <= 0 {
+		o.MaxLifetime = defaultSessionMaxLifetime
+	}
+	if o.MaxConcurrent < 0 {
+		o.MaxConcurrent = 0
+	}
+	if o.LimitPolicy == "" {
+		o.LimitPolicy = SessionLimitEvictOldest
+	}
+	return o
+}
+
+// limit is the session limit the repository applies to a login at now.
+func (o SessionOptions) limit(now time.Time) repository.SessionLimit {
+	return repository.SessionLimit{
+		MaxSessions: o.MaxConcurrent,
+		EvictOldest: o.LimitPolicy != SessionLimitReject,
+		IdleSince:   o.idleSince(now),
+	}
+}
+
+// idleSince is the time before which a session that was not seen since has
+// gone idle, the zero time when sessions do not go idle.
+func (o SessionOptions) idleSince(now time.Time) time.Time {
+	if o.IdleTimeout == 0 {
+		return time.Time{}
+	}
+	return now.Add(-o.IdleTimeout)
+}
+
+// accessTokenDuration is how long access tokens are valid. They do not
+// outlive the idle timeout, since services verifying them on their own
+// cannot tell whether the session went idle.
+func (o SessionOptions) accessTokenDuration() time.Duration {
+	if o.IdleTimeout > 0 && o.IdleTimeout < loginExpirationDuration {
+		return o.IdleTimeout
+	}
+	return loginExpirationDuration
+}
+
+// sessionCacheEntry is what is known about a session: err is why it ended,
+// nil while it is active.
+type sessionCacheEntry struct {
+	userID       int64
+	err          error
+	expiresAt    time.Time
+	tokenVersion int64
+	cachedAt     time.Time
+}
+
+// sessionCache remembers session lookups for a short time so that
+// authenticated requests do not hit the database on every call. Revocations
+// and token version changes made by this process are applied immediately;
+// those made by other instances become visible once the cached entry ages
+// out. Since a cached lookup does not touch the session, its last seen time
+// is at most sessionCacheDuration behind per instance.
+type sessionCache struct {
+	mu      sync.Mutex
+	entries map[string]sessionCacheEntry
+	// pruneAt is the size at which set next drops expired entries. It
+	// doubles with the entries that are still fresh, so that a cache full of
+	// them is not scanned on every set.
+	pruneAt int
+}
+
+func (c *sessionCache) get(sessionID string) (entry sessionCacheEntry, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found = c.entries[sessionID]
+	if !found {
+		return entry, false
+	}
+	if time.Since(entry.cachedAt) > sessionCacheDuration {
+		delete(c.entries, sessionID)
+		return sessionCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *sessionCache) set(sessionID string, entry sessionCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]sessionCacheEntry)
+	}
+	if c.pruneAt < sessionCachePruneSize {
+		c.pruneAt = sessionCachePruneSize
+	}
+	if len(c.entries) >= c.pruneAt {
+		c.prune()
+	}
+	entry.cachedAt = time.Now()
+	c.entries[sessionID] = entry
+}
+
+// prune drops the expired entries and sets the size of the next prune.
+func (c *sessionCache) prune() {
+	for id, cached := range c.entries {
+		if time.Since(cached.cachedAt) > sessionCacheDuration {
+			delete(c.entries, id)
+		}
+	}
+	c.pruneAt = sessionCachePruneSize
+	if 2*len(c.entries) > c.pruneAt {
+		c.pruneAt = 2 * len(c.entries)
+	}
+}
+
+// deleteUser forgets the sessions of the user, so that the next request of
+// each of them looks up the token version again.
+func (c *sessionCache) deleteUser(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range c.entries {
+		if entry.userID == userID {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// lookupSession returns what is known about the session, touching it from
+// the IP address when it was not looked up recently. The error is only set
+// when the lookup itself failed; entry.err tells whether the session ended.
+func (s *Server) lookupSession(ctx context.Context, sessionID string, ipAddress string) (entry sessionCacheEntry, err error) {
+	entry, found := s.sessionCache.get(sessionID)
+	if !found {
+		opts := s.Sessions.withDefaults()
+		session, err := s.Repository.TouchSession(ctx, sessionID, ipAddress, opts.idleSince(time.Now()))
+		if err != nil {
+			log.Errorf("Error When TouchSession: %s with session id: %s", err.Error(), sessionID)
+			return entry, errors.New("There was an error when checking session")
+		}
+
+		entry = sessionCacheEntry{
+			userID:       session.UserID,
+			expiresAt:    session.ExpiresAt,
+			tokenVersion: session.TokenVersion,
+		}
+		switch {
+		case session.ID == "" || !session.RevokedAt.IsZero():
+			entry.err = errSessionRevoked
+		case opts.IdleTimeout > 0 && time.Since(session.LastSeenAt) > opts.IdleTimeout:
+			entry.err = errSessionIdle
+		}
+		s.sessionCache.set(sessionID, entry)
+	}
+
+	// The session may have reached its end while it was cached.
+	if entry.err == nil && !time.Now().Before(entry.expiresAt) {
+		entry.err = errSessionEnded
+	}
+	return entry, nil
+}
+
+// checkSession returns an error when the session behind a token no longer
+// exists, was revoked, went idle or reached its maximum lifetime, or when
+// the token was issued before the token version of the user last went up.
+func (s *Server) checkSession(ctx context.Context, claims SessionClaims, ipAddress string) error {
+	if claims.Id == "" {
+		return errors.New("No session")
+	}
+
+	entry, err := s.lookupSession(ctx, claims.Id, ipAddress)
+	if err != nil {
+		return err
+	}
+	if entry.err != nil {
+		return entry.err
+	}
+	// A token newer than the cached version was issued by an instance that
+	// already saw the change.
+	if claims.TokenVersion < entry.tokenVersion {
+		return errOutdatedToken
+	}
+
+	return nil
+}
+
+func (s *Server) revokeSession(ctx context.Context, sessionID string) error {
+	err := s.Repository.RevokeSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	s.sessionCache.set(sessionID, sessionCacheEntry{err: errSessionRevoked})
+	return nil
+}
+
+// revokeOtherSessions revokes every session of the user except the current
+// one. With an empty current session id every session is revoked.
+func (s *Server) revokeOtherSessions(ctx context.Context, userID int64, currentSessionID string) error {
+	sessionIDs, err := s.Repository.RevokeOtherSessions(ctx, userID, currentSessionID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		s.sessionCache.set(sessionID, sessionCacheEntry{userID: userID, err: errSessionRevoked})
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+)
+
+func Test_checkSession(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		claims SessionClaims
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "empty session id",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				claims: SessionClaims{StandardClaims: jwt.StandardClaims{Id: ""}},
+			},
+			mock:      func(fields *fields) {},
+			detailErr: errors.New("No session"),
+		},
+		{
+			name: "session not found",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				claims: SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+					Return(repository.Session{}, nil).
+					Times(1)
+			},
+			detailErr: errors.New("Session is revoked"),
+		},
+		{
+			name: "session expired",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				claims: SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(-time.Hour),
+					}, nil).
+					Times(1)
+			},
+			detailErr: errSessionEnded,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				claims: SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
+			detailErr: nil,
+		},
+		{
+			name: "outdated token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				claims: SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}, TokenVersion: 1},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+					Return(repository.Session{
+						ID:           "some-session",
+						UserID:       1,
+						ExpiresAt:    time.Now().Add(time.Hour),
+						TokenVersion: 2,
+					}, nil).
+					Times(1)
+			},
+			detailErr: errOutdatedToken,
+		},
+		{
+			name: "token newer than the cached version",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				claims: SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}, TokenVersion: 3},
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+					Return(repository.Session{
+						ID:           "some-session",
+						UserID:       1,
+						ExpiresAt:    time.Now().Add(time.Hour),
+						TokenVersion: 2,
+					}, nil).
+					Times(1)
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			// The second lookup must be answered by the cache, so the
+			// repository expectations above are only met once.
+			for i := 0; i < 2; i++ {
+				err := s.checkSession(context.Background(), tt.args.claims, "192.0.2.1")
+				if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+					t.Errorf("Error When checkSession() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+				}
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
+
+func Test_revokeSession(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	repo := repository.NewMockRepositoryInterface(mockCtrl)
+	s := &Server{
+		Repository: repo,
+	}
+	claims := SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}}
+
+	repo.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+		Return(repository.Session{
+			ID:        "some-session",
+			UserID:    1,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}, nil).
+		Times(1)
+	repo.EXPECT().RevokeSession(context.Background(), "some-session").
+		Return(nil).
+		Times(1)
+
+	if err := s.checkSession(context.Background(), claims, "192.0.2.1"); err != nil {
+		t.Errorf("Error When checkSession() %s", err.Error())
+	}
+	if err := s.revokeSession(context.Background(), "some-session"); err != nil {
+		t.Errorf("Error When revokeSession() %s", err.Error())
+	}
+	// A revocation made by this process must be visible without waiting for
+	// the cached lookup to expire.
+	err := s.checkSession(context.Background(), claims, "192.0.2.1")
+	if utilsHelper.ErrorMessage(err) != "Session is revoked" {
+		t.Errorf("Error When checkSession() after revokeSession() %s", utilsHelper.ErrorMessage(err))
+	}
+}
+
+func Test_sessionCache_deleteUser(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	repo := repository.NewMockRepositoryInterface(mockCtrl)
+	s := &Server{
+		Repository: repo,
+	}
+	claims := SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}, UserID: 1}
+
+	gomock.InOrder(
+		repo.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+			Return(repository.Session{
+				ID:        "some-session",
+				UserID:    1,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil).
+			Times(1),
+		repo.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", time.Time{}).
+			Return(repository.Session{
+				ID:           "some-session",
+				UserID:       1,
+				ExpiresAt:    time.Now().Add(time.Hour),
+				TokenVersion: 1,
+			}, nil).
+			Times(1),
+	)
+
+	if err := s.checkSession(context.Background(), claims, "192.0.2.1"); err != nil {
+		t.Errorf("Error When checkSession() %s", err.Error())
+	}
+	// A token version change made by this process must be visible without
+	// waiting for the cached lookup to expire.
+	s.sessionCache.deleteUser(1)
+	err := s.checkSession(context.Background(), claims, "192.0.2.1")
+	if utilsHelper.ErrorMessage(err) != errOutdatedToken.Error() {
+		t.Errorf("Error When checkSession() after deleteUser() %s", utilsHelper.ErrorMessage(err))
+	}
+}
+
+func Test_sessionCache_set(t *testing.T) {
+	c := &sessionCache{}
+	expired := time.Now().Add(-2 * sessionCacheDuration)
+
+	c.set("fresh-session", sessionCacheEntry{userID: 1})
+	c.entries["expired-session"] = sessionCacheEntry{userID: 2, cachedAt: expired}
+	c.set("other-session", sessionCacheEntry{userID: 3})
+	// Below the prune size, set does not look at the other entries.
+	if _, found := c.entries["expired-session"]; !found {
+		t.Errorf("Result When set() below prune size dropped the expired entry")
+	}
+
+	for i := len(c.entries); i < sessionCachePruneSize; i++ {
+		c.entries[fmt.Sprintf("expired-session-%d", i)] = sessionCacheEntry{cachedAt: expired}
+	}
+	c.set("new-session", sessionCacheEntry{userID: 4})
+	if len(c.entries) != 3 {
+		t.Errorf("Result When set() at prune size left %d entries, want 3", len(c.entries))
+	}
+	if _, found := c.get("fresh-session"); !found {
+		t.Errorf("Result When set() at prune size dropped a fresh entry")
+	}
+	if c.pruneAt != sessionCachePruneSize {
+		t.Errorf("Result When set() at prune size set pruneAt %d", c.pruneAt)
+	}
+}
+
+func Test_checkSession_idle(t *testing.T) {
+	tests := []struct {
+		name       string
+		lastSeenAt time.Time
+		detailErr  error
+	}{
+		{
+			name:       "idle",
+			lastSeenAt: time.Now().Add(-2 * time.Hour),
+			detailErr:  errSessionIdle,
+		},
+		{
+			name:       "active",
+			lastSeenAt: time.Now(),
+			detailErr:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			repo := repository.NewMockRepositoryInterface(mockCtrl)
+			s := &Server{
+				Repository: repo,
+				Sessions:   SessionOptions{IdleTimeout: time.Hour},
+			}
+
+			repo.EXPECT().TouchSession(context.Background(), "some-session", "192.0.2.1", gomock.Any()).
+				DoAndReturn(func(ctx context.Context, sessionID string, ipAddress string, idleSince time.Time) (repository.Session, error) {
+					if time.Since(idleSince) < time.Hour {
+						t.Errorf("Result When TouchSession() idleSince = %s", idleSince)
+					}
+					return repository.Session{
+						ID:         "some-session",
+						UserID:     1,
+						ExpiresAt:  time.Now().Add(time.Hour),
+						LastSeenAt: tt.lastSeenAt,
+					}, nil
+				}).
+				Times(1)
+
+			err := s.checkSession(context.Background(), SessionClaims{StandardClaims: jwt.StandardClaims{Id: "some-session"}}, "192.0.2.1")
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When checkSession() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_SessionOptions_accessTokenDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      SessionOptions
+		detailRes time.Duration
+	}{
+		{
+			name:      "no idle timeout",
+			opts:      SessionOptions{},
+			detailRes: loginExpirationDuration,
+		},
+		{
+			name:      "short idle timeout",
+			opts:      SessionOptions{IdleTimeout: 30 * time.Minute},
+			detailRes: 30 * time.Minute,
+		},
+		{
+			name:      "long idle timeout",
+			opts:      SessionOptions{IdleTimeout: 7 * 24 * time.Hour},
+			detailRes: loginExpirationDuration,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.opts.withDefaults().accessTokenDuration()
+			if res != tt.detailRes {
+				t.Errorf("Result When accessTokenDuration() %s, detailRes = %s", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_SessionOptions_limit(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		opts      SessionOptions
+		detailRes repository.SessionLimit
+	}{
+		{
+			name:      "no limit",
+			opts:      SessionOptions{},
+			detailRes: repository.SessionLimit{EvictOldest: true},
+		},
+		{
+			name:      "evict oldest by default",
+			opts:      SessionOptions{MaxConcurrent: 3, IdleTimeout: time.Hour},
+			detailRes: repository.SessionLimit{MaxSessions: 3, EvictOldest: true, IdleSince: now.Add(-time.Hour)},
+		},
+		{
+			name:      "reject",
+			opts:      SessionOptions{MaxConcurrent: 3, LimitPolicy: SessionLimitReject},
+			detailRes: repository.SessionLimit{MaxSessions: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.opts.withDefaults().limit(now)
+			if res != tt.detailRes {
+				t.Errorf("Result When limit() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/pkg/totp"
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	totpIssuer = "User Service"
+	// totpSkew is how many 30 second steps a code may be off either way to
+	// make up for clock drift on the phone.
+	totpSkew = 1
+
+	// Recovery codes are shown as two groups of five characters taken from
+	// an alphabet without look-alike characters.
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	mfaChallengePurpose  = "mfa_challenge"
+	mfaChallengeDuration = time.Duration(5) * time.Minute
+
+	// defaultTOTPEncryptionKey is the development key used when none is
+	// configured. Never rely on it in production.
+	defaultTOTPEncryptionKey = "ZGV2ZWxvcG1lbnQtdG90cC1lbmNyeXB0aW9uLWtleSE"
+)
+
+var errInvalidMFAChallenge = errors.New("Invalid or expired challenge token")
+
+func (s *Server) totpEncryptionKey() []byte {
+	if len(s.TOTPEncryptionKey) == 0 {
+		key, _ := base64.RawURLEncoding.DecodeString(defaultTOTPEncryptionKey)
+		return key
+	}
+	return s.TOTPEncryptionKey
+}
+
+func (s *Server) totpCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.totpEncryptionKey())
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptTOTPSecret seals the secret with AES-GCM. The random nonce is kept
+// in front of the ciphertext.
+func (s *Server) encryptTOTPSecret(secret string) (string, error) {
+	aead, err := s.totpCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (s *Server) decryptTOTPSecret(encryptedSecret string) (string, error) {
+	aead, err := s.totpCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encryptedSecret)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// verifyTOTPCode checks the code against the confirmed secret of the user
+// and records its time step, so that the same code is refused afterwards.
+func (s *Server) verifyTOTPCode(ctx context.Context, secret repository.TOTPSecret, code string) (valid bool, err error) {
+	plainSecret, err := s.decryptTOTPSecret(secret.EncryptedSecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, valid, err := totp.Validate(plainSecret, code, time.Now(), totpSkew)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	return s.Repository.UseTOTPStep(ctx, secret.UserID, step)
+}
+
+// generateMFAChallengeToken returns the short lived token that proves the
+// password step of a login. It carries no session, so it is not accepted
+// as an access token.
+func (s *Server) generateMFAChallengeToken(user repository.User) (signedToken string, err error) {
+	return s.keySet().sign(SessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "some-issuer",
+			ExpiresAt: time.Now().Add(mfaChallengeDuration).Unix(),
+		},
+		UserID:      user.ID,
+		PhoneNumber: user.PhoneNumber,
+		Purpose:     mfaChallengePurpose,
+	})
+}
+
+func (s *Server) parseMFAChallengeToken(tokenString string) (sc SessionClaims, err error) {
+	_, err = jwt.ParseWithClaims(tokenString, &sc, s.keySet().verifyKey)
+	if err != nil || sc.Purpose != mfaChallengePurpose || sc.UserID == 0 {
+		return SessionClaims{}, errInvalidMFAChallenge
+	}
+	return sc, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	code := make([]byte, 0, recoveryCodeLength+1)
+	for i := 0; i < recoveryCodeLength; i++ {
+		if i == recoveryCodeLength/2 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code = append(code, recoveryCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so that codes are
+// accepted however they were copied. Like refresh tokens, recovery codes
+// are random enough for a plain SHA-256 hash.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashRefreshToken(code)
+}
+
+// issueRecoveryCodes replaces the recovery codes of the user with new ones
+// and returns them. Only their hashes are stored.
+func (s *Server) issueRecoveryCodes(ctx context.Context, userID int64) (codes []string, err error) {
+	codeHashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		codeHashes = append(codeHashes, hashRecoveryCode(code))
+	}
+
+	err = s.Repository.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// verifySecondFactor accepts either a code from the authenticator app or an
+// unused recovery code, which is used up.
+func (s *Server) verifySecondFactor(ctx context.Context, secret repository.TOTPSecret, code string) (valid bool, err error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		return s.verifyTOTPCode(ctx, secret, code)
+	}
+	return s.Repository.UseRecoveryCode(ctx, secret.UserID, hashRecoveryCode(code))
+}
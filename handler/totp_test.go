@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Richthonio10/requirement-swtpro/pkg/totp"
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+)
+
+func Test_encryptTOTPSecret(t *testing.T) {
+	s := &Server{}
+	encrypted, err := s.encryptTOTPSecret("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("Error When encryptTOTPSecret() %s", err.Error())
+	}
+	if encrypted == "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Result When encryptTOTPSecret() stored the plain secret")
+	}
+
+	other, _ := s.encryptTOTPSecret("JBSWY3DPEHPK3PXP")
+	if other == encrypted {
+		t.Errorf("Result When encryptTOTPSecret() reused a nonce")
+	}
+
+	secret, err := s.decryptTOTPSecret(encrypted)
+	if err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Result When decryptTOTPSecret() %s, %v", secret, err)
+	}
+
+	otherKey := &Server{TOTPEncryptionKey: []byte("another-32-byte-encryption-key!!")}
+	if _, err := otherKey.decryptTOTPSecret(encrypted); err == nil {
+		t.Errorf("Error When decryptTOTPSecret() accepted a secret sealed with another key")
+	}
+	if _, err := s.decryptTOTPSecret("short"); err == nil {
+		t.Errorf("Error When decryptTOTPSecret() accepted a truncated secret")
+	}
+}
+
+func Test_verifyTOTPCode(t *testing.T) {
+	s := &Server{}
+	encrypted, _ := s.encryptTOTPSecret("JBSWY3DPEHPK3PXP")
+	secret := repository.TOTPSecret{
+		UserID:          1,
+		EncryptedSecret: encrypted,
+		ConfirmedAt:     time.Now(),
+	}
+	code, _ := totp.Code("JBSWY3DPEHPK3PXP", totp.Step(time.Now()))
+
+	tests := []struct {
+		name      string
+		code      string
+		mock      func(mockRepository *repository.MockRepositoryInterface)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name:      "wrong code",
+			code:      "abcdef",
+			mock:      func(mockRepository *repository.MockRepositoryInterface) {},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "error UseTOTPStep",
+			code: code,
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().UseTOTPStep(context.Background(), int64(1), gomock.Any()).
+					Return(false, errors.New("expected UseTOTPStep error")).
+					Times(1)
+			},
+			detailRes: false,
+			detailErr: errors.New("expected UseTOTPStep error"),
+		},
+		{
+			name: "code already used",
+			code: code,
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().UseTOTPStep(context.Background(), int64(1), gomock.Any()).
+					Return(false, nil).
+					Times(1)
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			code: code,
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().UseTOTPStep(context.Background(), int64(1), totp.Step(time.Now())).
+					Return(true, nil).
+					Times(1)
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockRepository := repository.NewMockRepositoryInterface(mockCtrl)
+			tt.mock(mockRepository)
+
+			s.Repository = mockRepository
+			res, err := s.verifyTOTPCode(context.Background(), secret, tt.code)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When verifyTOTPCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When verifyTOTPCode() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_parseMFAChallengeToken(t *testing.T) {
+	s := &Server{}
+	user := repository.User{ID: 1, PhoneNumber: "+62821232342"}
+
+	challengeToken, _ := s.generateMFAChallengeToken(user)
+	accessToken, _ := s.generateToken(user, "some-session")
+	expiredToken, _ := s.keySet().sign(SessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+		},
+		UserID:  1,
+		Purpose: mfaChallengePurpose,
+	})
+
+	tests := []struct {
+		name      string
+		token     string
+		detailErr error
+	}{
+		{name: "passed", token: challengeToken, detailErr: nil},
+		{name: "access token", token: accessToken, detailErr: errInvalidMFAChallenge},
+		{name: "expired", token: expiredToken, detailErr: errInvalidMFAChallenge},
+		{name: "malformed", token: "not-a-token", detailErr: errInvalidMFAChallenge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.parseMFAChallengeToken(tt.token)
+			if err != tt.detailErr {
+				t.Errorf("Error When parseMFAChallengeToken() %v, detailErr = %v", err, tt.detailErr)
+			}
+			if err == nil && (res.UserID != 1 || res.PhoneNumber != "+62821232342") {
+				t.Errorf("Result When parseMFAChallengeToken() %+v", res)
+			}
+		})
+	}
+}
+
+func Test_generateRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatalf("Error When generateRecoveryCode() %s", err.Error())
+	}
+	if len(code) != recoveryCodeLength+1 || code[recoveryCodeLength/2] != '-' {
+		t.Errorf("Result When generateRecoveryCode() %s", code)
+	}
+	if strings.Trim(strings.ReplaceAll(code, "-", ""), recoveryCodeAlphabet) != "" {
+		t.Errorf("Result When generateRecoveryCode() %s uses characters outside the alphabet", code)
+	}
+
+	other, _ := generateRecoveryCode()
+	if other == code {
+		t.Errorf("Result When generateRecoveryCode() returned the same code twice")
+	}
+}
+
+func Test_hashRecoveryCode(t *testing.T) {
+	want := hashRecoveryCode("abcde-fghjk")
+	for _, code := range []string{"abcdefghjk", "ABCDE-FGHJK", " abcde fghjk "} {
+		if got := hashRecoveryCode(code); got != want {
+			t.Errorf("Result When hashRecoveryCode(%q) %s, want = %s", code, got, want)
+		}
+	}
+	if hashRecoveryCode("abcde-fghjm") == want {
+		t.Errorf("Result When hashRecoveryCode() matched another code")
+	}
+}
@@ -1,24 +1,29 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Richthonio10/requirement-swtpro/generated"
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
 	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
 	"github.com/Richthonio10/requirement-swtpro/repository"
 	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 )
 
 
 func Test_generateToken(t *testing.T) {
 	type args struct {
-		user repository.User
+		user      repository.User
+		sessionID string
 	}
 	tests := []struct {
 		name    string
@@ -33,6 +38,7 @@ func Test_generateToken(t *testing.T) {
 					ID:          1,
 					PhoneNumber: "+628223344556",
 				},
+				sessionID: "some-session",
 			},
 			detailRes: "let's say this is a jwt token",
 			Err: nil,
@@ -40,7 +46,8 @@ func Test_generateToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res, err := generateToken(tt.args.user)
+			s := &Server{}
+			res, err := s.generateToken(tt.args.user, tt.args.sessionID)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.Err) {
 				t.Errorf("Error When generateToken() %s, Err = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.Err))
 			}
@@ -52,21 +59,80 @@ func Test_generateToken(t *testing.T) {
 }
 
 func Test_getSessionClaims(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
 	type args struct {
 		ctx echo.Context
 	}
+	newContext := func(sessionID string) echo.Context {
+		req, _ := http.NewRequest(http.MethodGet, "url", nil)
+		jwt, _ := (&Server{}).generateToken(repository.User{
+			ID:          1,
+			PhoneNumber: "+62821232342",
+		}, sessionID)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+		res := httptest.NewRecorder()
+		return echo.New().NewContext(req, res)
+	}
 	tests := []struct {
-		name    string
-		args    args
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
 		detailRes SessionClaims
-		Err error
+		Err       error
 	}{
 		{
-			name: "passed",
+			name: "no authorization",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
 			args: args{
 				ctx: func() echo.Context {
 					req, _ := http.NewRequest(http.MethodGet, "url", nil)
-					jwt, _ := generateToken(repository.User{
+					res := httptest.NewRecorder()
+					return echo.New().NewContext(req, res)
+				}(),
+			},
+			mock:      func(fields *fields) {},
+			detailRes: SessionClaims{},
+			Err:       errors.New("Unauthorized"),
+		},
+		{
+			name: "no session id",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext(""),
+			},
+			mock:      func(fields *fields) {},
+			detailRes: SessionClaims{},
+			Err:       errors.New("No session"),
+		},
+		{
+			name: "challenge token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: func() echo.Context {
+					req, _ := http.NewRequest(http.MethodGet, "url", nil)
+					jwt, _ := (&Server{}).generateMFAChallengeToken(repository.User{
 						ID:          1,
 						PhoneNumber: "+62821232342",
 					})
@@ -75,8 +141,104 @@ func Test_getSessionClaims(t *testing.T) {
 					return echo.New().NewContext(req, res)
 				}(),
 			},
+			mock:      func(fields *fields) {},
+			detailRes: SessionClaims{},
+			Err:       errors.New("Not an access token"),
+		},
+		{
+			name: "error TouchSession",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext("some-session"),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{}, errors.New("expected TouchSession error")).
+					Times(1)
+			},
+			detailRes: SessionClaims{},
+			Err:       errors.New("There was an error when checking session"),
+		},
+		{
+			name: "session revoked",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext("some-session"),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+						RevokedAt: time.Now(),
+					}, nil).
+					Times(1)
+			},
+			detailRes: SessionClaims{},
+			Err:       errors.New("Session is revoked"),
+		},
+		{
+			name: "outdated token",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext("some-session"),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:           "some-session",
+						UserID:       1,
+						ExpiresAt:    time.Now().Add(time.Hour),
+						TokenVersion: 1,
+					}, nil).
+					Times(1)
+			},
+			detailRes: SessionClaims{},
+			Err:       errOutdatedToken,
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				ctx: newContext("some-session"),
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().TouchSession(context.Background(), "some-session", gomock.Any(), time.Time{}).
+					Return(repository.Session{
+						ID:        "some-session",
+						UserID:    1,
+						ExpiresAt: time.Now().Add(time.Hour),
+					}, nil).
+					Times(1)
+			},
 			detailRes: SessionClaims{
 				StandardClaims: jwt.StandardClaims{
+					Id:     "some-session",
 					Issuer: "some-issuer",
 				},
 				UserID:      1,
@@ -87,7 +249,11 @@ func Test_getSessionClaims(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res, err := getSessionClaims(tt.args.ctx)
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			res, err := s.getSessionClaims(tt.args.ctx)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.Err) {
 				t.Errorf("Error When getSessionClaims() = %s, Err = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.Err))
 			}
@@ -95,6 +261,7 @@ func Test_getSessionClaims(t *testing.T) {
 			if res != tt.detailRes {
 				t.Errorf("Result When getSessionClaims() = %+v, detailRes = %+v", res, tt.detailRes)
 			}
+			tt.fields.mockCtrl.Finish()
 		})
 	}
 }
@@ -186,7 +353,7 @@ func Test_createHashPassword(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res, err := createHashPassword(tt.args.input)
+			res, err := (&Server{}).createHashPassword(context.Background(), tt.args.input)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.Err) {
 				t.Errorf("Error When createHashPassword() %s, Err = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.Err))
 			}
@@ -223,13 +390,202 @@ func Test_comparePasswords(t *testing.T) {
 			},
 			detailRes: true,
 		},
+		{
+			name: "peppered hash without pepper",
+			args: args{
+				hashedPassword: "$peppered$id=2024$$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG",
+				plainPassword:  "SawitPro123$",
+			},
+			detailRes: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res := comparePasswords(tt.args.hashedPassword, tt.args.plainPassword)
+			res, err := (&Server{}).comparePasswords(context.Background(), tt.args.hashedPassword, tt.args.plainPassword)
+			if err != nil {
+				t.Errorf("Error When comparePasswords() %s", err.Error())
+			}
 			if res != tt.detailRes {
 				t.Errorf("Result When comparePasswords() %t, detailRes = %t", res, tt.detailRes)
 			}
 		})
 	}
 }
+
+// saturatedPool returns a hashing pool with every slot and queue place
+// taken until the test ends.
+func saturatedPool(t *testing.T) *password.Pool {
+	pool := password.NewPool(password.PoolOptions{Concurrency: 1, QueueDepth: 1})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	for i := 0; i < 2; i++ {
+		go pool.Do(context.Background(), func() { <-release })
+	}
+	for {
+		stats := pool.Stats()
+		if stats.Running == 1 && stats.Queued == 1 {
+			return pool
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func Test_comparePasswords_saturated(t *testing.T) {
+	s := &Server{HashingPool: saturatedPool(t)}
+	_, err := s.comparePasswords(context.Background(), "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG", "SawitPro123$")
+	if !errors.Is(err, password.ErrPoolSaturated) {
+		t.Errorf("Error When comparePasswords() %v, Err = %v", err, password.ErrPoolSaturated)
+	}
+}
+
+func Test_hashingFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		retryAfter string
+	}{
+		{
+			name:       "pool saturated",
+			err:        password.ErrPoolSaturated,
+			statusCode: http.StatusServiceUnavailable,
+			retryAfter: "1",
+		},
+		{
+			name:       "canceled",
+			err:        context.Canceled,
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "url", nil)
+			res := httptest.NewRecorder()
+			statusCode, _ := hashingFailure(echo.New().NewContext(req, res), tt.err)
+			if statusCode != tt.statusCode {
+				t.Errorf("Result When hashingFailure() %d, statusCode = %d", statusCode, tt.statusCode)
+			}
+			if res.Header().Get("Retry-After") != tt.retryAfter {
+				t.Errorf("Result When hashingFailure() Retry-After = %q, retryAfter = %q", res.Header().Get("Retry-After"), tt.retryAfter)
+			}
+		})
+	}
+}
+
+func Test_createSession(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	repo := repository.NewMockRepositoryInterface(mockCtrl)
+
+	req, _ := http.NewRequest(http.MethodPost, "url", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set("X-Device-Name", " Work laptop ")
+	repo.EXPECT().InsertSession(context.Background(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, data repository.Session, limit repository.SessionLimit) ([]string, error) {
+			if data.ID == "" || data.UserID != 1 || data.DeviceName != "Work laptop" || data.UserAgent != "curl/8.0" || data.IPAddress != "192.0.2.1" {
+				t.Errorf("Result When InsertSession() %+v", data)
+			}
+			if limit.MaxSessions != 2 || !limit.EvictOldest || limit.IdleSince.IsZero() {
+				t.Errorf("Result When InsertSession() limit = %+v", limit)
+			}
+			return []string{"oldest-session"}, nil
+		}).
+		Times(1)
+
+	s := &Server{
+		Repository: repo,
+		Sessions: SessionOptions{
+			IdleTimeout:   time.Hour,
+			MaxConcurrent: 2,
+		},
+	}
+	sessionID, err := s.createSession(echo.New().NewContext(req, httptest.NewRecorder()), 1)
+	if err != nil || sessionID == "" {
+		t.Errorf("Error When createSession() %v, session id = %q", err, sessionID)
+	}
+	entry, found := s.sessionCache.get("oldest-session")
+	if !found || entry.err != errSessionRevoked {
+		t.Errorf("Result When createSession() evicted session = %+v, found = %v", entry, found)
+	}
+}
+
+func Test_issueRefreshToken(t *testing.T) {
+	type fields struct {
+		mockCtrl   *gomock.Controller
+		Repository *repository.MockRepositoryInterface
+	}
+	type args struct {
+		userID   int64
+		familyID string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error InsertRefreshToken",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				userID:   1,
+				familyID: "some-family",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					Return(int64(0), errors.New("expected InsertRefreshToken error")).
+					Times(1)
+			},
+			detailErr: errors.New("expected InsertRefreshToken error"),
+		},
+		{
+			name: "passed",
+			fields: func() fields {
+				mockCtrl := gomock.NewController(t)
+				return fields{
+					mockCtrl:   mockCtrl,
+					Repository: repository.NewMockRepositoryInterface(mockCtrl),
+				}
+			}(),
+			args: args{
+				userID:   1,
+				familyID: "some-family",
+			},
+			mock: func(fields *fields) {
+				fields.Repository.EXPECT().InsertRefreshToken(context.Background(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, data repository.RefreshToken) (int64, error) {
+						if data.FamilyID != "some-family" {
+							t.Errorf("InsertRefreshToken() got family %s, want some-family", data.FamilyID)
+						}
+						return 1, nil
+					}).
+					Times(1)
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Repository: tt.fields.Repository,
+			}
+			tt.mock(&tt.fields)
+			res, err := s.issueRefreshToken(context.Background(), tt.args.userID, tt.args.familyID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When issueRefreshToken() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if (len(res) != 0) != (tt.detailErr == nil) {
+				t.Errorf("Result When issueRefreshToken() %s", res)
+			}
+			tt.fields.mockCtrl.Finish()
+		})
+	}
+}
@@ -2,10 +2,15 @@ package handler
 
 import (
 	"context"
+	"net/http"
 	"regexp"
 	"strings"
 
 	"github.com/Richthonio10/requirement-swtpro/generated"
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
+	"github.com/Richthonio10/requirement-swtpro/repository"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	"github.com/labstack/gommon/log"
 )
 
 func validateFullName(input string) []string {
@@ -40,6 +45,17 @@ func ValidatePhoneNumber(
 	return res, err
 }
 
+// registrationTaken picks the answer to a registration for a phone number
+// that already has an account. In enumeration safe mode it looks like any
+// other registration and the owner of the number is told by SMS instead.
+func (s *Server) registrationTaken(ctx context.Context, phoneNumber string) (statusCode int, header generated.ResponseHeader) {
+	if s.EnumerationSafe {
+		s.notifyRegistrationAttempt(ctx, phoneNumber)
+		return http.StatusOK, createResponseHeader(200, []string{registrationReceivedMessage}, true)
+	}
+	return http.StatusBadRequest, createResponseHeader(utilsHelper.ValidationErrorCode, []string{"Phone number is already registered"}, false)
+}
+
 func checkPhoneNumber(input string) []string {
 	var res []string
 
@@ -53,6 +69,8 @@ func checkPhoneNumber(input string) []string {
 	return res
 }
 
+const passwordRequirementMessage = "Passwords must be minimum 6 characters and maximum 64 characters, containing at least 1 capital characters AND 1 number AND 1 special (non alpha-numeric) characters"
+
 func validatePassword(input string) bool {
 	validations := []string{".{6,64}", "[A-Z]", "[0-9]", "[^\\d\\w]"}
 	for _, validation := range validations {
@@ -65,13 +83,59 @@ func validatePassword(input string) bool {
 	return true
 }
 
-func validateRegistration(request generated.RegistrationRequest) []string {
+const (
+	// defaultMinPasswordScore is the lowest password.Score accepted when
+	// none is configured.
+	defaultMinPasswordScore = 2
+	breachedPasswordMessage = "Password has appeared in a data breach, choose another one"
+	weakPasswordMessage     = "Password is too easy to guess"
+)
+
+func (s *Server) minPasswordScore() int {
+	if s.MinPasswordScore == 0 {
+		return defaultMinPasswordScore
+	}
+	return s.MinPasswordScore
+}
+
+// checkPasswordStrength returns why the password is rejected beyond the
+// rules of validatePassword: it is in the breached password list, or it is
+// too easy to guess, also given the name and phone number of the user. The
+// password is not rejected when the list cannot be read.
+func (s *Server) checkPasswordStrength(input string, user repository.User) []string {
+	if s.BreachedPasswords != nil {
+		breached, err := s.BreachedPasswords.Contains(input)
+		if err != nil {
+			log.Errorf("Error When Contains: %s", err.Error())
+		}
+		if breached {
+			return []string{breachedPasswordMessage}
+		}
+	}
+
+	strength := password.Score(input, user.FullName, user.PhoneNumber, strings.TrimPrefix(user.PhoneNumber, "+62"))
+	if strength.Score < s.minPasswordScore() {
+		if strength.Warning == "" {
+			return []string{weakPasswordMessage}
+		}
+		return []string{weakPasswordMessage + ": " + strength.Warning}
+	}
+
+	return nil
+}
+
+func (s *Server) validateRegistration(request generated.RegistrationRequest) []string {
 	var res []string
 	res = append(res, validateFullName(request.FullName)...)
 	res = append(res, checkPhoneNumber(request.PhoneNumber)...)
 
 	if !validatePassword(request.Password) {
-		res = append(res, "Passwords must be minimum 6 characters and maximum 64 characters, containing at least 1 capital characters AND 1 number AND 1 special (non alpha-numeric) characters")
+		res = append(res, passwordRequirementMessage)
+	} else {
+		res = append(res, s.checkPasswordStrength(request.Password, repository.User{
+			FullName:    request.FullName,
+			PhoneNumber: request.PhoneNumber,
+		})...)
 	}
 
 	return res
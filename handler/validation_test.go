@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/Richthonio10/requirement-swtpro/generated"
+	"github.com/Richthonio10/requirement-swtpro/pkg/password"
 	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
 	"github.com/Richthonio10/requirement-swtpro/repository"
 	"github.com/golang/mock/gomock"
@@ -323,13 +325,90 @@ func Test_validateRegistration(t *testing.T) {
 				"Passwords must be minimum 6 characters and maximum 64 characters, containing at least 1 capital characters AND 1 number AND 1 special (non alpha-numeric) characters",
 			},
 		},
+		{
+			name: "common password",
+			args: args{
+				request: generated.RegistrationRequest{
+					PhoneNumber: "+6298080980",
+					FullName:    "Some Full Name",
+					Password:    "Password1!",
+				},
+			},
+			detailRes: []string{
+				"Password is too easy to guess: This is a top-10 common password",
+			},
+		},
+		{
+			name: "passed",
+			args: args{
+				request: generated.RegistrationRequest{
+					PhoneNumber: "+6298080980",
+					FullName:    "Some Full Name",
+					Password:    "SawitPro!2344",
+				},
+			},
+			detailRes: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res := validateRegistration(tt.args.request)
+			res := (&Server{}).validateRegistration(tt.args.request)
 			if !reflect.DeepEqual(res, tt.detailRes) {
 				t.Errorf("Result When validateRegistration() %+v, detailRes = %+v", res, tt.detailRes)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func Test_checkPasswordStrength(t *testing.T) {
+	breached, _ := password.LoadBreachedSet(strings.NewReader("32CA9FC1A0F5B6330E3F4C8C1BBECDE9BEDB9573:2134\n"))
+	user := repository.User{
+		FullName:    "Budi Santoso",
+		PhoneNumber: "+62821232342",
+	}
+	tests := []struct {
+		name      string
+		server    *Server
+		input     string
+		detailRes []string
+	}{
+		{
+			name:      "breached password",
+			server:    &Server{BreachedPasswords: breached},
+			input:     "Password1!",
+			detailRes: []string{breachedPasswordMessage},
+		},
+		{
+			name:      "weak password without breached list",
+			server:    &Server{},
+			input:     "Password1!",
+			detailRes: []string{"Password is too easy to guess: This is a top-10 common password"},
+		},
+		{
+			name:      "contains phone number",
+			server:    &Server{},
+			input:     "A!0821232342",
+			detailRes: []string{"Password is too easy to guess: Passwords containing your name or phone number are easy to guess"},
+		},
+		{
+			name:      "below configured score",
+			server:    &Server{MinPasswordScore: 4},
+			input:     "NewPassword123$",
+			detailRes: []string{"Password is too easy to guess: This is a top-10 common password"},
+		},
+		{
+			name:      "passed",
+			server:    &Server{BreachedPasswords: breached},
+			input:     "SawitPro!2344",
+			detailRes: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.server.checkPasswordStrength(tt.input, user)
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When checkPasswordStrength() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
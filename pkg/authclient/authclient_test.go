@@ -0,0 +1,425 @@
+package authclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+// keyServer is a stub of the user service JWKS endpoint.
+type keyServer struct {
+	*httptest.Server
+	keys     map[string]*rsa.PrivateKey
+	requests int32
+}
+
+func newKeyServer(t *testing.T, keyIDs ...string) *keyServer {
+	ks := &keyServer{
+		keys: make(map[string]*rsa.PrivateKey),
+	}
+	for _, keyID := range keyIDs {
+		ks.addKey(t, keyID)
+	}
+	ks.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ks.requests, 1)
+		var set jsonWebKeySet
+		for keyID, key := range ks.keys {
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kty: "RSA",
+				Kid: keyID,
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(ks.Close)
+	return ks
+}
+
+func (ks *keyServer) addKey(t *testing.T, keyID string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("[addKey] %s", err.Error())
+	}
+	ks.keys[keyID] = key
+}
+
+func (ks *keyServer) sign(t *testing.T, keyID string, claims SessionClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	tokenString, err := token.SignedString(ks.keys[keyID])
+	if err != nil {
+		t.Fatalf("[sign] %s", err.Error())
+	}
+	return tokenString
+}
+
+func validClaims() SessionClaims {
+	return SessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        "some-session",
+			Issuer:    "some-issuer",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+		UserID:      1,
+		PhoneNumber: "+62821232342",
+	}
+}
+
+func Test_Verifier_Verify(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	v := NewVerifier(Options{
+		JWKSURL: ks.URL,
+		Issuer:  "some-issuer",
+	})
+
+	expiredClaims := validClaims()
+	expiredClaims.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+	otherIssuerClaims := validClaims()
+	otherIssuerClaims.Issuer = "other-issuer"
+	challengeClaims := validClaims()
+	challengeClaims.Purpose = "mfa_challenge"
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+
+	tests := []struct {
+		name      string
+		token     string
+		detailErr bool
+	}{
+		{
+			name:      "passed",
+			token:     ks.sign(t, "current", validClaims()),
+			detailErr: false,
+		},
+		{
+			name:      "expired",
+			token:     ks.sign(t, "current", expiredClaims),
+			detailErr: true,
+		},
+		{
+			name:      "other issuer",
+			token:     ks.sign(t, "current", otherIssuerClaims),
+			detailErr: true,
+		},
+		{
+			name:      "not an access token",
+			token:     ks.sign(t, "current", challengeClaims),
+			detailErr: true,
+		},
+		{
+			name:      "symmetric signature",
+			token:     hmacToken,
+			detailErr: true,
+		},
+		{
+			name:      "malformed",
+			token:     "not-a-token",
+			detailErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := v.Verify(context.Background(), tt.token)
+			if (err != nil) != tt.detailErr {
+				t.Fatalf("Error When Verify() %v, detailErr = %t", err, tt.detailErr)
+			}
+			if err == nil && (res.UserID != 1 || res.PhoneNumber != "+62821232342" || res.Id != "some-session") {
+				t.Errorf("Result When Verify() %+v", res)
+			}
+		})
+	}
+}
+
+func Test_Verifier_caching(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	v := NewVerifier(Options{
+		JWKSURL: ks.URL,
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), ks.sign(t, "current", validClaims())); err != nil {
+			t.Fatalf("Error When Verify() %s", err.Error())
+		}
+	}
+	if requests := atomic.LoadInt32(&ks.requests); requests != 1 {
+		t.Errorf("Result When Verify() fetched jwks %d times, detailRes = 1", requests)
+	}
+
+	// A token signed with an unknown key only triggers a new fetch once the
+	// minimum refresh interval has passed.
+	_, err := v.Verify(context.Background(), signWithNewKey(t, ks, "rotated"))
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Error When Verify() %v, detailErr = %v", err, ErrUnknownKey)
+	}
+	if requests := atomic.LoadInt32(&ks.requests); requests != 1 {
+		t.Errorf("Result When Verify() fetched jwks %d times, detailRes = 1", requests)
+	}
+}
+
+func signWithNewKey(t *testing.T, ks *keyServer, keyID string) string {
+	ks.addKey(t, keyID)
+	return ks.sign(t, keyID, validClaims())
+}
+
+func Test_Verifier_rotation(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	v := NewVerifier(Options{
+		JWKSURL:            ks.URL,
+		MinRefreshInterval: time.Nanosecond,
+	})
+
+	if _, err := v.Verify(context.Background(), ks.sign(t, "current", validClaims())); err != nil {
+		t.Fatalf("Error When Verify() %s", err.Error())
+	}
+
+	// The user service started signing with a new key after our last fetch.
+	token := signWithNewKey(t, ks, "rotated")
+	time.Sleep(time.Millisecond)
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Errorf("Error When Verify() after rotation %s", err.Error())
+	}
+	if requests := atomic.LoadInt32(&ks.requests); requests != 2 {
+		t.Errorf("Result When Verify() fetched jwks %d times, detailRes = 2", requests)
+	}
+}
+
+func Test_Verifier_unreachable(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	token := ks.sign(t, "current", validClaims())
+	ks.Close()
+
+	v := NewVerifier(Options{
+		JWKSURL: ks.URL,
+	})
+	if _, err := v.Verify(context.Background(), token); err == nil {
+		t.Errorf("Error When Verify() expected an error without reachable keys")
+	}
+}
+
+func Test_Verifier_unreachable_throttled(t *testing.T) {
+	var requests int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	token := newKeyServer(t, "current").sign(t, "current", validClaims())
+
+	v := NewVerifier(Options{
+		JWKSURL: down.URL,
+	})
+	for i := 0; i < 5; i++ {
+		if _, err := v.Verify(context.Background(), token); err == nil {
+			t.Errorf("Error When Verify() expected an error without reachable keys")
+		}
+	}
+	if requests := atomic.LoadInt32(&requests); requests != 1 {
+		t.Errorf("Result When Verify() fetched jwks %d times, detailRes = 1", requests)
+	}
+}
+
+// slowKeyServer serves the keys of ks, holding every request after the
+// first until release is closed.
+func slowKeyServer(t *testing.T, ks *keyServer) (server *httptest.Server, release chan struct{}, requests *int32) {
+	release = make(chan struct{})
+	requests = new(int32)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) > 1 {
+			<-release
+		}
+		ks.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+	return server, release, requests
+}
+
+func Test_Verifier_slowRefresh(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	slow, _, _ := slowKeyServer(t, ks)
+	v := NewVerifier(Options{
+		JWKSURL:            slow.URL,
+		CacheDuration:      time.Nanosecond,
+		MinRefreshInterval: time.Nanosecond,
+	})
+	token := ks.sign(t, "current", validClaims())
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Error When Verify() %s", err.Error())
+	}
+
+	// The keys are stale and the refresh hangs, but known keys are still
+	// verified right away.
+	time.Sleep(time.Millisecond)
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(context.Background(), token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Error When Verify() during a refresh %s", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Result When Verify() waited for the refresh")
+	}
+	v.mu.Lock()
+	refreshing := v.refreshing != nil
+	v.mu.Unlock()
+	if !refreshing {
+		t.Errorf("Result When Verify() did not refresh the stale keys")
+	}
+}
+
+func Test_Verifier_sharedRefresh(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	token := ks.sign(t, "current", validClaims())
+	slow, release, requests := slowKeyServer(t, ks)
+	v := NewVerifier(Options{
+		JWKSURL: slow.URL,
+	})
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Error When Verify() %s", err.Error())
+	}
+
+	// Tokens signed with a new key all wait for the same fetch.
+	v.mu.Lock()
+	v.attemptedAt = time.Time{}
+	v.mu.Unlock()
+	rotated := signWithNewKey(t, ks, "rotated")
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			_, err := v.Verify(context.Background(), rotated)
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	for i := 0; i < 5; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Error When Verify() after rotation %s", err.Error())
+		}
+	}
+	if requests := atomic.LoadInt32(requests); requests != 2 {
+		t.Errorf("Result When Verify() fetched jwks %d times, detailRes = 2", requests)
+	}
+}
+
+func Test_Middleware(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	v := NewVerifier(Options{
+		JWKSURL: ks.URL,
+	})
+	handler := Middleware(v)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok || claims.UserID != 1 {
+			t.Errorf("Result When ClaimsFromContext() %+v, %t", claims, ok)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		statusCode    int
+	}{
+		{
+			name:          "no authorization",
+			authorization: "",
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "invalid token",
+			authorization: "Bearer not-a-token",
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "passed",
+			authorization: "Bearer " + ks.sign(t, "current", validClaims()),
+			statusCode:    http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+			if res.Code != tt.statusCode {
+				t.Errorf("Result When Middleware() %d, statusCode = %d", res.Code, tt.statusCode)
+			}
+		})
+	}
+}
+
+func Test_EchoMiddleware(t *testing.T) {
+	ks := newKeyServer(t, "current")
+	v := NewVerifier(Options{
+		JWKSURL: ks.URL,
+	})
+	e := echo.New()
+	e.GET("/", func(c echo.Context) error {
+		claims, ok := ClaimsFromContext(c.Request().Context())
+		if !ok || claims.UserID != 1 {
+			t.Errorf("Result When ClaimsFromContext() %+v, %t", claims, ok)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}, EchoMiddleware(v))
+
+	tests := []struct {
+		name          string
+		authorization string
+		statusCode    int
+	}{
+		{
+			name:          "no authorization",
+			authorization: "",
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "wrong scheme",
+			authorization: "Basic dXNlcjpwYXNz",
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "passed",
+			authorization: "Bearer " + ks.sign(t, "current", validClaims()),
+			statusCode:    http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+			if res.Code != tt.statusCode {
+				t.Errorf("Result When EchoMiddleware() %d, statusCode = %d", res.Code, tt.statusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package authclient
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	defaultCacheDuration      = time.Duration(5) * time.Minute
+	defaultMinRefreshInterval = time.Duration(1) * time.Minute
+	defaultRequestTimeout     = time.Duration(10) * time.Second
+)
+
+type Options struct {
+	// JWKSURL is the user service key endpoint, e.g.
+	// http://user-service/.well-known/jwks.json.
+	JWKSURL    string
+	HTTPClient *http.Client
+	// CacheDuration is how long fetched keys are used before they are
+	// fetched again. Defaults to 5 minutes.
+	CacheDuration time.Duration
+	// MinRefreshInterval limits how often a token signed with an unknown
+	// key id triggers an early fetch. Defaults to 1 minute.
+	MinRefreshInterval time.Duration
+	// Issuer, when set, must match the iss claim.
+	Issuer string
+}
+
+// Verifier checks tokens against the keys published by the user service.
+// It is safe for concurrent use.
+type Verifier struct {
+	opts Options
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// refreshing is the fetch in progress, if any. Callers that need fresh
+	// keys wait for it instead of starting their own.
+	refreshing *refreshCall
+	// refreshErr is why the last fetch failed, nil once one succeeded.
+	refreshErr error
+}
+
+// refreshCall is a fetch of the keys; err is set before done is closed.
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+func NewVerifier(opts Options) *Verifier {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: defaultRequestTimeout}
+	}
+	if opts.CacheDuration == 0 {
+		opts.CacheDuration = defaultCacheDuration
+	}
+	if opts.MinRefreshInterval == 0 {
+		opts.MinRefreshInterval = defaultMinRefreshInterval
+	}
+	return &Verifier{
+		opts: opts,
+	}
+}
+
+// Verify parses the token and returns its claims when the signature, expiry
+// and issuer are valid and the token is an access token.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*SessionClaims, error) {
+	claims := &SessionClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("authclient: unexpected signing method %v", token.Header["alg"])
+		}
+		keyID, _ := token.Header["kid"].(string)
+		return v.key(ctx, keyID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if v.opts.Issuer != "" && !claims.VerifyIssuer(v.opts.Issuer, true) {
+		return nil, ErrInvalidIssuer
+	}
+	if claims.Purpose != "" {
+		return nil, ErrNotAccessToken
+	}
+
+	return claims, nil
+}
+
+// key returns the key by id. Known keys are returned right away, and once
+// they are stale a fetch is started in the background; stale keys keep
+// being used while the endpoint is unreachable. An unknown key id waits for
+// a fetch, as the key may have been added by a rotation since the last one.
+// Fetches run without holding the lock and concurrent callers share one.
+func (v *Verifier) key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	key, found := v.lookup(keyID)
+	var call *refreshCall
+	if found {
+		if v.refreshing == nil && time.Since(v.fetchedAt) > v.opts.CacheDuration && v.canRefresh() {
+			v.startRefresh()
+		}
+	} else if v.refreshing != nil {
+		call = v.refreshing
+	} else if v.canRefresh() {
+		call = v.startRefresh()
+	}
+	noKeys, refreshErr := len(v.keys) == 0, v.refreshErr
+	v.mu.Unlock()
+
+	if found {
+		return key, nil
+	}
+	if call == nil {
+		if noKeys && refreshErr != nil {
+			return nil, refreshErr
+		}
+		return nil, ErrUnknownKey
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+
+	v.mu.Lock()
+	key, found = v.lookup(keyID)
+	v.mu.Unlock()
+	if !found {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// canRefresh throttles fetches, so a flood of tokens with a bogus kid or an
+// unreachable endpoint does not turn into a flood of requests to the user
+// service. This holds before any key was fetched too.
+func (v *Verifier) canRefresh() bool {
+	return time.Since(v.attemptedAt) > v.opts.MinRefreshInterval
+}
+
+// startRefresh fetches the keys in the background. It must be called with
+// the lock held.
+func (v *Verifier) startRefresh() *refreshCall {
+	v.attemptedAt = time.Now()
+	call := &refreshCall{done: make(chan struct{})}
+	v.refreshing = call
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+		defer cancel()
+		keys, err := v.fetch(ctx)
+
+		v.mu.Lock()
+		if err == nil {
+			v.keys = keys
+			v.fetchedAt = time.Now()
+		}
+		v.refreshErr = err
+		v.refreshing = nil
+		v.mu.Unlock()
+
+		call.err = err
+		close(call.done)
+	}()
+
+	return call
+}
+
+// lookup finds the key by id. Tokens without a kid are only accepted while a
+// single key is published, so there is no ambiguity about which one to use.
+func (v *Verifier) lookup(keyID string) (*rsa.PublicKey, bool) {
+	if keyID == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, found := v.keys[keyID]
+	return key, found
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// fetch gets the published keys from the user service.
+func (v *Verifier) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.opts.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := v.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("authclient: fetch jwks: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("authclient: fetch jwks: unexpected status %d", res.StatusCode)
+	}
+
+	var set jsonWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("authclient: decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		key, err := parseRSAPublicKey(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("authclient: parse key %s: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func parseRSAPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf("exponent is too large")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
@@ -0,0 +1,131 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Defaults of Argon2idOptions, the second recommended option of RFC 9106
+// with less memory.
+const (
+	DefaultArgon2idMemory      = 64 * 1024
+	DefaultArgon2idIterations  = 3
+	DefaultArgon2idParallelism = 2
+	DefaultArgon2idSaltLength  = 16
+	DefaultArgon2idKeyLength   = 32
+)
+
+type Argon2idOptions struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type Argon2idHasher struct {
+	params Argon2idOptions
+}
+
+// NewArgon2idHasher fills the unset options with their defaults.
+func NewArgon2idHasher(opts Argon2idOptions) *Argon2idHasher {
+	if opts.Memory == 0 {
+		opts.Memory = DefaultArgon2idMemory
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = DefaultArgon2idIterations
+	}
+	if opts.Parallelism == 0 {
+		opts.Parallelism = DefaultArgon2idParallelism
+	}
+	if opts.SaltLength == 0 {
+		opts.SaltLength = DefaultArgon2idSaltLength
+	}
+	if opts.KeyLength == 0 {
+		opts.KeyLength = DefaultArgon2idKeyLength
+	}
+	return &Argon2idHasher{
+		params: opts,
+	}
+}
+
+func (h *Argon2idHasher) Hash(password string) (hash string, err error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return encodeArgon2id(h.params, salt, key), nil
+}
+
+func (h *Argon2idHasher) Verify(hash string, password string) (ok bool, err error) {
+	return Verify(hash, password)
+}
+
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) != h.params.SaltLength ||
+		uint32(len(key)) != h.params.KeyLength
+}
+
+func verifyArgon2id(hash string, password string) (ok bool, err error) {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func encodeArgon2id(params Argon2idOptions, salt []byte, key []byte) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2id(hash string) (params Argon2idOptions, salt []byte, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("password: unsupported argon2id version %q", parts[2])
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("password: invalid argon2id parameters %q", parts[3])
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("password: invalid argon2id salt: %w", err)
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("password: invalid argon2id key")
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
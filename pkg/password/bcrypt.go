@@ -0,0 +1,59 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost is used when BcryptOptions leaves the cost unset.
+const DefaultBcryptCost = 12
+
+type BcryptOptions struct {
+	// Cost is the bcrypt work factor, from bcrypt.MinCost to
+	// bcrypt.MaxCost. Defaults to DefaultBcryptCost.
+	Cost int
+}
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(opts BcryptOptions) *BcryptHasher {
+	if opts.Cost == 0 {
+		opts.Cost = DefaultBcryptCost
+	}
+	return &BcryptHasher{
+		cost: opts.Cost,
+	}
+}
+
+func (h *BcryptHasher) Hash(password string) (hash string, err error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(hashed), err
+}
+
+func (h *BcryptHasher) Verify(hash string, password string) (ok bool, err error) {
+	return Verify(hash, password)
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	if !isBcrypt(hash) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func verifyBcrypt(hash string, password string) (ok bool, err error) {
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,103 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedList tells whether a password appears in a corpus of breached
+// passwords.
+type BreachedList interface {
+	Contains(password string) (breached bool, err error)
+}
+
+// BreachedDir is a local copy of the Pwned Passwords range files, as served
+// by its k-anonymity API and saved by its downloader: one file per first 5
+// characters of the SHA-1 hash, named like 21BD1.txt, listing the other 35
+// characters of the hashes with their count, e.g.
+// "0018A45C4D1DEF81644B54AB7F969B88D65:10". Files are read on demand, so
+// the full corpus does not need to fit in memory.
+type BreachedDir struct {
+	dir string
+}
+
+func NewBreachedDir(dir string) *BreachedDir {
+	return &BreachedDir{
+		dir: dir,
+	}
+}
+
+func (l *BreachedDir) Contains(password string) (breached bool, err error) {
+	hash := sha1Hex(password)
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(l.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count := splitBreachedLine(scanner.Text())
+		// Padding entries have a count of 0.
+		if lineSuffix == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// BreachedSet keeps full SHA-1 hashes in memory, read from lines of 40
+// characters with an optional count, the format of the single file
+// downloads of the same corpus. It suits smaller lists such as the most
+// common passwords.
+type BreachedSet struct {
+	hashes map[string]struct{}
+}
+
+func LoadBreachedSet(r io.Reader) (*BreachedSet, error) {
+	l := &BreachedSet{
+		hashes: map[string]struct{}{},
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, _ := splitBreachedLine(scanner.Text())
+		if hash == "" {
+			continue
+		}
+		if len(hash) != sha1.Size*2 {
+			return nil, errors.New("password: breached list line is not a SHA-1 hash")
+		}
+		l.hashes[hash] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *BreachedSet) Contains(password string) (breached bool, err error) {
+	_, breached = l.hashes[sha1Hex(password)]
+	return breached, nil
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func splitBreachedLine(line string) (hash string, count string) {
+	hash, count, _ = strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash), count
+}
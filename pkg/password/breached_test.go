@@ -0,0 +1,67 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The SHA-1 of "Password1!" is 32CA9FC1A0F5B6330E3F4C8C1BBECDE9BEDB9573,
+// the one of "SawitPro123$" is 4F012EDC61C19351D169525B7445C4E8A930DC6A.
+
+func Test_BreachedDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"32CA9.txt": "0018A45C4D1DEF81644B54AB7F969B88D65:10\r\nFC1A0F5B6330E3F4C8C1BBECDE9BEDB9573:2134\r\n",
+		"4F012.txt": "0018A45C4D1DEF81644B54AB7F969B88D65:10\r\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Error When WriteFile() %s", err.Error())
+		}
+	}
+	padded := strings.ToLower(sha1Hex("Padding1!")[5:]) + ":0\n"
+	if err := os.WriteFile(filepath.Join(dir, sha1Hex("Padding1!")[:5]+".txt"), []byte(padded), 0o600); err != nil {
+		t.Fatalf("Error When WriteFile() %s", err.Error())
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "breached", password: "Password1!", want: true},
+		{name: "same prefix file", password: "SawitPro123$", want: false},
+		{name: "no prefix file", password: "Xk9$mQ2#vLp7", want: false},
+		{name: "padding entry", password: "Padding1!", want: false},
+	}
+	list := NewBreachedDir(dir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := list.Contains(tt.password)
+			if err != nil {
+				t.Errorf("Error When Contains() %s", err.Error())
+			}
+			if got != tt.want {
+				t.Errorf("Result When Contains() %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_LoadBreachedSet(t *testing.T) {
+	list, err := LoadBreachedSet(strings.NewReader("32ca9fc1a0f5b6330e3f4c8c1bbecde9bedb9573:2134\n\n" + sha1Hex("qwerty") + "\n"))
+	if err != nil {
+		t.Fatalf("Error When LoadBreachedSet() %s", err.Error())
+	}
+	for password, want := range map[string]bool{"Password1!": true, "qwerty": true, "SawitPro123$": false} {
+		if got, _ := list.Contains(password); got != want {
+			t.Errorf("Result When Contains(%q) %v, want = %v", password, got, want)
+		}
+	}
+
+	if _, err := LoadBreachedSet(strings.NewReader("FC1A0F5B6330E3F4C8C1BBECDE9BEDB9573:2134\n")); err == nil {
+		t.Errorf("Error When LoadBreachedSet() accepted a range file line")
+	}
+}
@@ -0,0 +1,42 @@
+// Package password hashes passwords into self-describing strings: the
+// algorithm and its parameters are part of the hash, so a stored hash can
+// always be verified and can be told apart from the hashes the current
+// configuration would produce.
+//
+// Two formats are supported, the usual bcrypt format ($2a$12$...) and the
+// PHC string format for argon2id ($argon2id$v=19$m=65536,t=3,p=2$salt$key).
+// Either can be wrapped with the id of the pepper applied to the password,
+// see PepperedHasher.
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnknownFormat = errors.New("password: unknown hash format")
+
+// PasswordHasher hashes new passwords with one configured algorithm and
+// verifies passwords against hashes of any supported format.
+type PasswordHasher interface {
+	Hash(password string) (hash string, err error)
+	Verify(hash string, password string) (ok bool, err error)
+	// NeedsRehash reports whether the hash was made with another algorithm
+	// or other parameters than Hash uses now.
+	NeedsRehash(hash string) bool
+}
+
+// Verify checks the password against a hash of any supported format. A
+// wrong password is not an error.
+func Verify(hash string, password string) (ok bool, err error) {
+	switch {
+	case isBcrypt(hash):
+		return verifyBcrypt(hash, password)
+	case strings.HasPrefix(hash, argon2idPrefix):
+		return verifyArgon2id(hash, password)
+	case strings.HasPrefix(hash, pepperedPrefix):
+		return false, ErrPepperRequired
+	default:
+		return false, ErrUnknownFormat
+	}
+}
@@ -0,0 +1,102 @@
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2id keeps the tests fast, the hashes it makes are not safe.
+var testArgon2id = Argon2idOptions{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func Test_Hash(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		prefix string
+	}{
+		{name: "bcrypt", hasher: NewBcryptHasher(BcryptOptions{Cost: bcrypt.MinCost}), prefix: "$2a$04$"},
+		{name: "argon2id", hasher: NewArgon2idHasher(testArgon2id), prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hasher.Hash("SawitPro123$")
+			if err != nil {
+				t.Fatalf("Error When Hash() %s", err.Error())
+			}
+			if len(hash) <= len(tt.prefix) || hash[:len(tt.prefix)] != tt.prefix {
+				t.Errorf("Result When Hash() %s, prefix = %s", hash, tt.prefix)
+			}
+			if other, _ := tt.hasher.Hash("SawitPro123$"); other == hash {
+				t.Errorf("Result When Hash() returned the same hash twice")
+			}
+
+			if ok, err := tt.hasher.Verify(hash, "SawitPro123$"); !ok || err != nil {
+				t.Errorf("Result When Verify() %v, %v", ok, err)
+			}
+			if ok, err := tt.hasher.Verify(hash, "SawitPro123%"); ok || err != nil {
+				t.Errorf("Result When Verify() accepted a wrong password %v, %v", ok, err)
+			}
+			if tt.hasher.NeedsRehash(hash) {
+				t.Errorf("Result When NeedsRehash() wants to rehash its own hash")
+			}
+		})
+	}
+}
+
+func Test_Verify(t *testing.T) {
+	argon2idHash, _ := NewArgon2idHasher(testArgon2id).Hash("SawitPro123$")
+	tests := []struct {
+		name    string
+		hash    string
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "bcrypt", hash: "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG", wantOK: true},
+		{name: "argon2id", hash: argon2idHash, wantOK: true},
+		{name: "plain text", hash: "SawitPro123$", wantErr: true},
+		{name: "empty", hash: "", wantErr: true},
+		{name: "argon2i", hash: "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5", wantErr: true},
+		{name: "argon2id other version", hash: "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5", wantErr: true},
+		{name: "argon2id missing parameters", hash: "$argon2id$v=19$m=64$c2FsdA$a2V5", wantErr: true},
+		{name: "argon2id invalid salt", hash: "$argon2id$v=19$m=64,t=1,p=1$!!$a2V5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := Verify(tt.hash, "SawitPro123$")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Error When Verify() %v, wantErr = %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("Result When Verify() %v, wantOK = %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_NeedsRehash(t *testing.T) {
+	bcryptHash := "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG"
+	argon2idHash, _ := NewArgon2idHasher(testArgon2id).Hash("SawitPro123$")
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{name: "bcrypt same cost", hasher: NewBcryptHasher(BcryptOptions{Cost: bcrypt.MinCost}), hash: bcryptHash, want: false},
+		{name: "bcrypt higher cost", hasher: NewBcryptHasher(BcryptOptions{}), hash: bcryptHash, want: true},
+		{name: "bcrypt to argon2id", hasher: NewArgon2idHasher(testArgon2id), hash: bcryptHash, want: true},
+		{name: "argon2id to bcrypt", hasher: NewBcryptHasher(BcryptOptions{}), hash: argon2idHash, want: true},
+		{name: "argon2id same parameters", hasher: NewArgon2idHasher(testArgon2id), hash: argon2idHash, want: false},
+		{name: "argon2id more memory", hasher: NewArgon2idHasher(Argon2idOptions{Memory: 128, Iterations: 1, Parallelism: 1}), hash: argon2idHash, want: true},
+		{name: "argon2id longer key", hasher: NewArgon2idHasher(Argon2idOptions{Memory: 64, Iterations: 1, Parallelism: 1, KeyLength: 64}), hash: argon2idHash, want: true},
+		{name: "unknown format", hasher: NewBcryptHasher(BcryptOptions{}), hash: "SawitPro123$", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("Result When NeedsRehash() %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pepperedPrefix starts the hashes made by PepperedHasher. The pepper id
+// follows, then the hash of the inner hasher:
+// $peppered$id=2024-01$$2a$12$...
+const pepperedPrefix = "$peppered$id="
+
+var (
+	ErrUnknownPepper  = errors.New("password: hash uses an unknown pepper")
+	ErrPepperRequired = errors.New("password: hash is peppered but no pepper is configured")
+)
+
+// Pepper is a secret key kept out of the database. ID is stored with every
+// hash so that the pepper can be rotated.
+type Pepper struct {
+	ID  string
+	Key []byte
+}
+
+type PepperedHasherOptions struct {
+	// Hasher hashes the peppered passwords.
+	Hasher PasswordHasher
+	// Current is the pepper new hashes are made with.
+	Current Pepper
+	// Previous peppers are still accepted for existing hashes, which are
+	// moved to Current on the next login.
+	Previous []Pepper
+}
+
+// PepperedHasher applies an HMAC-SHA256 with a pepper to passwords before
+// hashing them, so that a database dump alone is not enough to crack them.
+// Hashes without a pepper are still accepted and need a rehash.
+type PepperedHasher struct {
+	hasher  PasswordHasher
+	current Pepper
+	peppers map[string][]byte
+}
+
+func NewPepperedHasher(opts PepperedHasherOptions) (*PepperedHasher, error) {
+	h := &PepperedHasher{
+		hasher:  opts.Hasher,
+		current: opts.Current,
+		peppers: map[string][]byte{},
+	}
+	for _, pepper := range append([]Pepper{opts.Current}, opts.Previous...) {
+		if pepper.ID == "" || strings.Contains(pepper.ID, "$") || len(pepper.Key) == 0 {
+			return nil, fmt.Errorf("password: invalid pepper %q", pepper.ID)
+		}
+		if _, ok := h.peppers[pepper.ID]; ok {
+			return nil, fmt.Errorf("password: duplicate pepper %q", pepper.ID)
+		}
+		h.peppers[pepper.ID] = pepper.Key
+	}
+	return h, nil
+}
+
+func (h *PepperedHasher) Hash(password string) (hash string, err error) {
+	inner, err := h.hasher.Hash(applyPepper(h.current.Key, password))
+	if err != nil {
+		return "", err
+	}
+	return pepperedPrefix + h.current.ID + "$" + inner, nil
+}
+
+func (h *PepperedHasher) Verify(hash string, password string) (ok bool, err error) {
+	id, inner, peppered := splitPeppered(hash)
+	if !peppered {
+		return Verify(hash, password)
+	}
+
+	key, known := h.peppers[id]
+	if !known {
+		return false, ErrUnknownPepper
+	}
+	return Verify(inner, applyPepper(key, password))
+}
+
+func (h *PepperedHasher) NeedsRehash(hash string) bool {
+	id, inner, peppered := splitPeppered(hash)
+	if !peppered || id != h.current.ID {
+		return true
+	}
+	return h.hasher.NeedsRehash(inner)
+}
+
+// applyPepper returns the base64 encoded HMAC of the password, which stays
+// below the 72 byte input limit of bcrypt.
+func applyPepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func splitPeppered(hash string) (id string, inner string, peppered bool) {
+	if !strings.HasPrefix(hash, pepperedPrefix) {
+		return "", "", false
+	}
+	id, inner, found := strings.Cut(strings.TrimPrefix(hash, pepperedPrefix), "$")
+	if !found {
+		return "", "", true
+	}
+	return id, inner, true
+}
@@ -0,0 +1,93 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_PepperedHasher(t *testing.T) {
+	bcryptHasher := NewBcryptHasher(BcryptOptions{Cost: bcrypt.MinCost})
+	oldPepper := Pepper{ID: "2023", Key: []byte("old-pepper")}
+	newPepper := Pepper{ID: "2024", Key: []byte("new-pepper")}
+
+	old, _ := NewPepperedHasher(PepperedHasherOptions{Hasher: bcryptHasher, Current: oldPepper})
+	rotated, err := NewPepperedHasher(PepperedHasherOptions{Hasher: bcryptHasher, Current: newPepper, Previous: []Pepper{oldPepper}})
+	if err != nil {
+		t.Fatalf("Error When NewPepperedHasher() %s", err.Error())
+	}
+	dropped, _ := NewPepperedHasher(PepperedHasherOptions{Hasher: bcryptHasher, Current: newPepper})
+
+	oldHash, err := old.Hash("SawitPro123$")
+	if err != nil {
+		t.Fatalf("Error When Hash() %s", err.Error())
+	}
+	if !strings.HasPrefix(oldHash, "$peppered$id=2023$$2a$04$") {
+		t.Errorf("Result When Hash() %s", oldHash)
+	}
+	newHash, _ := rotated.Hash("SawitPro123$")
+	plainHash := "$2a$04$1IjAa.80dLp2uNt.ls0pGe7JKv5QpPCo.qYwGPZjYQrK/BFL2ZDwG"
+
+	tests := []struct {
+		name        string
+		hasher      PasswordHasher
+		hash        string
+		password    string
+		wantOK      bool
+		wantErr     error
+		wantRehash  bool
+		checkRehash bool
+	}{
+		{name: "current pepper", hasher: rotated, hash: newHash, password: "SawitPro123$", wantOK: true, checkRehash: true},
+		{name: "wrong password", hasher: rotated, hash: newHash, password: "SawitPro123%"},
+		{name: "previous pepper", hasher: rotated, hash: oldHash, password: "SawitPro123$", wantOK: true, wantRehash: true, checkRehash: true},
+		{name: "dropped pepper", hasher: dropped, hash: oldHash, password: "SawitPro123$", wantErr: ErrUnknownPepper},
+		{name: "without pepper", hasher: rotated, hash: plainHash, password: "SawitPro123$", wantOK: true, wantRehash: true, checkRehash: true},
+		{name: "pepper not configured", hasher: bcryptHasher, hash: newHash, password: "SawitPro123$", wantErr: ErrPepperRequired},
+		{name: "malformed", hasher: rotated, hash: "$peppered$id=2024", password: "SawitPro123$", wantErr: ErrUnknownPepper},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.hasher.Verify(tt.hash, tt.password)
+			if err != tt.wantErr {
+				t.Errorf("Error When Verify() %v, wantErr = %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("Result When Verify() %v, wantOK = %v", ok, tt.wantOK)
+			}
+			if tt.checkRehash && tt.hasher.NeedsRehash(tt.hash) != tt.wantRehash {
+				t.Errorf("Result When NeedsRehash() %v, wantRehash = %v", !tt.wantRehash, tt.wantRehash)
+			}
+		})
+	}
+
+	// The pepper alone must not be enough to verify the password.
+	if ok, _ := bcryptHasher.Verify(strings.TrimPrefix(newHash, "$peppered$id=2024$"), "SawitPro123$"); ok {
+		t.Errorf("Result When Verify() accepted the unpeppered password against a peppered hash")
+	}
+}
+
+func Test_NewPepperedHasher(t *testing.T) {
+	hasher := NewBcryptHasher(BcryptOptions{})
+	tests := []struct {
+		name     string
+		current  Pepper
+		previous []Pepper
+		wantErr  bool
+	}{
+		{name: "passed", current: Pepper{ID: "2024", Key: []byte("key")}, previous: []Pepper{{ID: "2023", Key: []byte("old")}}},
+		{name: "empty id", current: Pepper{Key: []byte("key")}, wantErr: true},
+		{name: "id with separator", current: Pepper{ID: "20$24", Key: []byte("key")}, wantErr: true},
+		{name: "empty key", current: Pepper{ID: "2024"}, wantErr: true},
+		{name: "duplicate id", current: Pepper{ID: "2024", Key: []byte("key")}, previous: []Pepper{{ID: "2024", Key: []byte("old")}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPepperedHasher(PepperedHasherOptions{Hasher: hasher, Current: tt.current, Previous: tt.previous})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Error When NewPepperedHasher() %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
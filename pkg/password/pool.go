@@ -0,0 +1,125 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var ErrPoolSaturated = errors.New("password: hashing pool is saturated")
+
+type PoolOptions struct {
+	// Concurrency is how many hashes are computed at once. Defaults to
+	// runtime.GOMAXPROCS.
+	Concurrency int
+	// QueueDepth is how many more callers may wait for a free slot before
+	// the next one is turned away with ErrPoolSaturated. Defaults to four
+	// times Concurrency.
+	QueueDepth int
+}
+
+// PoolStats is a snapshot of what a Pool did since it was created.
+type PoolStats struct {
+	Concurrency int
+	QueueDepth  int
+	// Running and Queued are the callers computing a hash and waiting for
+	// a slot right now.
+	Running int
+	Queued  int
+	// Completed calls ran, Rejected ones found the queue full and Canceled
+	// ones gave up waiting when their context ended.
+	Completed int64
+	Rejected  int64
+	Canceled  int64
+	// WaitTotal and WaitMax are the time completed calls spent in the
+	// queue.
+	WaitTotal time.Duration
+	WaitMax   time.Duration
+}
+
+// Pool bounds how many password hashes are computed at once, so that a
+// burst of logins cannot take every core away from cheaper requests.
+// Callers beyond the limit queue up to a fixed depth and are turned away
+// after that. The work runs on the calling goroutine; only the wait for a
+// slot can be canceled, a hash that started runs to its end.
+type Pool struct {
+	admitted chan struct{}
+	slots    chan struct{}
+
+	mu    sync.Mutex
+	stats PoolStats
+}
+
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	if opts.QueueDepth <= 0 {
+		opts.QueueDepth = 4 * opts.Concurrency
+	}
+	return &Pool{
+		admitted: make(chan struct{}, opts.Concurrency+opts.QueueDepth),
+		slots:    make(chan struct{}, opts.Concurrency),
+		stats: PoolStats{
+			Concurrency: opts.Concurrency,
+			QueueDepth:  opts.QueueDepth,
+		},
+	}
+}
+
+// Do runs fn once a slot is free. It returns ErrPoolSaturated right away
+// when the queue is full, and the context error when ctx ends first.
+func (p *Pool) Do(ctx context.Context, fn func()) error {
+	select {
+	case p.admitted <- struct{}{}:
+	default:
+		p.mu.Lock()
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return ErrPoolSaturated
+	}
+	defer func() { <-p.admitted }()
+
+	p.mu.Lock()
+	p.stats.Queued++
+	p.mu.Unlock()
+
+	queuedAt := time.Now()
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		p.mu.Lock()
+		p.stats.Queued--
+		p.stats.Canceled++
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	wait := time.Since(queuedAt)
+	p.mu.Lock()
+	p.stats.Queued--
+	p.stats.Running++
+	p.stats.WaitTotal += wait
+	if wait > p.stats.WaitMax {
+		p.stats.WaitMax = wait
+	}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.stats.Running--
+		p.stats.Completed++
+		p.mu.Unlock()
+	}()
+	fn()
+	return nil
+}
+
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
@@ -0,0 +1,76 @@
+package password
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fillPool occupies every slot and queue place of the pool until release
+// is closed.
+func fillPool(p *Pool, release chan struct{}) {
+	stats := p.Stats()
+	for i := 0; i < stats.Concurrency+stats.QueueDepth; i++ {
+		go p.Do(context.Background(), func() { <-release })
+	}
+	for {
+		stats = p.Stats()
+		if stats.Running == stats.Concurrency && stats.Queued == stats.QueueDepth {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func Test_NewPool(t *testing.T) {
+	stats := NewPool(PoolOptions{Concurrency: 2}).Stats()
+	if stats.Concurrency != 2 || stats.QueueDepth != 8 {
+		t.Errorf("Result When NewPool() %+v", stats)
+	}
+}
+
+func Test_Pool_Do(t *testing.T) {
+	p := NewPool(PoolOptions{Concurrency: 1, QueueDepth: 1})
+
+	ran := false
+	if err := p.Do(context.Background(), func() { ran = true }); err != nil || !ran {
+		t.Fatalf("Error When Do() %v, ran = %v", err, ran)
+	}
+
+	release := make(chan struct{})
+	fillPool(p, release)
+
+	if err := p.Do(context.Background(), func() {}); err != ErrPoolSaturated {
+		t.Errorf("Error When Do() %v on a full queue", err)
+	}
+
+	close(release)
+	for p.Stats().Completed != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	stats := p.Stats()
+	if stats.Rejected != 1 || stats.Running != 0 || stats.Queued != 0 || stats.WaitMax <= 0 || stats.WaitTotal < stats.WaitMax {
+		t.Errorf("Result When Stats() %+v", stats)
+	}
+}
+
+func Test_Pool_Do_canceled(t *testing.T) {
+	p := NewPool(PoolOptions{Concurrency: 1, QueueDepth: 1})
+
+	release := make(chan struct{})
+	defer close(release)
+	go p.Do(context.Background(), func() { <-release })
+	for p.Stats().Running != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := false
+	if err := p.Do(ctx, func() { ran = true }); err != context.DeadlineExceeded || ran {
+		t.Errorf("Error When Do() %v, ran = %v", err, ran)
+	}
+	if stats := p.Stats(); stats.Canceled != 1 || stats.Queued != 0 {
+		t.Errorf("Result When Stats() %+v", stats)
+	}
+}
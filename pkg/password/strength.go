@@ -0,0 +1,305 @@
+package password
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Strength estimates how hard a password is to guess, in the manner of
+// zxcvbn: the password is split into the patterns attackers try first
+// (common words, repeats, sequences, rows of keys and years) and the number
+// of guesses for each is multiplied. Characters outside any pattern count
+// as brute force.
+type Strength struct {
+	// Score is 0 (too guessable) to 4 (very unguessable).
+	Score int
+	// Guesses is the estimated number of guesses needed.
+	Guesses float64
+	// Warning explains the weakest pattern found, if any.
+	Warning string
+}
+
+const (
+	bruteforceCardinality = 10
+	minSubmatchGuesses    = 50
+	minYearSpace          = 20
+	minDictionaryLength   = 3
+)
+
+const (
+	warningTop10       = "This is a top-10 common password"
+	warningTop100      = "This is a top-100 common password"
+	warningCommon      = "This is similar to a commonly used password"
+	warningUserInput   = "Passwords containing your name or phone number are easy to guess"
+	warningRepeat      = `Repeats like "aaa" are easy to guess`
+	warningSequence    = "Sequences like abc or 6543 are easy to guess"
+	warningKeyboardRow = "Straight rows of keys are easy to guess"
+	warningRecentYear  = "Recent years are easy to guess"
+)
+
+// commonPasswords are ranked by how often they are used. Digit only
+// passwords are left out, the sequence and repeat patterns cover them.
+var commonPasswords = []string{
+	"password", "qwerty", "iloveyou", "admin", "welcome", "monkey", "dragon", "letmein", "abc", "football",
+	"sunshine", "princess", "master", "shadow", "baseball", "superman", "trustno", "hello", "freedom", "whatever",
+	"login", "starwars", "passw", "secret", "charlie", "michael", "jessica", "pass", "love", "flower",
+	"hottie", "loveme", "lovely", "jesus", "ninja", "mustang", "access", "batman", "computer", "cheese",
+	"summer", "winter", "spring", "autumn", "soccer", "hunter", "killer", "pepper", "ginger", "cookie",
+	"test", "user", "guest", "root", "default", "changeme", "sayang", "rahasia", "bismillah", "indonesia",
+	"jakarta", "cinta", "kucing", "ganteng", "cantik", "merdeka", "garuda", "bandung", "surabaya", "bali",
+}
+
+var dictionary = rankWords(commonPasswords)
+
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "!@#$%^&*()"}
+
+var leetSubstitutions = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i',
+	'0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+type match struct {
+	length  int
+	guesses float64
+	warning string
+}
+
+// Score rates the password. userInputs, such as the name and phone number
+// of the user, are treated as the most common words.
+func Score(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	inputs := rankWords(userInputs)
+
+	var (
+		guesses    = 1.0
+		tokens     = 0
+		bruteforce = 0
+		weakest    match
+	)
+	flushBruteforce := func() {
+		if bruteforce > 0 {
+			guesses *= math.Pow(bruteforceCardinality, float64(bruteforce))
+			tokens++
+			bruteforce = 0
+		}
+	}
+
+	for i := 0; i < len(runes); {
+		m, ok := longestMatch(runes[i:], inputs)
+		if !ok {
+			bruteforce++
+			i++
+			continue
+		}
+
+		flushBruteforce()
+		guesses *= m.guesses
+		tokens++
+		if weakest.warning == "" || m.guesses < weakest.guesses {
+			weakest = m
+		}
+		i += m.length
+	}
+	flushBruteforce()
+
+	// The attacker also has to guess how the patterns are put together.
+	for k := 2; k <= tokens; k++ {
+		guesses *= float64(k)
+	}
+
+	return Strength{
+		Score:   scoreOf(guesses),
+		Guesses: guesses,
+		Warning: weakest.warning,
+	}
+}
+
+func scoreOf(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// longestMatch returns the pattern covering the most characters from the
+// start of runes, the one with fewer guesses on a tie.
+func longestMatch(runes []rune, inputs map[string]int) (best match, ok bool) {
+	candidates := []func([]rune, map[string]int) (match, bool){
+		dictionaryMatch, repeatMatch, sequenceMatch, keyboardMatch, yearMatch,
+	}
+	for _, candidate := range candidates {
+		m, found := candidate(runes, inputs)
+		if !found {
+			continue
+		}
+		if m.length > 1 && m.guesses < minSubmatchGuesses {
+			m.guesses = minSubmatchGuesses
+		}
+		if !ok || m.length > best.length || (m.length == best.length && m.guesses < best.guesses) {
+			best, ok = m, true
+		}
+	}
+	return best, ok
+}
+
+func dictionaryMatch(runes []rune, inputs map[string]int) (match, bool) {
+	for length := len(runes); length >= minDictionaryLength; length-- {
+		word := runes[:length]
+		lower := strings.ToLower(string(word))
+		unleet := unleetWord(lower)
+
+		variations := 1.0
+		if lower != string(word) {
+			variations *= 2
+		}
+		if unleet != lower {
+			variations *= 2
+		}
+
+		for _, candidate := range []string{lower, unleet} {
+			if _, ok := inputs[candidate]; ok {
+				return match{length: length, guesses: variations, warning: warningUserInput}, true
+			}
+			if rank, ok := dictionary[candidate]; ok {
+				warning := warningCommon
+				if rank <= 10 {
+					warning = warningTop10
+				} else if rank <= 100 {
+					warning = warningTop100
+				}
+				return match{length: length, guesses: float64(rank) * variations, warning: warning}, true
+			}
+		}
+	}
+	return match{}, false
+}
+
+func repeatMatch(runes []rune, _ map[string]int) (match, bool) {
+	length := 1
+	for length < len(runes) && runes[length] == runes[0] {
+		length++
+	}
+	if length < 3 {
+		return match{}, false
+	}
+	return match{length: length, guesses: bruteforceCardinality * float64(length), warning: warningRepeat}, true
+}
+
+func sequenceMatch(runes []rune, _ map[string]int) (match, bool) {
+	if len(runes) < 3 {
+		return match{}, false
+	}
+	runes = []rune(strings.ToLower(string(runes)))
+	step := runes[1] - runes[0]
+	if step != 1 && step != -1 {
+		return match{}, false
+	}
+
+	length := 2
+	for length < len(runes) && runes[length]-runes[length-1] == step && sameClass(runes[length], runes[0]) {
+		length++
+	}
+	if length < 3 || !sameClass(runes[1], runes[0]) {
+		return match{}, false
+	}
+
+	base := 26.0
+	switch {
+	case strings.ContainsRune("aAzZ019", runes[0]):
+		base = 4
+	case unicode.IsDigit(runes[0]):
+		base = 10
+	}
+	if step < 0 {
+		base *= 2
+	}
+	return match{length: length, guesses: base * float64(length), warning: warningSequence}, true
+}
+
+func keyboardMatch(runes []rune, _ map[string]int) (match, bool) {
+	best := 0
+	for _, row := range keyboardRows {
+		for _, direction := range []string{row, reverse(row)} {
+			start := strings.IndexRune(direction, unicode.ToLower(runes[0]))
+			if start < 0 {
+				continue
+			}
+			length := 1
+			for length < len(runes) && start+length < len(direction) && unicode.ToLower(runes[length]) == rune(direction[start+length]) {
+				length++
+			}
+			if length > best {
+				best = length
+			}
+		}
+	}
+	if best < 3 {
+		return match{}, false
+	}
+	return match{length: best, guesses: 40 * float64(best), warning: warningKeyboardRow}, true
+}
+
+func yearMatch(runes []rune, _ map[string]int) (match, bool) {
+	if len(runes) < 4 {
+		return match{}, false
+	}
+	year, err := strconv.Atoi(string(runes[:4]))
+	if err != nil || year < 1900 || year > 2099 {
+		return match{}, false
+	}
+	space := math.Abs(float64(year - time.Now().Year()))
+	if space < minYearSpace {
+		space = minYearSpace
+	}
+	return match{length: 4, guesses: space, warning: warningRecentYear}, true
+}
+
+func rankWords(words []string) map[string]int {
+	ranks := map[string]int{}
+	for _, input := range words {
+		for _, word := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return unicode.IsSpace(r) || r == '+' || r == '-'
+		}) {
+			if len([]rune(word)) < minDictionaryLength {
+				continue
+			}
+			if _, ok := ranks[word]; !ok {
+				ranks[word] = len(ranks) + 1
+			}
+		}
+	}
+	return ranks
+}
+
+func unleetWord(word string) string {
+	return strings.Map(func(r rune) rune {
+		if plain, ok := leetSubstitutions[r]; ok {
+			return plain
+		}
+		return r
+	}, word)
+}
+
+func sameClass(a rune, b rune) bool {
+	return unicode.IsDigit(a) == unicode.IsDigit(b) && unicode.IsLetter(a) == unicode.IsLetter(b)
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
@@ -0,0 +1,33 @@
+package password
+
+import "testing"
+
+func Test_Score(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		userInputs  []string
+		wantScore   int
+		wantWarning string
+	}{
+		{name: "common password with suffix", password: "Password1!", wantScore: 1, wantWarning: warningTop10},
+		{name: "leet common password", password: "P@ssw0rd!", wantScore: 0, wantWarning: warningTop10},
+		{name: "keyboard row", password: "Qwertyuiop1!", wantScore: 1, wantWarning: warningKeyboardRow},
+		{name: "repeat", password: "aaaaaaA1!", wantScore: 1, wantWarning: warningRepeat},
+		{name: "sequence", password: "Abcdefgh1!", wantScore: 1, wantWarning: warningSequence},
+		{name: "recent year", password: "Jakarta2024!", wantScore: 1, wantWarning: warningRecentYear},
+		{name: "user input", password: "Santoso1990!", userInputs: []string{"Budi Santoso", "+62821232342"}, wantScore: 1, wantWarning: warningUserInput},
+		{name: "phone number", password: "A!62821232342", userInputs: []string{"Budi Santoso", "+62821232342"}, wantScore: 1, wantWarning: warningUserInput},
+		{name: "random", password: "Xk9$mQ2#vLp7", wantScore: 4, wantWarning: ""},
+		{name: "passphrase", password: "correct horse battery staple", wantScore: 4, wantWarning: ""},
+		{name: "empty", password: "", wantScore: 0, wantWarning: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.password, tt.userInputs...)
+			if got.Score != tt.wantScore || got.Warning != tt.wantWarning {
+				t.Errorf("Result When Score() %d %q (%.3g guesses), want = %d %q", got.Score, got.Warning, got.Guesses, tt.wantScore, tt.wantWarning)
+			}
+		})
+	}
+}
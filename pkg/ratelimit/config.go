@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRules parses a comma separated list of route rules of the form
+//
+//	<route>=<requests>/<period>[:<key>]
+//
+// for example "POST /login=5/1m:phone,*=100/1m". The route is "METHOD /path"
+// as registered with echo or DefaultRoute, period is a time.ParseDuration
+// string and key names one of keys. Rules without a key use "ip".
+func ParseRules(spec string, keys map[string]KeyFunc) (map[string]Rule, error) {
+	rules := make(map[string]Rule)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, limitSpec, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid rule %q: missing =", entry)
+		}
+		route = strings.TrimSpace(route)
+
+		limitSpec, keyName, found := strings.Cut(limitSpec, ":")
+		if !found {
+			keyName = "ip"
+		}
+		key, found := keys[strings.TrimSpace(keyName)]
+		if !found {
+			return nil, fmt.Errorf("invalid rule %q: unknown key %q", entry, keyName)
+		}
+
+		requestsSpec, periodSpec, found := strings.Cut(limitSpec, "/")
+		if !found {
+			return nil, fmt.Errorf("invalid rule %q: missing period", entry)
+		}
+		requests, err := strconv.Atoi(strings.TrimSpace(requestsSpec))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", entry, err)
+		}
+		period, err := time.ParseDuration(strings.TrimSpace(periodSpec))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", entry, err)
+		}
+
+		limit := Limit{Requests: requests, Period: period}
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", entry, err)
+		}
+		if _, found := rules[route]; found {
+			return nil, fmt.Errorf("duplicate rule for %s", route)
+		}
+		rules[route] = Rule{Limit: limit, Key: key}
+	}
+	return rules, nil
+}
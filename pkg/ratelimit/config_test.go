@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+)
+
+func Test_ParseRules(t *testing.T) {
+	keys := map[string]KeyFunc{
+		"ip":    KeyByIP,
+		"phone": KeyByPhoneNumber,
+	}
+	tests := []struct {
+		name      string
+		spec      string
+		detailRes map[string]Limit
+		detailErr string
+	}{
+		{
+			name:      "empty",
+			spec:      "",
+			detailRes: map[string]Limit{},
+		},
+		{
+			name: "passed",
+			spec: "POST /login=5/1m:phone, *=100/1h",
+			detailRes: map[string]Limit{
+				"POST /login": {Requests: 5, Period: time.Minute},
+				"*":           {Requests: 100, Period: time.Hour},
+			},
+		},
+		{
+			name:      "missing limit",
+			spec:      "POST /login",
+			detailErr: `invalid rule "POST /login": missing =`,
+		},
+		{
+			name:      "unknown key",
+			spec:      "POST /login=5/1m:user",
+			detailErr: `invalid rule "POST /login=5/1m:user": unknown key "user"`,
+		},
+		{
+			name:      "missing period",
+			spec:      "POST /login=5",
+			detailErr: `invalid rule "POST /login=5": missing period`,
+		},
+		{
+			name:      "zero requests",
+			spec:      "POST /login=0/1m",
+			detailErr: `invalid rule "POST /login=0/1m": limit requests and period must be positive`,
+		},
+		{
+			name:      "duplicate",
+			spec:      "*=1/1m,*=2/1m",
+			detailErr: "duplicate rule for *",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(tt.spec, keys)
+			if utilsHelper.ErrorMessage(err) != tt.detailErr {
+				t.Fatalf("Error When ParseRules() %s, detailErr = %s", utilsHelper.ErrorMessage(err), tt.detailErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(rules) != len(tt.detailRes) {
+				t.Fatalf("Result When ParseRules() %+v, detailRes = %+v", rules, tt.detailRes)
+			}
+			for route, limit := range tt.detailRes {
+				if rules[route].Limit != limit || rules[route].Key == nil {
+					t.Errorf("Result When ParseRules() %s %+v, detailRes = %+v", route, rules[route], limit)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxKeyBodySize bounds how much of the request body KeyByPhoneNumber looks
+// at. The body is handed to the handler unchanged either way.
+const maxKeyBodySize = 64 << 10
+
+// KeyFunc returns the key that identifies the client of a request.
+type KeyFunc func(c echo.Context) string
+
+// KeyByIP identifies clients by their IP address as reported by
+// echo.Context.RealIP, so the echo IPExtractor decides which headers are
+// trusted.
+func KeyByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// KeyByUserID identifies authenticated clients by their user id and falls
+// back to the IP address for anonymous ones. userID returns false when the
+// request carries no valid token.
+func KeyByUserID(userID func(c echo.Context) (int64, bool)) KeyFunc {
+	return func(c echo.Context) string {
+		id, ok := userID(c)
+		if !ok {
+			return KeyByIP(c)
+		}
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+}
+
+// KeyByPhoneNumber identifies clients by the phone_number field of a JSON
+// request body, so that attempts against one phone number are limited no
+// matter where they come from. Requests without one fall back to the IP
+// address.
+func KeyByPhoneNumber(c echo.Context) string {
+	req := c.Request()
+	if req.Body == nil {
+		return KeyByIP(c)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxKeyBodySize))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	if err != nil {
+		return KeyByIP(c)
+	}
+
+	var payload struct {
+		PhoneNumber string `json:"phone_number"`
+	}
+	if json.Unmarshal(body, &payload) != nil || payload.PhoneNumber == "" {
+		return KeyByIP(c)
+	}
+	return "phone:" + payload.PhoneNumber
+}
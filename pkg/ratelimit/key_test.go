@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newKeyContext(body string, authorization string) echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.RemoteAddr = "10.0.0.1:1234"
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	return e.NewContext(req, httptest.NewRecorder())
+}
+
+func Test_KeyByPhoneNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "phone number",
+			body: `{"phone_number": "+62821232342", "password": "SawitPro123$"}`,
+			want: "phone:+62821232342",
+		},
+		{
+			name: "no phone number",
+			body: `{"password": "SawitPro123$"}`,
+			want: "ip:10.0.0.1",
+		},
+		{
+			name: "not json",
+			body: `phone_number=+62821232342`,
+			want: "ip:10.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newKeyContext(tt.body, "")
+			got := KeyByPhoneNumber(c)
+			if got != tt.want {
+				t.Errorf("Result When KeyByPhoneNumber() %s, detailRes = %s", got, tt.want)
+			}
+
+			body, _ := io.ReadAll(c.Request().Body)
+			if string(body) != tt.body {
+				t.Errorf("Result When KeyByPhoneNumber() left body %q, detailRes = %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func Test_KeyByUserID(t *testing.T) {
+	key := KeyByUserID(func(c echo.Context) (int64, bool) {
+		if c.Request().Header.Get("Authorization") == "" {
+			return 0, false
+		}
+		return 1, true
+	})
+
+	if got := key(newKeyContext("", "Bearer token")); got != "user:1" {
+		t.Errorf("Result When KeyByUserID() %s, detailRes = user:1", got)
+	}
+	if got := key(newKeyContext("", "")); got != "ip:10.0.0.1" {
+		t.Errorf("Result When KeyByUserID() %s, detailRes = ip:10.0.0.1", got)
+	}
+}
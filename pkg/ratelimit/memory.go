@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryStoreMaxBuckets caps the number of buckets of a MemoryStore. Keys
+// such as phone numbers are chosen by clients, so without a cap a flood of
+// new keys would grow the store without end.
+const memoryStoreMaxBuckets = 100000
+
+// MemoryStore keeps buckets in process memory. It is meant for a single
+// instance; with several instances every one of them enforces the limits on
+// its own. Once it holds maxBuckets buckets, the least recently used one is
+// dropped for every new key, which forgets the bucket that is most likely to
+// have refilled already.
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*list.Element
+	recent     *list.List
+	maxBuckets int
+	now        func() time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	key string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:    make(map[string]*list.Element),
+		recent:     list.New(),
+		maxBuckets: memoryStoreMaxBuckets,
+		now:        time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if err := limit.validate(); err != nil {
+		return Result{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, found := s.buckets[key]
+	if !found {
+		if s.recent.Len() >= s.maxBuckets {
+			s.evictOldest()
+		}
+		element = s.recent.PushFront(&memoryBucket{key: key})
+		s.buckets[key] = element
+	} else {
+		s.recent.MoveToFront(element)
+	}
+
+	b := element.Value.(*memoryBucket)
+	next, result := limit.take(b.bucket, found, s.now())
+	b.bucket = next
+	return result, nil
+}
+
+// evictOldest drops the least recently used bucket.
+func (s *MemoryStore) evictOldest() {
+	element := s.recent.Back()
+	if element == nil {
+		return
+	}
+	s.recent.Remove(element)
+	delete(s.buckets, element.Value.(*memoryBucket).key)
+}
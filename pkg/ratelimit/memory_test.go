@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_MemoryStore_Take(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Requests: 1, Period: time.Minute, Burst: 2}
+
+	for i, want := range []bool{true, true, false} {
+		result, err := s.Take(context.Background(), "key", limit)
+		if err != nil {
+			t.Fatalf("Error When Take() %s", err.Error())
+		}
+		if result.Allowed != want {
+			t.Fatalf("Result When Take() request %d %t, detailRes = %t", i, result.Allowed, want)
+		}
+	}
+
+	result, _ := s.Take(context.Background(), "other-key", limit)
+	if !result.Allowed {
+		t.Errorf("Result When Take() other key was limited")
+	}
+
+	now = now.Add(time.Minute)
+	result, _ = s.Take(context.Background(), "key", limit)
+	if !result.Allowed {
+		t.Errorf("Result When Take() bucket did not refill")
+	}
+
+	_, err := s.Take(context.Background(), "key", Limit{})
+	if err != errInvalidLimit {
+		t.Errorf("Error When Take() %v, detailErr = %v", err, errInvalidLimit)
+	}
+}
+
+func Test_MemoryStore_evictOldest(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.maxBuckets = 3
+	limit := Limit{Requests: 1, Period: time.Hour}
+
+	for _, key := range []string{"oldest", "busy", "other"} {
+		_, _ = s.Take(context.Background(), key, limit)
+	}
+	_, _ = s.Take(context.Background(), "busy", limit)
+	for i := 0; i < 100; i++ {
+		_, _ = s.Take(context.Background(), fmt.Sprintf("flood-%d", i), limit)
+	}
+
+	if len(s.buckets) != s.maxBuckets || s.recent.Len() != s.maxBuckets {
+		t.Errorf("Result When Take() %d buckets, %d in use order, maxBuckets = %d", len(s.buckets), s.recent.Len(), s.maxBuckets)
+	}
+	if _, found := s.buckets["oldest"]; found {
+		t.Errorf("Result When Take() kept the least recently used bucket")
+	}
+	for _, key := range []string{"flood-98", "flood-99"} {
+		if _, found := s.buckets[key]; !found {
+			t.Errorf("Result When Take() dropped the recent bucket %s", key)
+		}
+	}
+}
+
+func Test_MemoryStore_evictOldest_recentlyUsed(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.maxBuckets = 2
+	limit := Limit{Requests: 1, Period: time.Hour}
+
+	_, _ = s.Take(context.Background(), "busy", limit)
+	_, _ = s.Take(context.Background(), "idle", limit)
+	_, _ = s.Take(context.Background(), "busy", limit)
+	_, _ = s.Take(context.Background(), "new", limit)
+
+	if _, found := s.buckets["idle"]; found {
+		t.Errorf("Result When Take() kept the idle bucket")
+	}
+	result, _ := s.Take(context.Background(), "busy", limit)
+	if result.Allowed {
+		t.Errorf("Result When Take() the busy bucket was dropped and refilled")
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	queryInsertBucket = `
+		INSERT INTO rate_limit_bucket ("key", tokens, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT ("key") DO NOTHING;
+	`
+
+	queryGetBucketForUpdate = `
+		SELECT
+			tokens,
+			updated_at
+		FROM rate_limit_bucket
+		WHERE "key" = $1
+		FOR UPDATE;
+	`
+
+	queryUpdateBucket = `
+		UPDATE rate_limit_bucket
+		SET tokens = $2,
+			updated_at = $3
+		WHERE "key" = $1;
+	`
+
+	queryDeleteBucketsBefore = `
+		DELETE FROM rate_limit_bucket
+		WHERE updated_at < $1;
+	`
+)
+
+// PostgresStore keeps buckets in the rate_limit_bucket table so that every
+// instance of the service shares them. Each Take locks the row of its bucket
+// for the duration of a short transaction.
+type PostgresStore struct {
+	Db  *sql.DB
+	now func() time.Time
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		Db:  db,
+		now: time.Now,
+	}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (result Result, err error) {
+	if err = limit.validate(); err != nil {
+		return result, err
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	now := s.now()
+	// Make sure the row exists so that concurrent first requests for a key
+	// serialize on its lock as well.
+	_, err = tx.ExecContext(ctx, queryInsertBucket, key, limit.capacity(), now)
+	if err != nil {
+		return result, err
+	}
+
+	var b bucket
+	err = tx.QueryRowContext(ctx, queryGetBucketForUpdate, key).Scan(&b.Tokens, &b.UpdatedAt)
+	if err != nil {
+		return result, err
+	}
+
+	next, result := limit.take(b, true, now)
+	_, err = tx.ExecContext(ctx, queryUpdateBucket, key, next.Tokens, next.UpdatedAt)
+	if err != nil {
+		return result, err
+	}
+
+	return result, tx.Commit()
+}
+
+// DeleteBefore removes the buckets that were last used before t. Buckets that
+// had time to refill completely carry no state, so this can be run
+// periodically with t set to the longest configured period ago.
+func (s *PostgresStore) DeleteBefore(ctx context.Context, t time.Time) (err error) {
+	_, err = s.Db.ExecContext(ctx, queryDeleteBucketsBefore, t)
+	if err != nil {
+		return err
+	}
+	return nil
+}
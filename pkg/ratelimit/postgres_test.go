@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+)
+
+func Test_PostgresStore_Take(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_PostgresStore_Take] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+	tests := []struct {
+		name      string
+		mock      func()
+		detailRes Result
+		detailErr error
+	}{
+		{
+			name: "error insert",
+			mock: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertBucket)).
+					WithArgs("key", float64(10), now).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			detailRes: Result{},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "error select",
+			mock: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertBucket)).
+					WithArgs("key", float64(10), now).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetBucketForUpdate)).
+					WithArgs("key").
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			detailRes: Result{},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "limited",
+			mock: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertBucket)).
+					WithArgs("key", float64(10), now).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetBucketForUpdate)).
+					WithArgs("key").
+					WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at"}).AddRow(0.5, now))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdateBucket)).
+					WithArgs("key", 0.5, now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			detailRes: Result{Allowed: false, Limit: 10, Remaining: 0, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			mock: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertBucket)).
+					WithArgs("key", float64(10), now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetBucketForUpdate)).
+					WithArgs("key").
+					WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at"}).AddRow(float64(10), now))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUpdateBucket)).
+					WithArgs("key", float64(9), now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			detailRes: Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPostgresStore(dbMock)
+			s.now = func() time.Time { return now }
+			tt.mock()
+			res, err := s.Take(context.Background(), "key", limit)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When Take() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When Take() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+			if err := sqlMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Error When Take() %s", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package ratelimit provides a token bucket rate limiting middleware for echo.
+//
+// Every route gets its own Rule: how many requests are allowed per period and
+// which client key the bucket is tracked under (client IP, authenticated user
+// or the phone number in the request body). The bucket state lives in a Store
+// so that several instances of the service can share it.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// DefaultRoute is the Rules key used for routes without a rule of their own.
+const DefaultRoute = "*"
+
+// Limit allows Requests per Period, refilled continuously, with bursts of up
+// to Burst requests. A zero Burst means Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity.
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// Reset is how long it takes until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long to wait for the next token when not allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the bucket state. Take refills the bucket for the given key,
+// takes one token if there is one and reports the result. Implementations
+// must be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rule is the limit of a route and how its clients are told apart.
+type Rule struct {
+	Limit Limit
+	Key   KeyFunc
+}
+
+type Config struct {
+	Store Store
+	// Rules maps "METHOD /path" as registered with echo, e.g. "POST /login"
+	// or "DELETE /sessions/:id", to its rule. DefaultRoute applies to every
+	// other route; without it other routes are not limited.
+	Rules map[string]Rule
+	// DenyHandler writes the response of a limited request. The RateLimit-*
+	// and Retry-After headers are already set when it is called. Defaults to
+	// a plain 429 error.
+	DenyHandler echo.HandlerFunc
+}
+
+var errInvalidLimit = errors.New("limit requests and period must be positive")
+
+func (l Limit) validate() error {
+	if l.Requests <= 0 || l.Period <= 0 {
+		return errInvalidLimit
+	}
+	return nil
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// bucket is the persisted state of a token bucket.
+type bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// take refills b up to now and takes a token from it if possible. A bucket
+// that was never used starts full.
+func (l Limit) take(b bucket, found bool, now time.Time) (bucket, Result) {
+	capacity := l.capacity()
+	rate := float64(l.Requests) / l.Period.Seconds()
+
+	tokens := capacity
+	if found {
+		elapsed := now.Sub(b.UpdatedAt).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(capacity, b.Tokens+elapsed*rate)
+	}
+
+	result := Result{
+		Allowed: tokens >= 1,
+		Limit:   int(capacity),
+	}
+	if result.Allowed {
+		tokens--
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = secondsToDuration((capacity - tokens) / rate)
+
+	return bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Middleware limits the requests of every route with a rule. When the store
+// fails the request is let through, so an outage of the store does not take
+// the service down with it.
+func Middleware(cfg Config) echo.MiddlewareFunc {
+	deny := cfg.DenyHandler
+	if deny == nil {
+		deny = func(c echo.Context) error {
+			return echo.NewHTTPError(429)
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Request().Method + " " + c.Path()
+			rule, found := cfg.Rules[route]
+			if !found {
+				route = DefaultRoute
+				rule, found = cfg.Rules[route]
+			}
+			if !found {
+				return next(c)
+			}
+
+			key := route + "|" + rule.Key(c)
+			result, err := cfg.Store.Take(c.Request().Context(), key, rule.Limit)
+			if err != nil {
+				log.Errorf("Error When Take: %s with key: %s", err.Error(), key)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+				return deny(c)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Test_Limit_take(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+	tests := []struct {
+		name       string
+		bucket     bucket
+		found      bool
+		wantTokens float64
+		wantResult Result
+	}{
+		{
+			name:       "new bucket starts full",
+			found:      false,
+			wantTokens: 9,
+			wantResult: Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "refills with elapsed time",
+			bucket:     bucket{Tokens: 0, UpdatedAt: now.Add(-3 * time.Second)},
+			found:      true,
+			wantTokens: 2,
+			wantResult: Result{Allowed: true, Limit: 10, Remaining: 2, Reset: 8 * time.Second},
+		},
+		{
+			name:       "refill is capped at capacity",
+			bucket:     bucket{Tokens: 5, UpdatedAt: now.Add(-time.Hour)},
+			found:      true,
+			wantTokens: 9,
+			wantResult: Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "empty bucket",
+			bucket:     bucket{Tokens: 0.5, UpdatedAt: now},
+			found:      true,
+			wantTokens: 0.5,
+			wantResult: Result{Allowed: false, Limit: 10, Remaining: 0, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, result := limit.take(tt.bucket, tt.found, now)
+			if next.Tokens != tt.wantTokens || !next.UpdatedAt.Equal(now) {
+				t.Errorf("Result When take() %+v, detailRes = %v", next, tt.wantTokens)
+			}
+			if result != tt.wantResult {
+				t.Errorf("Result When take() %+v, detailRes = %+v", result, tt.wantResult)
+			}
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, errors.New("expected Take error")
+}
+
+func Test_Middleware(t *testing.T) {
+	newEcho := func(store Store) *echo.Echo {
+		e := echo.New()
+		e.Use(Middleware(Config{
+			Store: store,
+			Rules: map[string]Rule{
+				"POST /login": {Limit: Limit{Requests: 2, Period: time.Minute}, Key: KeyByIP},
+			},
+			DenyHandler: func(c echo.Context) error {
+				return c.String(http.StatusTooManyRequests, "denied")
+			},
+		}))
+		ok := func(c echo.Context) error {
+			return c.NoContent(http.StatusNoContent)
+		}
+		e.POST("/login", ok)
+		e.GET("/profile", ok)
+		return e
+	}
+	do := func(e *echo.Echo, method string, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		res := httptest.NewRecorder()
+		e.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("limits route", func(t *testing.T) {
+		e := newEcho(NewMemoryStore())
+		for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+			res := do(e, http.MethodPost, "/login")
+			if res.Code != want {
+				t.Fatalf("Result When Middleware() request %d %d, statusCode = %d", i, res.Code, want)
+			}
+		}
+
+		res := do(e, http.MethodPost, "/login")
+		if res.Header().Get("RateLimit-Limit") != "2" || res.Header().Get("RateLimit-Remaining") != "0" || res.Header().Get("Retry-After") != "30" {
+			t.Errorf("Result When Middleware() headers %+v", res.Header())
+		}
+	})
+
+	t.Run("route without rule", func(t *testing.T) {
+		e := newEcho(NewMemoryStore())
+		for i := 0; i < 5; i++ {
+			res := do(e, http.MethodGet, "/profile")
+			if res.Code != http.StatusNoContent || res.Header().Get("RateLimit-Limit") != "" {
+				t.Fatalf("Result When Middleware() %d %+v", res.Code, res.Header())
+			}
+		}
+	})
+
+	t.Run("store error lets requests through", func(t *testing.T) {
+		e := newEcho(failingStore{})
+		for i := 0; i < 5; i++ {
+			res := do(e, http.MethodPost, "/login")
+			if res.Code != http.StatusNoContent {
+				t.Fatalf("Result When Middleware() %d, statusCode = %d", res.Code, http.StatusNoContent)
+			}
+		}
+	})
+}
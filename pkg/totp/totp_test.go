@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func Test_Code(t *testing.T) {
+	// The RFC lists 8 digit codes, these are their last 6 digits.
+	tests := []struct {
+		name string
+		time int64
+		want string
+	}{
+		{name: "59", time: 59, want: "287082"},
+		{name: "1111111109", time: 1111111109, want: "081804"},
+		{name: "1111111111", time: 1111111111, want: "050471"},
+		{name: "1234567890", time: 1234567890, want: "005924"},
+		{name: "2000000000", time: 2000000000, want: "279037"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.time, 0)))
+			if err != nil {
+				t.Errorf("Error When Code() %s", err.Error())
+			}
+			if got != tt.want {
+				t.Errorf("Result When Code() %s, want = %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name     string
+		secret   string
+		input    string
+		skew     int
+		wantStep int64
+		wantOK   bool
+		wantErr  bool
+	}{
+		{name: "current step", secret: rfcSecret, input: "050471", skew: 1, wantStep: Step(now), wantOK: true},
+		{name: "previous step within skew", secret: rfcSecret, input: "081804", skew: 1, wantStep: Step(now) - 1, wantOK: true},
+		{name: "previous step without skew", secret: rfcSecret, input: "081804", skew: 0},
+		{name: "surrounding spaces", secret: rfcSecret, input: " 050471 ", skew: 0, wantStep: Step(now), wantOK: true},
+		{name: "wrong code", secret: rfcSecret, input: "123456", skew: 1},
+		{name: "wrong length", secret: rfcSecret, input: "0504710", skew: 1},
+		{name: "invalid secret", secret: "not base32!", input: "050471", skew: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok, err := Validate(tt.secret, tt.input, now, tt.skew)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Error When Validate() %v, wantErr = %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Result When Validate() %d %v, want = %d %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_GenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("Error When GenerateSecret() %s", err.Error())
+	}
+	other, _ := GenerateSecret()
+	if secret == other {
+		t.Errorf("Result When GenerateSecret() returned the same secret twice")
+	}
+
+	code, err := Code(secret, Step(time.Now()))
+	if err != nil {
+		t.Fatalf("Error When Code() %s", err.Error())
+	}
+	if _, ok, _ := Validate(secret, code, time.Now(), 1); !ok {
+		t.Errorf("Result When Validate() rejected the current code of a generated secret")
+	}
+}
+
+func Test_URI(t *testing.T) {
+	got := URI("User Service", "+62821232342", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(got, "otpauth://totp/User%20Service:+62821232342?") {
+		t.Errorf("Result When URI() %s", got)
+	}
+
+	parsed, err := url.Parse(got)
+	if err != nil {
+		t.Fatalf("Error When url.Parse() %s", err.Error())
+	}
+	query := parsed.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "User Service" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("Result When URI() query %v", query)
+	}
+}
@@ -2,8 +2,13 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 func (r *Repository) GetUserByID(ctx context.Context, userID int64) (user User, err error) {
@@ -14,10 +19,13 @@ func (r *Repository) GetUserByID(ctx context.Context, userID int64) (user User,
 
 	defer rows.Close()
 	for rows.Next() {
-		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName)
+		var phoneVerifiedAt, lastLoginAt sql.NullTime
+		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName, &phoneVerifiedAt, &user.PasswordChangedAt, &lastLoginAt, &user.TokenVersion)
 		if err != nil {
 			return user, err
 		}
+		user.PhoneVerifiedAt = phoneVerifiedAt.Time
+		user.LastLoginAt = lastLoginAt.Time
 	}
 
 	return user, nil
@@ -31,30 +39,68 @@ func (r *Repository) GetUserByPhoneNumber(ctx context.Context, phoneNumber strin
 
 	defer rows.Close()
 	for rows.Next() {
-		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName)
+		var phoneVerifiedAt, lastLoginAt sql.NullTime
+		err = rows.Scan(&user.ID, &user.PhoneNumber, &user.Password, &user.FullName, &phoneVerifiedAt, &user.PasswordChangedAt, &lastLoginAt, &user.TokenVersion)
 		if err != nil {
 			return user, err
 		}
+		user.PhoneVerifiedAt = phoneVerifiedAt.Time
+		user.LastLoginAt = lastLoginAt.Time
 	}
 
 	return user, nil
 }
 
-func (r *Repository) CreateLoginCount(ctx context.Context, userID int64) (err error) {
-	_, err = r.Db.ExecContext(ctx, queryCreateLoginCount, userID)
+// InsertLoginEvent records a login attempt for data.UserID, or when it is
+// zero for the user with data.PhoneNumber, if any, and moves their last
+// login forward when it succeeded.
+func (r *Repository) InsertLoginEvent(ctx context.Context, data LoginEvent) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryInsertLoginEvent,
+		data.UserID,
+		data.PhoneNumber,
+		data.IPAddress,
+		data.UserAgent,
+		data.Outcome,
+		data.FailureReason)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// GetLoginEvents returns up to limit login events of the user, newest
+// first, starting after beforeID unless it is 0.
+func (r *Repository) GetLoginEvents(ctx context.Context, userID int64, beforeID int64, limit int) (events []LoginEvent, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetLoginEvents, userID, beforeID, limit)
+	if err != nil {
+		return events, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			event         LoginEvent
+			failureReason sql.NullString
+		)
+		err = rows.Scan(&event.ID, &event.UserID, &event.PhoneNumber, &event.IPAddress, &event.UserAgent, &event.Outcome, &failureReason, &event.CreatedAt)
+		if err != nil {
+			return events, err
+		}
+		event.FailureReason = failureReason.String
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
 func (r *Repository) InsertUser(ctx context.Context, data User) (userID int64, err error) {
 	rows, err := r.Db.QueryContext(ctx, queryInsertUser,
 		data.PhoneNumber,
 		data.Password,
-		data.FullName)
+		data.FullName,
+		sql.NullTime{Time: data.RegistrationExpiresAt, Valid: !data.RegistrationExpiresAt.IsZero()})
 	if err != nil {
-		return userID, err
+		return userID, phoneNumberError(err)
 	}
 
 	defer rows.Close()
@@ -65,7 +111,35 @@ func (r *Repository) InsertUser(ctx context.Context, data User) (userID int64, e
 		}
 	}
 
-	return userID, err
+	return userID, phoneNumberError(rows.Err())
+}
+
+// phoneNumberError turns a violation of the unique index on the phone
+// number into ErrPhoneNumberTaken.
+func phoneNumberError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "user_phone_number" {
+		return ErrPhoneNumberTaken
+	}
+	return err
+}
+
+func (r *Repository) MarkPhoneNumberVerified(ctx context.Context, userID int64) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryMarkPhoneNumberVerified, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpiredRegistrations deletes the users whose registration expired
+// before their phone number was verified.
+func (r *Repository) DeleteExpiredRegistrations(ctx context.Context, before time.Time) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryDeleteExpiredRegistrations, before)
+	if err != nil {
+		return err
+	}
+	return nil
 }
 
 func (r *Repository) UpdateUser(ctx context.Context, data User) (err error) {
@@ -77,19 +151,622 @@ func (r *Repository) UpdateUser(ctx context.Context, data User) (err error) {
 	if data.PhoneNumber != "" {
 		params = append(params, data.PhoneNumber)
 		updatedFields = append(updatedFields, fmt.Sprintf("phone_number = $%d", len(params)))
+		// Tokens carry the phone number, so the ones issued before are stale.
+		updatedFields = append(updatedFields, "token_version = token_version + 1")
 	}
 	if data.FullName != "" {
 		params = append(params, data.FullName)
 		updatedFields = append(updatedFields, fmt.Sprintf("full_name = $%d", len(params)))
 	}
+	if data.Password != "" {
+		params = append(params, data.Password)
+		updatedFields = append(updatedFields, fmt.Sprintf("password = $%d", len(params)))
+	}
+	if !data.PhoneVerifiedAt.IsZero() {
+		params = append(params, data.PhoneVerifiedAt)
+		updatedFields = append(updatedFields, fmt.Sprintf("phone_verified_at = $%d", len(params)))
+	}
 	if len(params) == 1 {
 		return nil
 	}
 	_, err = r.Db.ExecContext(ctx,
 		fmt.Sprintf(queryUpdateUser, strings.Join(updatedFields, ", ")),
 		params...)
+	if err != nil {
+		return phoneNumberError(err)
+	}
+	return nil
+}
+
+// UpdatePassword sets a new password hash, records it in the password
+// history of the user and returns the new token version of the user.
+func (r *Repository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) (tokenVersion int64, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryUpdatePassword, userID, passwordHash)
+	if err != nil {
+		return tokenVersion, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&tokenVersion)
+		if err != nil {
+			return tokenVersion, err
+		}
+	}
+
+	return tokenVersion, nil
+}
+
+// GetPasswordHistory returns the last password hashes of the user, the
+// current one first.
+func (r *Repository) GetPasswordHistory(ctx context.Context, userID int64, limit int) (passwordHashes []string, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetPasswordHistory, userID, limit)
+	if err != nil {
+		return passwordHashes, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var passwordHash string
+		err = rows.Scan(&passwordHash)
+		if err != nil {
+			return passwordHashes, err
+		}
+		passwordHashes = append(passwordHashes, passwordHash)
+	}
+
+	return passwordHashes, nil
+}
+
+// RehashPassword replaces the password hash only while it is still oldHash,
+// so that a password changed in the meantime is not overwritten.
+func (r *Repository) RehashPassword(ctx context.Context, userID int64, oldHash string, newHash string) (updated bool, err error) {
+	result, err := r.Db.ExecContext(ctx, queryRehashPassword, userID, oldHash, newHash)
+	if err != nil {
+		return updated, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return updated, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *Repository) InsertRefreshToken(ctx context.Context, data RefreshToken) (refreshTokenID int64, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryInsertRefreshToken,
+		data.UserID,
+		data.FamilyID,
+		data.TokenHash,
+		data.ExpiresAt)
+	if err != nil {
+		return refreshTokenID, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&refreshTokenID)
+		if err != nil {
+			return refreshTokenID, err
+		}
+	}
+
+	return refreshTokenID, err
+}
+
+func (r *Repository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (refreshToken RefreshToken, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetRefreshTokenByHash, tokenHash)
+	if err != nil {
+		return refreshToken, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var rotatedAt, revokedAt sql.NullTime
+		err = rows.Scan(
+			&refreshToken.ID,
+			&refreshToken.UserID,
+			&refreshToken.FamilyID,
+			&refreshToken.TokenHash,
+			&refreshToken.ExpiresAt,
+			&rotatedAt,
+			&revokedAt)
+		if err != nil {
+			return refreshToken, err
+		}
+		refreshToken.RotatedAt = rotatedAt.Time
+		refreshToken.RevokedAt = revokedAt.Time
+	}
+
+	return refreshToken, nil
+}
+
+// RotateRefreshToken marks the token as used. It reports false when the token
+// was already rotated or revoked, which callers must treat as token reuse.
+func (r *Repository) RotateRefreshToken(ctx context.Context, refreshTokenID int64) (rotated bool, err error) {
+	result, err := r.Db.ExecContext(ctx, queryRotateRefreshToken, refreshTokenID)
+	if err != nil {
+		return rotated, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return rotated, err
+	}
+	return affected == 1, nil
+}
+
+// InsertSession stores the session within the limit. Without a limit it is
+// a single insert; with one, the user is locked while the active sessions
+// are counted so that parallel logins cannot go over it. The ids of the
+// sessions revoked to make room are returned.
+func (r *Repository) InsertSession(ctx context.Context, data Session, limit SessionLimit) (evictedSessionIDs []string, err error) {
+	if limit.MaxSessions <= 0 {
+		_, err = r.Db.ExecContext(ctx, queryInsertSession,
+			data.ID,
+			data.UserID,
+			data.ExpiresAt,
+			data.DeviceName,
+			data.UserAgent,
+			data.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Rolling back after the commit does nothing.
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, queryLockUser, data.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionIDs, err := getActiveSessionIDs(ctx, tx, data.UserID, limit.IdleSince)
+	if err != nil {
+		return nil, err
+	}
+
+	if excess := len(sessionIDs) - limit.MaxSessions + 1; excess > 0 {
+		if !limit.EvictOldest {
+			return nil, ErrSessionLimitReached
+		}
+		evictedSessionIDs = sessionIDs[:excess]
+		_, err = tx.ExecContext(ctx, queryRevokeSessions, pq.Array(evictedSessionIDs))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, queryInsertSession,
+		data.ID,
+		data.UserID,
+		data.ExpiresAt,
+		data.DeviceName,
+		data.UserAgent,
+		data.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return evictedSessionIDs, nil
+}
+
+// getActiveSessionIDs returns the ids of the active sessions of the user,
+// oldest first. The rows are closed before it returns so that the
+// transaction can run the next statement.
+func getActiveSessionIDs(ctx context.Context, tx *sql.Tx, userID int64, idleSince time.Time) (sessionIDs []string, err error) {
+	rows, err := tx.QueryContext(ctx, queryGetActiveSessionIDs, userID, idleSince)
+	if err != nil {
+		return sessionIDs, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var sessionID string
+		err = rows.Scan(&sessionID)
+		if err != nil {
+			return sessionIDs, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+
+	return sessionIDs, rows.Err()
+}
+
+func (r *Repository) GetSessionByID(ctx context.Context, sessionID string) (session Session, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetSessionByID, sessionID)
+	if err != nil {
+		return session, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var revokedAt sql.NullTime
+		err = rows.Scan(&session.ID, &session.UserID, &session.ExpiresAt, &revokedAt)
+		if err != nil {
+			return session, err
+		}
+		session.RevokedAt = revokedAt.Time
+	}
+
+	return session, nil
+}
+
+// TouchSession records that the session was just used from the IP address
+// and returns it. Sessions that were revoked, expired or not seen since
+// idleSince are not touched and come back as they are; a session that does
+// not exist comes back empty.
+func (r *Repository) TouchSession(ctx context.Context, sessionID string, ipAddress string, idleSince time.Time) (session Session, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryTouchSession, sessionID, ipAddress, idleSince)
+	if err != nil {
+		return session, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var revokedAt sql.NullTime
+		err = rows.Scan(&session.ID, &session.UserID, &session.ExpiresAt, &revokedAt, &session.LastSeenAt, &session.TokenVersion)
+		if err != nil {
+			return session, err
+		}
+		session.RevokedAt = revokedAt.Time
+	}
+
+	return session, nil
+}
+
+// GetActiveSessions returns the sessions of the user that are neither
+// revoked nor expired and were seen since idleSince, the most recently used
+// first.
+func (r *Repository) GetActiveSessions(ctx context.Context, userID int64, idleSince time.Time) (sessions []Session, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetActiveSessions, userID, idleSince)
+	if err != nil {
+		return sessions, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var session Session
+		err = rows.Scan(&session.ID, &session.UserID, &session.DeviceName, &session.UserAgent, &session.IPAddress, &session.ExpiresAt, &session.CreatedAt, &session.LastSeenAt)
+		if err != nil {
+			return sessions, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *Repository) RevokeSession(ctx context.Context, sessionID string) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryRevokeSession, sessionID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (r *Repository) GetLoginAttempts(ctx context.Context, keys []string) (attempts []LoginAttempt, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetLoginAttempts, pq.Array(keys))
+	if err != nil {
+		return attempts, err
+	}
+
+	defer rows.Close()
+	return scanLoginAttempts(rows)
+}
+
+func (r *Repository) IncrementLoginAttempts(ctx context.Context, keys []string, windowStart time.Time) (attempts []LoginAttempt, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryIncrementLoginAttempts, pq.Array(keys), windowStart)
+	if err != nil {
+		return attempts, err
+	}
+
+	defer rows.Close()
+	return scanLoginAttempts(rows)
+}
+
+func scanLoginAttempts(rows *sql.Rows) (attempts []LoginAttempt, err error) {
+	for rows.Next() {
+		var (
+			attempt     LoginAttempt
+			lockedUntil sql.NullTime
+		)
+		err = rows.Scan(&attempt.Key, &attempt.FailedCount, &attempt.LastFailedAt, &lockedUntil)
+		if err != nil {
+			return attempts, err
+		}
+		attempt.LockedUntil = lockedUntil.Time
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+func (r *Repository) LockLoginAttempt(ctx context.Context, key string, lockedUntil time.Time) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryLockLoginAttempt, key, lockedUntil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) ResetLoginAttempts(ctx context.Context, keys []string) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryResetLoginAttempts, pq.Array(keys))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) RevokeOtherSessions(ctx context.Context, userID int64, keepSessionID string) (sessionIDs []string, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryRevokeOtherSessions, userID, keepSessionID)
+	if err != nil {
+		return sessionIDs, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var sessionID string
+		err = rows.Scan(&sessionID)
+		if err != nil {
+			return sessionIDs, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+
+	return sessionIDs, nil
+}
+
+func (r *Repository) InsertOneTimeCode(ctx context.Context, data OneTimeCode, limit OneTimeCodeLimit) (oneTimeCodeID int64, err error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return oneTimeCodeID, err
+	}
+	// Rolling back after the commit does nothing.
+	defer tx.Rollback()
+
+	if limit.MaxCodes > 0 {
+		_, err = tx.ExecContext(ctx, queryLockUser, data.UserID)
+		if err != nil {
+			return oneTimeCodeID, err
+		}
+
+		count, err := countOneTimeCodes(ctx, tx, data.UserID, data.Purpose, limit.Since)
+		if err != nil {
+			return oneTimeCodeID, err
+		}
+		if count >= limit.MaxCodes {
+			return oneTimeCodeID, ErrOneTimeCodeLimitReached
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, queryInsertOneTimeCode,
+		data.UserID,
+		data.Purpose,
+		data.PhoneNumber,
+		data.CodeHash,
+		data.ExpiresAt)
+	if err != nil {
+		return oneTimeCodeID, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&oneTimeCodeID)
+		if err != nil {
+			return oneTimeCodeID, err
+		}
+	}
+
+	// The rows have to be done with before the transaction can commit.
+	err = rows.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return oneTimeCodeID, nil
+}
+
+func countOneTimeCodes(ctx context.Context, tx *sql.Tx, userID int64, purpose string, since time.Time) (count int, err error) {
+	rows, err := tx.QueryContext(ctx, queryCountOneTimeCodes, userID, purpose, since)
+	if err != nil {
+		return count, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, rows.Err()
+}
+
+func (r *Repository) GetActiveOneTimeCode(ctx context.Context, userID int64, purpose string) (oneTimeCode OneTimeCode, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetActiveOneTimeCode, userID, purpose)
+	if err != nil {
+		return oneTimeCode, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(
+			&oneTimeCode.ID,
+			&oneTimeCode.UserID,
+			&oneTimeCode.Purpose,
+			&oneTimeCode.PhoneNumber,
+			&oneTimeCode.CodeHash,
+			&oneTimeCode.Attempts,
+			&oneTimeCode.ExpiresAt)
+		if err != nil {
+			return oneTimeCode, err
+		}
+	}
+
+	return oneTimeCode, nil
+}
+
+// ReserveOneTimeCodeAttempt counts an attempt at the code. It reports false
+// when the code was used or already tried maxAttempts times.
+func (r *Repository) ReserveOneTimeCodeAttempt(ctx context.Context, oneTimeCodeID int64, maxAttempts int) (reserved bool, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryReserveOneTimeCodeAttempt, oneTimeCodeID, maxAttempts)
+	if err != nil {
+		return reserved, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var attempts int
+		err = rows.Scan(&attempts)
+		if err != nil {
+			return reserved, err
+		}
+		reserved = true
+	}
+
+	return reserved, nil
+}
+
+func (r *Repository) UseOneTimeCode(ctx context.Context, oneTimeCodeID int64) (used bool, err error) {
+	result, err := r.Db.ExecContext(ctx, queryUseOneTimeCode, oneTimeCodeID)
+	if err != nil {
+		return used, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return used, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *Repository) DeleteExpiredOneTimeCodes(ctx context.Context, before time.Time) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryDeleteExpiredOneTimeCodes, before)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) SaveTOTPSecret(ctx context.Context, data TOTPSecret) (saved bool, err error) {
+	result, err := r.Db.ExecContext(ctx, querySaveTOTPSecret, data.UserID, data.EncryptedSecret)
+	if err != nil {
+		return saved, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return saved, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *Repository) GetTOTPSecret(ctx context.Context, userID int64) (secret TOTPSecret, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryGetTOTPSecret, userID)
+	if err != nil {
+		return secret, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var lastUsedStep sql.NullInt64
+		var confirmedAt sql.NullTime
+		err = rows.Scan(
+			&secret.UserID,
+			&secret.EncryptedSecret,
+			&lastUsedStep,
+			&confirmedAt)
+		if err != nil {
+			return secret, err
+		}
+		secret.LastUsedStep = lastUsedStep.Int64
+		secret.ConfirmedAt = confirmedAt.Time
+	}
+
+	return secret, nil
+}
+
+func (r *Repository) ConfirmTOTPSecret(ctx context.Context, userID int64, step int64) (confirmed bool, err error) {
+	result, err := r.Db.ExecContext(ctx, queryConfirmTOTPSecret, userID, step)
+	if err != nil {
+		return confirmed, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return confirmed, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *Repository) UseTOTPStep(ctx context.Context, userID int64, step int64) (used bool, err error) {
+	result, err := r.Db.ExecContext(ctx, queryUseTOTPStep, userID, step)
+	if err != nil {
+		return used, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return used, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) (err error) {
+	_, err = r.Db.ExecContext(ctx, queryReplaceRecoveryCodes, userID, pq.Array(codeHashes))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (used bool, err error) {
+	result, err := r.Db.ExecContext(ctx, queryUseRecoveryCode, userID, codeHash)
+	if err != nil {
+		return used, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return used, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID int64) (count int, err error) {
+	rows, err := r.Db.QueryContext(ctx, queryCountRecoveryCodes, userID)
+	if err != nil {
+		return count, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	utilsHelper "github.com/Richthonio10/requirement-swtpro/utils"
+	"github.com/lib/pq"
 )
 
 func Test_Repository_GetUserByID(t *testing.T) {
@@ -20,6 +23,9 @@ func Test_Repository_GetUserByID(t *testing.T) {
 		return
 	}
 	defer dbMock.Close()
+	verifiedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	changedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	lastLoginAt := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
 	type fields struct {
 		Db *sql.DB
 	}
@@ -63,7 +69,7 @@ func Test_Repository_GetUserByID(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"})
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "password_changed_at", "last_login_at", "token_version"})
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).
 					WithArgs(int64(1)).
@@ -83,18 +89,22 @@ func Test_Repository_GetUserByID(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"}).
-					AddRow(1, "+628223344556", "<password>", "Sawit")
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "password_changed_at", "last_login_at", "token_version"}).
+					AddRow(1, "+628223344556", "<password>", "Sawit", verifiedAt, changedAt, lastLoginAt, 2)
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).
 					WithArgs(int64(1)).
 					WillReturnRows(resultRows)
 			},
 			detailRes: User{
-				ID:          1,
-				PhoneNumber: "+628223344556",
-				Password:    "<password>",
-				FullName:    "Sawit",
+				ID:                1,
+				PhoneNumber:       "+628223344556",
+				Password:          "<password>",
+				FullName:          "Sawit",
+				PhoneVerifiedAt:   verifiedAt,
+				PasswordChangedAt: changedAt,
+				LastLoginAt:       lastLoginAt,
+				TokenVersion:      2,
 			},
 			detailErr: nil,
 		},
@@ -123,6 +133,7 @@ func Test_Repository_GetUserByPhoneNumber(t *testing.T) {
 		return
 	}
 	defer dbMock.Close()
+	changedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
 	type fields struct {
 		Db *sql.DB
 	}
@@ -166,7 +177,7 @@ func Test_Repository_GetUserByPhoneNumber(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"})
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "password_changed_at", "last_login_at", "token_version"})
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByPhoneNumber)).
 					WithArgs("+628223344556").
@@ -186,18 +197,19 @@ func Test_Repository_GetUserByPhoneNumber(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
-					NewRows([]string{"id", "phone_number", "password", "full_name"}).
-					AddRow(1, "+628223344556", "<password>", "Sawit")
+					NewRows([]string{"id", "phone_number", "password", "full_name", "phone_verified_at", "password_changed_at", "last_login_at", "token_version"}).
+					AddRow(1, "+628223344556", "<password>", "Sawit", nil, changedAt, nil, 0)
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetUserByPhoneNumber)).
 					WithArgs("+628223344556").
 					WillReturnRows(resultRows)
 			},
 			detailRes: User{
-				ID:          1,
-				PhoneNumber: "+628223344556",
-				Password:    "<password>",
-				FullName:    "Sawit",
+				ID:                1,
+				PhoneNumber:       "+628223344556",
+				Password:          "<password>",
+				FullName:          "Sawit",
+				PasswordChangedAt: changedAt,
 			},
 			detailErr: nil,
 		},
@@ -219,10 +231,216 @@ func Test_Repository_GetUserByPhoneNumber(t *testing.T) {
 	}
 }
 
-func Test_Repository_CreateLoginCount(t *testing.T) {
+func Test_Repository_InsertLoginEvent(t *testing.T) {
 	dbMock, sqlMock, err := sqlmock.New()
 	if err != nil {
-		t.Errorf("[Test_Repository_CreateLoginCount] %s", err.Error())
+		t.Errorf("[Test_Repository_InsertLoginEvent] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data LoginEvent
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: LoginEvent{
+					PhoneNumber:   "+628223344556",
+					IPAddress:     "192.0.2.1",
+					UserAgent:     "curl/8.0",
+					Outcome:       LoginOutcomeFailure,
+					FailureReason: "Wrong password",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertLoginEvent)).
+					WithArgs(int64(0), "+628223344556", "192.0.2.1", "curl/8.0", LoginOutcomeFailure, "Wrong password").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: LoginEvent{
+					UserID:      1,
+					PhoneNumber: "+628223344556",
+					IPAddress:   "192.0.2.1",
+					UserAgent:   "curl/8.0",
+					Outcome:     LoginOutcomeSuccess,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertLoginEvent)).
+					WithArgs(int64(1), "+628223344556", "192.0.2.1", "curl/8.0", LoginOutcomeSuccess, "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			err := r.InsertLoginEvent(tt.args.ctx, tt.args.data)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When InsertLoginEvent() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_GetLoginEvents(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetLoginEvents] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	createdAt := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx      context.Context
+		userID   int64
+		beforeID int64
+		limit    int
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes []LoginEvent
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				limit:  2,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginEvents)).
+					WithArgs(int64(1), int64(0), 2).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				beforeID: 10,
+				limit:    2,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "phone_number", "ip_address", "user_agent", "outcome", "failure_reason", "created_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginEvents)).
+					WithArgs(int64(1), int64(10), 2).
+					WillReturnRows(resultRows)
+			},
+			detailRes: nil,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				limit:  2,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "phone_number", "ip_address", "user_agent", "outcome", "failure_reason", "created_at"}).
+					AddRow(11, 1, "+628223344556", "192.0.2.1", "curl/8.0", LoginOutcomeSuccess, nil, createdAt).
+					AddRow(10, 1, "+628223344556", "192.0.2.1", "curl/8.0", LoginOutcomeFailure, "Wrong password", createdAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginEvents)).
+					WithArgs(int64(1), int64(0), 2).
+					WillReturnRows(resultRows)
+			},
+			detailRes: []LoginEvent{
+				{
+					ID:          11,
+					UserID:      1,
+					PhoneNumber: "+628223344556",
+					IPAddress:   "192.0.2.1",
+					UserAgent:   "curl/8.0",
+					Outcome:     LoginOutcomeSuccess,
+					CreatedAt:   createdAt,
+				},
+				{
+					ID:            10,
+					UserID:        1,
+					PhoneNumber:   "+628223344556",
+					IPAddress:     "192.0.2.1",
+					UserAgent:     "curl/8.0",
+					Outcome:       LoginOutcomeFailure,
+					FailureReason: "Wrong password",
+					CreatedAt:     createdAt,
+				},
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetLoginEvents(tt.args.ctx, tt.args.userID, tt.args.beforeID, tt.args.limit)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetLoginEvents() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetLoginEvents() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_MarkPhoneNumberVerified(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_MarkPhoneNumberVerified] %s", err.Error())
 		return
 	}
 	defer dbMock.Close()
@@ -250,7 +468,7 @@ func Test_Repository_CreateLoginCount(t *testing.T) {
 				userID: 1,
 			},
 			mock: func(fields *fields) {
-				sqlMock.ExpectExec(regexp.QuoteMeta(queryCreateLoginCount)).
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneNumberVerified)).
 					WithArgs(int64(1)).
 					WillReturnError(errors.New("expected error"))
 			},
@@ -266,7 +484,7 @@ func Test_Repository_CreateLoginCount(t *testing.T) {
 				userID: 1,
 			},
 			mock: func(fields *fields) {
-				sqlMock.ExpectExec(regexp.QuoteMeta(queryCreateLoginCount)).
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryMarkPhoneNumberVerified)).
 					WithArgs(int64(1)).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 			},
@@ -279,9 +497,78 @@ func Test_Repository_CreateLoginCount(t *testing.T) {
 				Db: tt.fields.Db,
 			}
 			tt.mock(&tt.fields)
-			err := r.CreateLoginCount(tt.args.ctx, tt.args.userID)
+			err := r.MarkPhoneNumberVerified(tt.args.ctx, tt.args.userID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When MarkPhoneNumberVerified() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_DeleteExpiredRegistrations(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_DeleteExpiredRegistrations] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		before time.Time
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				before: before,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredRegistrations)).
+					WithArgs(before).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				before: before,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredRegistrations)).
+					WithArgs(before).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			err := r.DeleteExpiredRegistrations(tt.args.ctx, tt.args.before)
 			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
-				t.Errorf("Error When CreateLoginCount() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+				t.Errorf("Error When DeleteExpiredRegistrations() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
 			}
 		})
 	}
@@ -294,6 +581,7 @@ func Test_Repository_InsertUser(t *testing.T) {
 		return
 	}
 	defer dbMock.Close()
+	registrationExpiresAt := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)
 	type fields struct {
 		Db *sql.DB
 	}
@@ -324,14 +612,14 @@ func Test_Repository_InsertUser(t *testing.T) {
 			},
 			mock: func(fields *fields) {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertUser)).
-					WithArgs("+628223344556", "<password>", "Sawit").
+					WithArgs("+628223344556", "<password>", "Sawit", sql.NullTime{}).
 					WillReturnError(errors.New("expected error"))
 			},
 			detailRes: 0,
 			detailErr: errors.New("expected error"),
 		},
 		{
-			name: "passed",
+			name: "phone number taken",
 			fields: fields{
 				Db: dbMock,
 			},
@@ -343,13 +631,35 @@ func Test_Repository_InsertUser(t *testing.T) {
 					FullName:    "Sawit",
 				},
 			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertUser)).
+					WithArgs("+628223344556", "<password>", "Sawit", sql.NullTime{}).
+					WillReturnError(&pq.Error{Code: "23505", Constraint: "user_phone_number"})
+			},
+			detailRes: 0,
+			detailErr: ErrPhoneNumberTaken,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					PhoneNumber:           "+628223344556",
+					Password:              "<password>",
+					FullName:              "Sawit",
+					RegistrationExpiresAt: registrationExpiresAt,
+				},
+			},
 			mock: func(fields *fields) {
 				resultRows := sqlmock.
 					NewRows([]string{"id"}).
 					AddRow(1)
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertUser)).
-					WithArgs("+628223344556", "<password>", "Sawit").
+					WithArgs("+628223344556", "<password>", "Sawit", registrationExpiresAt).
 					WillReturnRows(resultRows)
 			},
 			detailRes: 1,
@@ -424,7 +734,7 @@ func Test_Repository_UpdateUser(t *testing.T) {
 				},
 			},
 			mock: func(fields *fields) {
-				sqlMock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(queryUpdateUser, "phone_number = $2, full_name = $3"))).
+				sqlMock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(queryUpdateUser, "phone_number = $2, token_version = token_version + 1, full_name = $3"))).
 					WithArgs(int64(1), "+62812345678", "New Name").
 					WillReturnError(errors.New("expected error"))
 			},
@@ -444,12 +754,51 @@ func Test_Repository_UpdateUser(t *testing.T) {
 				},
 			},
 			mock: func(fields *fields) {
-				sqlMock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(queryUpdateUser, "phone_number = $2, full_name = $3"))).
+				sqlMock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(queryUpdateUser, "phone_number = $2, token_version = token_version + 1, full_name = $3"))).
 					WithArgs(int64(1), "+62812345678", "New Name").
 					WillReturnResult(sqlmock.NewResult(0, 1))
 			},
 			detailErr: nil,
 		},
+		{
+			name: "password",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:       1,
+					Password: "<hash>",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(queryUpdateUser, "password = $2"))).
+					WithArgs(int64(1), "<hash>").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailErr: nil,
+		},
+		{
+			name: "verified phone number",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: User{
+					ID:              1,
+					PhoneNumber:     "+62812345678",
+					PhoneVerifiedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(queryUpdateUser, "phone_number = $2, token_version = token_version + 1, phone_verified_at = $3"))).
+					WithArgs(int64(1), "+62812345678", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailErr: nil,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -464,3 +813,2817 @@ func Test_Repository_UpdateUser(t *testing.T) {
 		})
 	}
 }
+
+func Test_Repository_UpdatePassword(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UpdatePassword] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx          context.Context
+		userID       int64
+		passwordHash string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes int64
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				userID:       1,
+				passwordHash: "<new hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<new hash>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: 0,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:          context.Background(),
+				userID:       1,
+				passwordHash: "<new hash>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"token_version"}).
+					AddRow(3)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryUpdatePassword)).
+					WithArgs(int64(1), "<new hash>").
+					WillReturnRows(resultRows)
+			},
+			detailRes: 3,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.UpdatePassword(tt.args.ctx, tt.args.userID, tt.args.passwordHash)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When UpdatePassword() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When UpdatePassword() %d, detailRes = %d", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetPasswordHistory(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetPasswordHistory] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+		limit  int
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes []string
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				limit:  3,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetPasswordHistory)).
+					WithArgs(int64(1), 3).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				limit:  3,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"password"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetPasswordHistory)).
+					WithArgs(int64(1), 3).
+					WillReturnRows(resultRows)
+			},
+			detailRes: nil,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				limit:  3,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"password"}).
+					AddRow("<current hash>").
+					AddRow("<previous hash>")
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetPasswordHistory)).
+					WithArgs(int64(1), 3).
+					WillReturnRows(resultRows)
+			},
+			detailRes: []string{"<current hash>", "<previous hash>"},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetPasswordHistory(tt.args.ctx, tt.args.userID, tt.args.limit)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetPasswordHistory() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetPasswordHistory() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_RehashPassword(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RehashPassword] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx     context.Context
+		userID  int64
+		oldHash string
+		newHash string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:     context.Background(),
+				userID:  1,
+				oldHash: "<old hash>",
+				newHash: "<new hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRehashPassword)).
+					WithArgs(int64(1), "<old hash>", "<new hash>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "password changed meanwhile",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:     context.Background(),
+				userID:  1,
+				oldHash: "<old hash>",
+				newHash: "<new hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRehashPassword)).
+					WithArgs(int64(1), "<old hash>", "<new hash>").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:     context.Background(),
+				userID:  1,
+				oldHash: "<old hash>",
+				newHash: "<new hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRehashPassword)).
+					WithArgs(int64(1), "<old hash>", "<new hash>").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.RehashPassword(tt.args.ctx, tt.args.userID, tt.args.oldHash, tt.args.newHash)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RehashPassword() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When RehashPassword() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertRefreshToken(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertRefreshToken] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data RefreshToken
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes int64
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: RefreshToken{
+					UserID:    1,
+					FamilyID:  "<family>",
+					TokenHash: "<hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertRefreshToken)).
+					WithArgs(int64(1), "<family>", "<hash>", expiresAt).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: 0,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: RefreshToken{
+					UserID:    1,
+					FamilyID:  "<family>",
+					TokenHash: "<hash>",
+					ExpiresAt: expiresAt,
+				},
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id"}).
+					AddRow(1)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertRefreshToken)).
+					WithArgs(int64(1), "<family>", "<hash>", expiresAt).
+					WillReturnRows(resultRows)
+			},
+			detailRes: 1,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.InsertRefreshToken(tt.args.ctx, tt.args.data)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When InsertRefreshToken() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When InsertRefreshToken() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetRefreshTokenByHash(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetRefreshTokenByHash] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rotatedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx       context.Context
+		tokenHash string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes RefreshToken
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokenByHash)).
+					WithArgs("<hash>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: RefreshToken{},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "family_id", "token_hash", "expires_at", "rotated_at", "revoked_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokenByHash)).
+					WithArgs("<hash>").
+					WillReturnRows(resultRows)
+			},
+			detailRes: RefreshToken{},
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				tokenHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "family_id", "token_hash", "expires_at", "rotated_at", "revoked_at"}).
+					AddRow(1, 2, "<family>", "<hash>", expiresAt, rotatedAt, nil)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetRefreshTokenByHash)).
+					WithArgs("<hash>").
+					WillReturnRows(resultRows)
+			},
+			detailRes: RefreshToken{
+				ID:        1,
+				UserID:    2,
+				FamilyID:  "<family>",
+				TokenHash: "<hash>",
+				ExpiresAt: expiresAt,
+				RotatedAt: rotatedAt,
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetRefreshTokenByHash(tt.args.ctx, tt.args.tokenHash)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetRefreshTokenByHash() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetRefreshTokenByHash() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_RotateRefreshToken(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RotateRefreshToken] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx            context.Context
+		refreshTokenID int64
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:            context.Background(),
+				refreshTokenID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRotateRefreshToken)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "already rotated",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:            context.Background(),
+				refreshTokenID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRotateRefreshToken)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:            context.Background(),
+				refreshTokenID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRotateRefreshToken)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.RotateRefreshToken(tt.args.ctx, tt.args.refreshTokenID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RotateRefreshToken() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When RotateRefreshToken() %t, detailRes = %t", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertSession(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertSession] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	idleSince := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	session := Session{
+		ID:         "<session>",
+		UserID:     1,
+		DeviceName: "Work laptop",
+		UserAgent:  "curl/8.0",
+		IPAddress:  "192.0.2.1",
+		ExpiresAt:  expiresAt,
+	}
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx   context.Context
+		data  Session
+		limit SessionLimit
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes []string
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				data: session,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertSession)).
+					WithArgs("<session>", int64(1), expiresAt, "Work laptop", "curl/8.0", "192.0.2.1").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				data: session,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertSession)).
+					WithArgs("<session>", int64(1), expiresAt, "Work laptop", "curl/8.0", "192.0.2.1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: nil,
+			detailErr: nil,
+		},
+		{
+			name: "error lock user",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  session,
+				limit: SessionLimit{MaxSessions: 2, IdleSince: idleSince},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "limit reached",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  session,
+				limit: SessionLimit{MaxSessions: 2, IdleSince: idleSince},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveSessionIDs)).
+					WithArgs(int64(1), idleSince).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("<first>").AddRow("<second>"))
+				sqlMock.ExpectRollback()
+			},
+			detailRes: nil,
+			detailErr: ErrSessionLimitReached,
+		},
+		{
+			name: "under the limit",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  session,
+				limit: SessionLimit{MaxSessions: 2, IdleSince: idleSince},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveSessionIDs)).
+					WithArgs(int64(1), idleSince).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("<first>"))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertSession)).
+					WithArgs("<session>", int64(1), expiresAt, "Work laptop", "curl/8.0", "192.0.2.1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			detailRes: nil,
+			detailErr: nil,
+		},
+		{
+			name: "evict oldest",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  session,
+				limit: SessionLimit{MaxSessions: 2, EvictOldest: true, IdleSince: idleSince},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveSessionIDs)).
+					WithArgs(int64(1), idleSince).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("<first>").AddRow("<second>").AddRow("<third>"))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeSessions)).
+					WithArgs(pq.Array([]string{"<first>", "<second>"})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertSession)).
+					WithArgs("<session>", int64(1), expiresAt, "Work laptop", "curl/8.0", "192.0.2.1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			detailRes: []string{"<first>", "<second>"},
+			detailErr: nil,
+		},
+		{
+			name: "error commit",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  session,
+				limit: SessionLimit{MaxSessions: 2, EvictOldest: true, IdleSince: idleSince},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveSessionIDs)).
+					WithArgs(int64(1), idleSince).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryInsertSession)).
+					WithArgs("<session>", int64(1), expiresAt, "Work laptop", "curl/8.0", "192.0.2.1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit().
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.InsertSession(tt.args.ctx, tt.args.data, tt.args.limit)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When InsertSession() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When InsertSession() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetSessionByID(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetSessionByID] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	revokedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx       context.Context
+		sessionID string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes Session
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetSessionByID)).
+					WithArgs("<session>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: Session{},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "expires_at", "revoked_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetSessionByID)).
+					WithArgs("<session>").
+					WillReturnRows(resultRows)
+			},
+			detailRes: Session{},
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "expires_at", "revoked_at"}).
+					AddRow("<session>", 1, expiresAt, revokedAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetSessionByID)).
+					WithArgs("<session>").
+					WillReturnRows(resultRows)
+			},
+			detailRes: Session{
+				ID:        "<session>",
+				UserID:    1,
+				ExpiresAt: expiresAt,
+				RevokedAt: revokedAt,
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetSessionByID(tt.args.ctx, tt.args.sessionID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetSessionByID() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetSessionByID() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_TouchSession(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_TouchSession] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	idleSince := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	lastSeenAt := time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx       context.Context
+		sessionID string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes Session
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryTouchSession)).
+					WithArgs("<session>", "192.0.2.1", idleSince).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: Session{},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "last_seen_at", "token_version"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryTouchSession)).
+					WithArgs("<session>", "192.0.2.1", idleSince).
+					WillReturnRows(resultRows)
+			},
+			detailRes: Session{},
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "last_seen_at", "token_version"}).
+					AddRow("<session>", 1, expiresAt, nil, lastSeenAt, 2)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryTouchSession)).
+					WithArgs("<session>", "192.0.2.1", idleSince).
+					WillReturnRows(resultRows)
+			},
+			detailRes: Session{
+				ID:           "<session>",
+				UserID:       1,
+				ExpiresAt:    expiresAt,
+				LastSeenAt:   lastSeenAt,
+				TokenVersion: 2,
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.TouchSession(tt.args.ctx, tt.args.sessionID, "192.0.2.1", idleSince)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When TouchSession() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When TouchSession() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetActiveSessions(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetActiveSessions] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	lastSeenAt := time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)
+	idleSince := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes []Session
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveSessions)).
+					WithArgs(int64(1), idleSince).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "device_name", "user_agent", "ip_address", "expires_at", "created_at", "last_seen_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveSessions)).
+					WithArgs(int64(1), idleSince).
+					WillReturnRows(resultRows)
+			},
+			detailRes: nil,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "device_name", "user_agent", "ip_address", "expires_at", "created_at", "last_seen_at"}).
+					AddRow("<session>", 1, "Work laptop", "curl/8.0", "192.0.2.1", expiresAt, createdAt, lastSeenAt).
+					AddRow("<other session>", 1, "", "Mozilla/5.0", "192.0.2.2", expiresAt, createdAt, createdAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveSessions)).
+					WithArgs(int64(1), idleSince).
+					WillReturnRows(resultRows)
+			},
+			detailRes: []Session{
+				{
+					ID:         "<session>",
+					UserID:     1,
+					DeviceName: "Work laptop",
+					UserAgent:  "curl/8.0",
+					IPAddress:  "192.0.2.1",
+					ExpiresAt:  expiresAt,
+					CreatedAt:  createdAt,
+					LastSeenAt: lastSeenAt,
+				},
+				{
+					ID:         "<other session>",
+					UserID:     1,
+					UserAgent:  "Mozilla/5.0",
+					IPAddress:  "192.0.2.2",
+					ExpiresAt:  expiresAt,
+					CreatedAt:  createdAt,
+					LastSeenAt: createdAt,
+				},
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetActiveSessions(tt.args.ctx, tt.args.userID, idleSince)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetActiveSessions() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetActiveSessions() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_RevokeSession(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RevokeSession] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx       context.Context
+		sessionID string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeSession)).
+					WithArgs("<session>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:       context.Background(),
+				sessionID: "<session>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryRevokeSession)).
+					WithArgs("<session>").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			err := r.RevokeSession(tt.args.ctx, tt.args.sessionID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RevokeSession() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_GetLoginAttempts(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetLoginAttempts] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	lastFailedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	lockedUntil := time.Date(2024, 3, 1, 0, 15, 0, 0, time.UTC)
+	keys := []string{"phone:+62821232342", "ip:127.0.0.1"}
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		keys []string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes []LoginAttempt
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				keys: keys,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginAttempts)).
+					WithArgs(pq.Array(keys)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				keys: keys,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"key", "failed_count", "last_failed_at", "locked_until"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginAttempts)).
+					WithArgs(pq.Array(keys)).
+					WillReturnRows(resultRows)
+			},
+			detailRes: nil,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				keys: keys,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"key", "failed_count", "last_failed_at", "locked_until"}).
+					AddRow("phone:+62821232342", 5, lastFailedAt, lockedUntil).
+					AddRow("ip:127.0.0.1", 2, lastFailedAt, nil)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetLoginAttempts)).
+					WithArgs(pq.Array(keys)).
+					WillReturnRows(resultRows)
+			},
+			detailRes: []LoginAttempt{
+				{
+					Key:          "phone:+62821232342",
+					FailedCount:  5,
+					LastFailedAt: lastFailedAt,
+					LockedUntil:  lockedUntil,
+				},
+				{
+					Key:          "ip:127.0.0.1",
+					FailedCount:  2,
+					LastFailedAt: lastFailedAt,
+				},
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetLoginAttempts(tt.args.ctx, tt.args.keys)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetLoginAttempts() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetLoginAttempts() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_IncrementLoginAttempts(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_IncrementLoginAttempts] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	windowStart := time.Date(2024, 2, 29, 23, 45, 0, 0, time.UTC)
+	lastFailedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	keys := []string{"phone:+62821232342"}
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx         context.Context
+		keys        []string
+		windowStart time.Time
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes []LoginAttempt
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				keys:        keys,
+				windowStart: windowStart,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryIncrementLoginAttempts)).
+					WithArgs(pq.Array(keys), windowStart).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				keys:        keys,
+				windowStart: windowStart,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"key", "failed_count", "last_failed_at", "locked_until"}).
+					AddRow("phone:+62821232342", 3, lastFailedAt, nil)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryIncrementLoginAttempts)).
+					WithArgs(pq.Array(keys), windowStart).
+					WillReturnRows(resultRows)
+			},
+			detailRes: []LoginAttempt{
+				{
+					Key:          "phone:+62821232342",
+					FailedCount:  3,
+					LastFailedAt: lastFailedAt,
+				},
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.IncrementLoginAttempts(tt.args.ctx, tt.args.keys, tt.args.windowStart)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When IncrementLoginAttempts() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When IncrementLoginAttempts() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_LockLoginAttempt(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_LockLoginAttempt] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	lockedUntil := time.Date(2024, 3, 1, 0, 15, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx         context.Context
+		key         string
+		lockedUntil time.Time
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				key:         "phone:+62821232342",
+				lockedUntil: lockedUntil,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockLoginAttempt)).
+					WithArgs("phone:+62821232342", lockedUntil).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:         context.Background(),
+				key:         "phone:+62821232342",
+				lockedUntil: lockedUntil,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockLoginAttempt)).
+					WithArgs("phone:+62821232342", lockedUntil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			err := r.LockLoginAttempt(tt.args.ctx, tt.args.key, tt.args.lockedUntil)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When LockLoginAttempt() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_ResetLoginAttempts(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ResetLoginAttempts] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	keys := []string{"phone:+62821232342"}
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		keys []string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				keys: keys,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryResetLoginAttempts)).
+					WithArgs(pq.Array(keys)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				keys: keys,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryResetLoginAttempts)).
+					WithArgs(pq.Array(keys)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			err := r.ResetLoginAttempts(tt.args.ctx, tt.args.keys)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When ResetLoginAttempts() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_RevokeOtherSessions(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_RevokeOtherSessions] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx           context.Context
+		userID        int64
+		keepSessionID string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes []string
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				userID:        1,
+				keepSessionID: "<current>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryRevokeOtherSessions)).
+					WithArgs(int64(1), "<current>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: nil,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				userID:        1,
+				keepSessionID: "<current>",
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id"}).
+					AddRow("<other-1>").
+					AddRow("<other-2>")
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryRevokeOtherSessions)).
+					WithArgs(int64(1), "<current>").
+					WillReturnRows(resultRows)
+			},
+			detailRes: []string{"<other-1>", "<other-2>"},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.RevokeOtherSessions(tt.args.ctx, tt.args.userID, tt.args.keepSessionID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When RevokeOtherSessions() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When RevokeOtherSessions() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_InsertOneTimeCode(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_InsertOneTimeCode] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 10, 0, 0, time.UTC)
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	data := OneTimeCode{
+		UserID:      1,
+		Purpose:     OneTimeCodePurposePasswordReset,
+		PhoneNumber: "+62821232342",
+		CodeHash:    "<hash>",
+		ExpiresAt:   expiresAt,
+	}
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx   context.Context
+		data  OneTimeCode
+		limit OneTimeCodeLimit
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes int64
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				data: data,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertOneTimeCode)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset, "+62821232342", "<hash>", expiresAt).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			detailRes: 0,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "error count codes",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  data,
+				limit: OneTimeCodeLimit{MaxCodes: 3, Since: since},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryCountOneTimeCodes)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset, since).
+					WillReturnError(errors.New("expected error"))
+				sqlMock.ExpectRollback()
+			},
+			detailRes: 0,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "limit reached",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  data,
+				limit: OneTimeCodeLimit{MaxCodes: 3, Since: since},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryCountOneTimeCodes)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset, since).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				sqlMock.ExpectRollback()
+			},
+			detailRes: 0,
+			detailErr: ErrOneTimeCodeLimitReached,
+		},
+		{
+			name: "under the limit",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:   context.Background(),
+				data:  data,
+				limit: OneTimeCodeLimit{MaxCodes: 3, Since: since},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryLockUser)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryCountOneTimeCodes)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset, since).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertOneTimeCode)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset, "+62821232342", "<hash>", expiresAt).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				sqlMock.ExpectCommit()
+			},
+			detailRes: 1,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:  context.Background(),
+				data: data,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id"}).
+					AddRow(1)
+
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryInsertOneTimeCode)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset, "+62821232342", "<hash>", expiresAt).
+					WillReturnRows(resultRows)
+				sqlMock.ExpectCommit()
+			},
+			detailRes: 1,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.InsertOneTimeCode(tt.args.ctx, tt.args.data, tt.args.limit)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When InsertOneTimeCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When InsertOneTimeCode() %d, detailRes = %d", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetActiveOneTimeCode(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetActiveOneTimeCode] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	expiresAt := time.Date(2024, 3, 1, 0, 10, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx     context.Context
+		userID  int64
+		purpose string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes OneTimeCode
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:     context.Background(),
+				userID:  1,
+				purpose: OneTimeCodePurposePasswordReset,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveOneTimeCode)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: OneTimeCode{},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:     context.Background(),
+				userID:  1,
+				purpose: OneTimeCodePurposePasswordReset,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "purpose", "phone_number", "code_hash", "attempts", "expires_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveOneTimeCode)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset).
+					WillReturnRows(resultRows)
+			},
+			detailRes: OneTimeCode{},
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:     context.Background(),
+				userID:  1,
+				purpose: OneTimeCodePurposePasswordReset,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"id", "user_id", "purpose", "phone_number", "code_hash", "attempts", "expires_at"}).
+					AddRow(2, 1, OneTimeCodePurposePasswordReset, "+62821232342", "<hash>", 1, expiresAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetActiveOneTimeCode)).
+					WithArgs(int64(1), OneTimeCodePurposePasswordReset).
+					WillReturnRows(resultRows)
+			},
+			detailRes: OneTimeCode{
+				ID:          2,
+				UserID:      1,
+				Purpose:     OneTimeCodePurposePasswordReset,
+				PhoneNumber: "+62821232342",
+				CodeHash:    "<hash>",
+				Attempts:    1,
+				ExpiresAt:   expiresAt,
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetActiveOneTimeCode(tt.args.ctx, tt.args.userID, tt.args.purpose)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetActiveOneTimeCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetActiveOneTimeCode() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_ReserveOneTimeCodeAttempt(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ReserveOneTimeCodeAttempt] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx           context.Context
+		oneTimeCodeID int64
+		maxAttempts   int
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				oneTimeCodeID: 1,
+				maxAttempts:   5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryReserveOneTimeCodeAttempt)).
+					WithArgs(int64(1), 5).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no attempts left",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				oneTimeCodeID: 1,
+				maxAttempts:   5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryReserveOneTimeCodeAttempt)).
+					WithArgs(int64(1), 5).
+					WillReturnRows(sqlmock.NewRows([]string{"attempts"}))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				oneTimeCodeID: 1,
+				maxAttempts:   5,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryReserveOneTimeCodeAttempt)).
+					WithArgs(int64(1), 5).
+					WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(5))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.ReserveOneTimeCodeAttempt(tt.args.ctx, tt.args.oneTimeCodeID, tt.args.maxAttempts)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When ReserveOneTimeCodeAttempt() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When ReserveOneTimeCodeAttempt() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+// Test_queryReserveOneTimeCodeAttempt checks that the attempt limit is part
+// of the update itself, so that it holds for parallel guesses: the row is
+// only counted while attempts are below the limit and unused.
+func Test_queryReserveOneTimeCodeAttempt(t *testing.T) {
+	for _, condition := range []string{"attempts < $2", "used_at IS NULL", "RETURNING attempts"} {
+		if !strings.Contains(queryReserveOneTimeCodeAttempt, condition) {
+			t.Errorf("Result When queryReserveOneTimeCodeAttempt has no %q", condition)
+		}
+	}
+}
+
+func Test_Repository_UseOneTimeCode(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UseOneTimeCode] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx           context.Context
+		oneTimeCodeID int64
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				oneTimeCodeID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseOneTimeCode)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "already used",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				oneTimeCodeID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseOneTimeCode)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:           context.Background(),
+				oneTimeCodeID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseOneTimeCode)).
+					WithArgs(int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.UseOneTimeCode(tt.args.ctx, tt.args.oneTimeCodeID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When UseOneTimeCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When UseOneTimeCode() %t, detailRes = %t", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_DeleteExpiredOneTimeCodes(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_DeleteExpiredOneTimeCodes] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		before time.Time
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				before: before,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredOneTimeCodes)).
+					WithArgs(before).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				before: before,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredOneTimeCodes)).
+					WithArgs(before).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			err := r.DeleteExpiredOneTimeCodes(tt.args.ctx, tt.args.before)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When DeleteExpiredOneTimeCodes() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_SaveTOTPSecret(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_SaveTOTPSecret] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx  context.Context
+		data TOTPSecret
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: TOTPSecret{
+					UserID:          1,
+					EncryptedSecret: "<encrypted>",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(querySaveTOTPSecret)).
+					WithArgs(int64(1), "<encrypted>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "not saved",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: TOTPSecret{
+					UserID:          1,
+					EncryptedSecret: "<encrypted>",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(querySaveTOTPSecret)).
+					WithArgs(int64(1), "<encrypted>").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx: context.Background(),
+				data: TOTPSecret{
+					UserID:          1,
+					EncryptedSecret: "<encrypted>",
+				},
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(querySaveTOTPSecret)).
+					WithArgs(int64(1), "<encrypted>").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.SaveTOTPSecret(tt.args.ctx, tt.args.data)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When SaveTOTPSecret() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When SaveTOTPSecret() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_GetTOTPSecret(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_GetTOTPSecret] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	confirmedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes TOTPSecret
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTOTPSecret)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: TOTPSecret{},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "no data",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"user_id", "encrypted_secret", "last_used_step", "confirmed_at"})
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTOTPSecret)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			detailRes: TOTPSecret{},
+			detailErr: nil,
+		},
+		{
+			name: "not confirmed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"user_id", "encrypted_secret", "last_used_step", "confirmed_at"}).
+					AddRow(1, "<encrypted>", nil, nil)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTOTPSecret)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			detailRes: TOTPSecret{
+				UserID:          1,
+				EncryptedSecret: "<encrypted>",
+			},
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"user_id", "encrypted_secret", "last_used_step", "confirmed_at"}).
+					AddRow(1, "<encrypted>", 56666666, confirmedAt)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryGetTOTPSecret)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			detailRes: TOTPSecret{
+				UserID:          1,
+				EncryptedSecret: "<encrypted>",
+				LastUsedStep:    56666666,
+				ConfirmedAt:     confirmedAt,
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.GetTOTPSecret(tt.args.ctx, tt.args.userID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When GetTOTPSecret() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if !reflect.DeepEqual(res, tt.detailRes) {
+				t.Errorf("Result When GetTOTPSecret() %+v, detailRes = %+v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_ConfirmTOTPSecret(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ConfirmTOTPSecret] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+		step   int64
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   56666666,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmTOTPSecret)).
+					WithArgs(int64(1), int64(56666666)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "not confirmed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   56666666,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmTOTPSecret)).
+					WithArgs(int64(1), int64(56666666)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   56666666,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryConfirmTOTPSecret)).
+					WithArgs(int64(1), int64(56666666)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.ConfirmTOTPSecret(tt.args.ctx, tt.args.userID, tt.args.step)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When ConfirmTOTPSecret() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When ConfirmTOTPSecret() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_UseTOTPStep(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UseTOTPStep] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+		step   int64
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   56666666,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseTOTPStep)).
+					WithArgs(int64(1), int64(56666666)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "not used",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   56666666,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseTOTPStep)).
+					WithArgs(int64(1), int64(56666666)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+				step:   56666666,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseTOTPStep)).
+					WithArgs(int64(1), int64(56666666)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.UseTOTPStep(tt.args.ctx, tt.args.userID, tt.args.step)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When UseTOTPStep() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When UseTOTPStep() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_ReplaceRecoveryCodes(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_ReplaceRecoveryCodes] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	codeHashes := []string{"<hash1>", "<hash2>"}
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx        context.Context
+		userID     int64
+		codeHashes []string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				codeHashes: codeHashes,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryReplaceRecoveryCodes)).
+					WithArgs(int64(1), pq.Array(codeHashes)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:        context.Background(),
+				userID:     1,
+				codeHashes: codeHashes,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryReplaceRecoveryCodes)).
+					WithArgs(int64(1), pq.Array(codeHashes)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			err := r.ReplaceRecoveryCodes(tt.args.ctx, tt.args.userID, tt.args.codeHashes)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When ReplaceRecoveryCodes() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+		})
+	}
+}
+
+func Test_Repository_UseRecoveryCode(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_UseRecoveryCode] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx      context.Context
+		userID   int64
+		codeHash string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes bool
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				codeHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseRecoveryCode)).
+					WithArgs(int64(1), "<hash>").
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: false,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "unknown or used code",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				codeHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseRecoveryCode)).
+					WithArgs(int64(1), "<hash>").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			detailRes: false,
+			detailErr: nil,
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:      context.Background(),
+				userID:   1,
+				codeHash: "<hash>",
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectExec(regexp.QuoteMeta(queryUseRecoveryCode)).
+					WithArgs(int64(1), "<hash>").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			detailRes: true,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.UseRecoveryCode(tt.args.ctx, tt.args.userID, tt.args.codeHash)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When UseRecoveryCode() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When UseRecoveryCode() %v, detailRes = %v", res, tt.detailRes)
+			}
+		})
+	}
+}
+
+func Test_Repository_CountRecoveryCodes(t *testing.T) {
+	dbMock, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("[Test_Repository_CountRecoveryCodes] %s", err.Error())
+		return
+	}
+	defer dbMock.Close()
+	type fields struct {
+		Db *sql.DB
+	}
+	type args struct {
+		ctx    context.Context
+		userID int64
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		args      args
+		mock      func(fields *fields)
+		detailRes int
+		detailErr error
+	}{
+		{
+			name: "error",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryCountRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnError(errors.New("expected error"))
+			},
+			detailRes: 0,
+			detailErr: errors.New("expected error"),
+		},
+		{
+			name: "passed",
+			fields: fields{
+				Db: dbMock,
+			},
+			args: args{
+				ctx:    context.Background(),
+				userID: 1,
+			},
+			mock: func(fields *fields) {
+				resultRows := sqlmock.
+					NewRows([]string{"count"}).
+					AddRow(8)
+
+				sqlMock.ExpectQuery(regexp.QuoteMeta(queryCountRecoveryCodes)).
+					WithArgs(int64(1)).
+					WillReturnRows(resultRows)
+			},
+			detailRes: 8,
+			detailErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Repository{
+				Db: tt.fields.Db,
+			}
+			tt.mock(&tt.fields)
+			res, err := r.CountRecoveryCodes(tt.args.ctx, tt.args.userID)
+			if utilsHelper.ErrorMessage(err) != utilsHelper.ErrorMessage(tt.detailErr) {
+				t.Errorf("Error When CountRecoveryCodes() %s, detailErr = %s", utilsHelper.ErrorMessage(err), utilsHelper.ErrorMessage(tt.detailErr))
+			}
+			if res != tt.detailRes {
+				t.Errorf("Result When CountRecoveryCodes() %d, detailRes = %d", res, tt.detailRes)
+			}
+		})
+	}
+}
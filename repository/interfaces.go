@@ -4,13 +4,47 @@
 // interfaces using mockgen. See the Makefile for more information.
 package repository
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 //go:generate mockgen -source=interfaces.go -destination=interfaces.mock.gen.go -package=repository
 type RepositoryInterface interface {
 	GetUserByID(ctx context.Context, userID int64) (user User, err error)
 	GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (user User, err error)
-	CreateLoginCount(ctx context.Context, userID int64) (err error)
+	InsertLoginEvent(ctx context.Context, data LoginEvent) (err error)
+	GetLoginEvents(ctx context.Context, userID int64, beforeID int64, limit int) (events []LoginEvent, err error)
 	InsertUser(ctx context.Context, data User) (userID int64, err error)
 	UpdateUser(ctx context.Context, data User) (err error)
+	UpdatePassword(ctx context.Context, userID int64, passwordHash string) (tokenVersion int64, err error)
+	GetPasswordHistory(ctx context.Context, userID int64, limit int) (passwordHashes []string, err error)
+	RehashPassword(ctx context.Context, userID int64, oldHash string, newHash string) (updated bool, err error)
+	MarkPhoneNumberVerified(ctx context.Context, userID int64) (err error)
+	DeleteExpiredRegistrations(ctx context.Context, before time.Time) (err error)
+	InsertRefreshToken(ctx context.Context, data RefreshToken) (refreshTokenID int64, err error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (refreshToken RefreshToken, err error)
+	RotateRefreshToken(ctx context.Context, refreshTokenID int64) (rotated bool, err error)
+	InsertSession(ctx context.Context, data Session, limit SessionLimit) (evictedSessionIDs []string, err error)
+	GetSessionByID(ctx context.Context, sessionID string) (session Session, err error)
+	TouchSession(ctx context.Context, sessionID string, ipAddress string, idleSince time.Time) (session Session, err error)
+	GetActiveSessions(ctx context.Context, userID int64, idleSince time.Time) (sessions []Session, err error)
+	RevokeSession(ctx context.Context, sessionID string) (err error)
+	RevokeOtherSessions(ctx context.Context, userID int64, keepSessionID string) (sessionIDs []string, err error)
+	GetLoginAttempts(ctx context.Context, keys []string) (attempts []LoginAttempt, err error)
+	IncrementLoginAttempts(ctx context.Context, keys []string, windowStart time.Time) (attempts []LoginAttempt, err error)
+	LockLoginAttempt(ctx context.Context, key string, lockedUntil time.Time) (err error)
+	ResetLoginAttempts(ctx context.Context, keys []string) (err error)
+	InsertOneTimeCode(ctx context.Context, data OneTimeCode, limit OneTimeCodeLimit) (oneTimeCodeID int64, err error)
+	GetActiveOneTimeCode(ctx context.Context, userID int64, purpose string) (oneTimeCode OneTimeCode, err error)
+	ReserveOneTimeCodeAttempt(ctx context.Context, oneTimeCodeID int64, maxAttempts int) (reserved bool, err error)
+	UseOneTimeCode(ctx context.Context, oneTimeCodeID int64) (used bool, err error)
+	DeleteExpiredOneTimeCodes(ctx context.Context, before time.Time) (err error)
+	SaveTOTPSecret(ctx context.Context, data TOTPSecret) (saved bool, err error)
+	GetTOTPSecret(ctx context.Context, userID int64) (secret TOTPSecret, err error)
+	ConfirmTOTPSecret(ctx context.Context, userID int64, step int64) (confirmed bool, err error)
+	UseTOTPStep(ctx context.Context, userID int64, step int64) (used bool, err error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) (err error)
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (used bool, err error)
+	CountRecoveryCodes(ctx context.Context, userID int64) (count int, err error)
 }
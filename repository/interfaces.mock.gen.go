@@ -7,6 +7,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +35,184 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// ConfirmTOTPSecret mocks base method.
+func (m *MockRepositoryInterface) ConfirmTOTPSecret(ctx context.Context, userID, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTPSecret", ctx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTPSecret indicates an expected call of ConfirmTOTPSecret.
+func (mr *MockRepositoryInterfaceMockRecorder) ConfirmTOTPSecret(ctx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTPSecret", reflect.TypeOf((*MockRepositoryInterface)(nil).ConfirmTOTPSecret), ctx, userID, step)
+}
+
+// CountRecoveryCodes mocks base method.
+func (m *MockRepositoryInterface) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRecoveryCodes", ctx, userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRecoveryCodes indicates an expected call of CountRecoveryCodes.
+func (mr *MockRepositoryInterfaceMockRecorder) CountRecoveryCodes(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRecoveryCodes", reflect.TypeOf((*MockRepositoryInterface)(nil).CountRecoveryCodes), ctx, userID)
+}
+
+// DeleteExpiredOneTimeCodes mocks base method.
+func (m *MockRepositoryInterface) DeleteExpiredOneTimeCodes(ctx context.Context, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredOneTimeCodes", ctx, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpiredOneTimeCodes indicates an expected call of DeleteExpiredOneTimeCodes.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteExpiredOneTimeCodes(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredOneTimeCodes", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteExpiredOneTimeCodes), ctx, before)
+}
+
+// DeleteExpiredRegistrations mocks base method.
+func (m *MockRepositoryInterface) DeleteExpiredRegistrations(ctx context.Context, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredRegistrations", ctx, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpiredRegistrations indicates an expected call of DeleteExpiredRegistrations.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteExpiredRegistrations(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredRegistrations", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteExpiredRegistrations), ctx, before)
+}
+
+// GetActiveOneTimeCode mocks base method.
+func (m *MockRepositoryInterface) GetActiveOneTimeCode(ctx context.Context, userID int64, purpose string) (OneTimeCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveOneTimeCode", ctx, userID, purpose)
+	ret0, _ := ret[0].(OneTimeCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveOneTimeCode indicates an expected call of GetActiveOneTimeCode.
+func (mr *MockRepositoryInterfaceMockRecorder) GetActiveOneTimeCode(ctx, userID, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveOneTimeCode", reflect.TypeOf((*MockRepositoryInterface)(nil).GetActiveOneTimeCode), ctx, userID, purpose)
+}
+
+// GetActiveSessions mocks base method.
+func (m *MockRepositoryInterface) GetActiveSessions(ctx context.Context, userID int64, idleSince time.Time) ([]Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveSessions", ctx, userID, idleSince)
+	ret0, _ := ret[0].([]Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveSessions indicates an expected call of GetActiveSessions.
+func (mr *MockRepositoryInterfaceMockRecorder) GetActiveSessions(ctx, userID, idleSince interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveSessions", reflect.TypeOf((*MockRepositoryInterface)(nil).GetActiveSessions), ctx, userID, idleSince)
+}
+
+// GetLoginAttempts mocks base method.
+func (m *MockRepositoryInterface) GetLoginAttempts(ctx context.Context, keys []string) ([]LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginAttempts", ctx, keys)
+	ret0, _ := ret[0].([]LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginAttempts indicates an expected call of GetLoginAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) GetLoginAttempts(ctx, keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).GetLoginAttempts), ctx, keys)
+}
+
+// GetLoginEvents mocks base method.
+func (m *MockRepositoryInterface) GetLoginEvents(ctx context.Context, userID, beforeID int64, limit int) ([]LoginEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginEvents", ctx, userID, beforeID, limit)
+	ret0, _ := ret[0].([]LoginEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginEvents indicates an expected call of GetLoginEvents.
+func (mr *MockRepositoryInterfaceMockRecorder) GetLoginEvents(ctx, userID, beforeID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginEvents", reflect.TypeOf((*MockRepositoryInterface)(nil).GetLoginEvents), ctx, userID, beforeID, limit)
+}
+
+// GetPasswordHistory mocks base method.
+func (m *MockRepositoryInterface) GetPasswordHistory(ctx context.Context, userID int64, limit int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordHistory", ctx, userID, limit)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordHistory indicates an expected call of GetPasswordHistory.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPasswordHistory(ctx, userID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordHistory", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPasswordHistory), ctx, userID, limit)
+}
+
+// GetRefreshTokenByHash mocks base method.
+func (m *MockRepositoryInterface) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByHash", ctx, tokenHash)
+	ret0, _ := ret[0].(RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByHash indicates an expected call of GetRefreshTokenByHash.
+func (mr *MockRepositoryInterfaceMockRecorder) GetRefreshTokenByHash(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByHash", reflect.TypeOf((*MockRepositoryInterface)(nil).GetRefreshTokenByHash), ctx, tokenHash)
+}
+
+// GetSessionByID mocks base method.
+func (m *MockRepositoryInterface) GetSessionByID(ctx context.Context, sessionID string) (Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByID", ctx, sessionID)
+	ret0, _ := ret[0].(Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByID indicates an expected call of GetSessionByID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetSessionByID(ctx, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetSessionByID), ctx, sessionID)
+}
+
+// GetTOTPSecret mocks base method.
+func (m *MockRepositoryInterface) GetTOTPSecret(ctx context.Context, userID int64) (TOTPSecret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTPSecret", ctx, userID)
+	ret0, _ := ret[0].(TOTPSecret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTPSecret indicates an expected call of GetTOTPSecret.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTOTPSecret(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTPSecret", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTOTPSecret), ctx, userID)
+}
+
 // GetUserByID mocks base method.
 func (m *MockRepositoryInterface) GetUserByID(ctx context.Context, userID int64) (User, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +243,80 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetUserByPhoneNumber(ctx, phoneNu
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserByPhoneNumber), ctx, phoneNumber)
 }
 
+// IncrementLoginAttempts mocks base method.
+func (m *MockRepositoryInterface) IncrementLoginAttempts(ctx context.Context, keys []string, windowStart time.Time) ([]LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementLoginAttempts", ctx, keys, windowStart)
+	ret0, _ := ret[0].([]LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementLoginAttempts indicates an expected call of IncrementLoginAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncrementLoginAttempts(ctx, keys, windowStart interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementLoginAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncrementLoginAttempts), ctx, keys, windowStart)
+}
+
+// InsertLoginEvent mocks base method.
+func (m *MockRepositoryInterface) InsertLoginEvent(ctx context.Context, data LoginEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertLoginEvent", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertLoginEvent indicates an expected call of InsertLoginEvent.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertLoginEvent(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertLoginEvent", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertLoginEvent), ctx, data)
+}
+
+// InsertOneTimeCode mocks base method.
+func (m *MockRepositoryInterface) InsertOneTimeCode(ctx context.Context, data OneTimeCode, limit OneTimeCodeLimit) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertOneTimeCode", ctx, data, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertOneTimeCode indicates an expected call of InsertOneTimeCode.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertOneTimeCode(ctx, data, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertOneTimeCode", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertOneTimeCode), ctx, data, limit)
+}
+
+// InsertRefreshToken mocks base method.
+func (m *MockRepositoryInterface) InsertRefreshToken(ctx context.Context, data RefreshToken) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertRefreshToken", ctx, data)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertRefreshToken indicates an expected call of InsertRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertRefreshToken(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertRefreshToken), ctx, data)
+}
+
+// InsertSession mocks base method.
+func (m *MockRepositoryInterface) InsertSession(ctx context.Context, data Session, limit SessionLimit) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertSession", ctx, data, limit)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertSession indicates an expected call of InsertSession.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertSession(ctx, data, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSession", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertSession), ctx, data, limit)
+}
+
 // InsertUser mocks base method.
 func (m *MockRepositoryInterface) InsertUser(ctx context.Context, data User) (int64, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +332,181 @@ func (mr *MockRepositoryInterfaceMockRecorder) InsertUser(ctx, data interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertUser), ctx, data)
 }
 
+// LockLoginAttempt mocks base method.
+func (m *MockRepositoryInterface) LockLoginAttempt(ctx context.Context, key string, lockedUntil time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockLoginAttempt", ctx, key, lockedUntil)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockLoginAttempt indicates an expected call of LockLoginAttempt.
+func (mr *MockRepositoryInterfaceMockRecorder) LockLoginAttempt(ctx, key, lockedUntil interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLoginAttempt", reflect.TypeOf((*MockRepositoryInterface)(nil).LockLoginAttempt), ctx, key, lockedUntil)
+}
+
+// MarkPhoneNumberVerified mocks base method.
+func (m *MockRepositoryInterface) MarkPhoneNumberVerified(ctx context.Context, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkPhoneNumberVerified", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkPhoneNumberVerified indicates an expected call of MarkPhoneNumberVerified.
+func (mr *MockRepositoryInterfaceMockRecorder) MarkPhoneNumberVerified(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkPhoneNumberVerified", reflect.TypeOf((*MockRepositoryInterface)(nil).MarkPhoneNumberVerified), ctx, userID)
+}
+
+// RehashPassword mocks base method.
+func (m *MockRepositoryInterface) RehashPassword(ctx context.Context, userID int64, oldHash, newHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RehashPassword", ctx, userID, oldHash, newHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RehashPassword indicates an expected call of RehashPassword.
+func (mr *MockRepositoryInterfaceMockRecorder) RehashPassword(ctx, userID, oldHash, newHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RehashPassword", reflect.TypeOf((*MockRepositoryInterface)(nil).RehashPassword), ctx, userID, oldHash, newHash)
+}
+
+// ReplaceRecoveryCodes mocks base method.
+func (m *MockRepositoryInterface) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodes", ctx, userID, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRecoveryCodes indicates an expected call of ReplaceRecoveryCodes.
+func (mr *MockRepositoryInterfaceMockRecorder) ReplaceRecoveryCodes(ctx, userID, codeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodes", reflect.TypeOf((*MockRepositoryInterface)(nil).ReplaceRecoveryCodes), ctx, userID, codeHashes)
+}
+
+// ReserveOneTimeCodeAttempt mocks base method.
+func (m *MockRepositoryInterface) ReserveOneTimeCodeAttempt(ctx context.Context, oneTimeCodeID int64, maxAttempts int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveOneTimeCodeAttempt", ctx, oneTimeCodeID, maxAttempts)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveOneTimeCodeAttempt indicates an expected call of ReserveOneTimeCodeAttempt.
+func (mr *MockRepositoryInterfaceMockRecorder) ReserveOneTimeCodeAttempt(ctx, oneTimeCodeID, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveOneTimeCodeAttempt", reflect.TypeOf((*MockRepositoryInterface)(nil).ReserveOneTimeCodeAttempt), ctx, oneTimeCodeID, maxAttempts)
+}
+
+// ResetLoginAttempts mocks base method.
+func (m *MockRepositoryInterface) ResetLoginAttempts(ctx context.Context, keys []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", ctx, keys)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) ResetLoginAttempts(ctx, keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).ResetLoginAttempts), ctx, keys)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockRepositoryInterface) RevokeOtherSessions(ctx context.Context, userID int64, keepSessionID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", ctx, userID, keepSessionID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeOtherSessions(ctx, userID, keepSessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeOtherSessions), ctx, userID, keepSessionID)
+}
+
+// RevokeSession mocks base method.
+func (m *MockRepositoryInterface) RevokeSession(ctx context.Context, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeSession(ctx, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeSession), ctx, sessionID)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockRepositoryInterface) RotateRefreshToken(ctx context.Context, refreshTokenID int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, refreshTokenID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) RotateRefreshToken(ctx, refreshTokenID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).RotateRefreshToken), ctx, refreshTokenID)
+}
+
+// SaveTOTPSecret mocks base method.
+func (m *MockRepositoryInterface) SaveTOTPSecret(ctx context.Context, data TOTPSecret) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTPSecret", ctx, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveTOTPSecret indicates an expected call of SaveTOTPSecret.
+func (mr *MockRepositoryInterfaceMockRecorder) SaveTOTPSecret(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPSecret", reflect.TypeOf((*MockRepositoryInterface)(nil).SaveTOTPSecret), ctx, data)
+}
+
+// TouchSession mocks base method.
+func (m *MockRepositoryInterface) TouchSession(ctx context.Context, sessionID, ipAddress string, idleSince time.Time) (Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchSession", ctx, sessionID, ipAddress, idleSince)
+	ret0, _ := ret[0].(Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TouchSession indicates an expected call of TouchSession.
+func (mr *MockRepositoryInterfaceMockRecorder) TouchSession(ctx, sessionID, ipAddress, idleSince interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchSession", reflect.TypeOf((*MockRepositoryInterface)(nil).TouchSession), ctx, sessionID, ipAddress, idleSince)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockRepositoryInterface) UpdatePassword(ctx context.Context, userID int64, passwordHash string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, userID, passwordHash)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdatePassword(ctx, userID, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdatePassword), ctx, userID, passwordHash)
+}
+
 // UpdateUser mocks base method.
 func (m *MockRepositoryInterface) UpdateUser(ctx context.Context, data User) error {
 	m.ctrl.T.Helper()
@@ -93,16 +521,47 @@ func (mr *MockRepositoryInterfaceMockRecorder) UpdateUser(ctx, data interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUser), ctx, data)
 }
 
-// CreateLoginCount mocks base method.
-func (m *MockRepositoryInterface) CreateLoginCount(ctx context.Context, userID int64) error {
+// UseOneTimeCode mocks base method.
+func (m *MockRepositoryInterface) UseOneTimeCode(ctx context.Context, oneTimeCodeID int64) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateLoginCount", ctx, userID)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "UseOneTimeCode", ctx, oneTimeCodeID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseOneTimeCode indicates an expected call of UseOneTimeCode.
+func (mr *MockRepositoryInterfaceMockRecorder) UseOneTimeCode(ctx, oneTimeCodeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseOneTimeCode", reflect.TypeOf((*MockRepositoryInterface)(nil).UseOneTimeCode), ctx, oneTimeCodeID)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockRepositoryInterface) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockRepositoryInterfaceMockRecorder) UseRecoveryCode(ctx, userID, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockRepositoryInterface)(nil).UseRecoveryCode), ctx, userID, codeHash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockRepositoryInterface) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CreateLoginCount indicates an expected call of CreateLoginCount.
-func (mr *MockRepositoryInterfaceMockRecorder) CreateLoginCount(ctx, userID interface{}) *gomock.Call {
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockRepositoryInterfaceMockRecorder) UseTOTPStep(ctx, userID, step interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginCount", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateLoginCount), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockRepositoryInterface)(nil).UseTOTPStep), ctx, userID, step)
 }
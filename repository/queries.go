@@ -6,7 +6,11 @@ const (
 			id,
 			phone_number,
 			password,
-			full_name
+			full_name,
+			phone_verified_at,
+			password_changed_at,
+			last_login_at,
+			token_version
 		FROM "user"
 		WHERE id = $1;
 	`
@@ -16,24 +20,78 @@ const (
 			id,
 			phone_number,
 			password,
-			full_name
+			full_name,
+			phone_verified_at,
+			password_changed_at,
+			last_login_at,
+			token_version
 		FROM "user"
 		WHERE phone_number = $1;
 	`
 
-	queryCreateLoginCount = `
+	// The user is only looked up by phone number when the caller does not
+	// know who is logging in.
+	queryInsertLoginEvent = `
+		WITH new_login_event AS (
+			INSERT INTO login_events (user_id, phone_number, ip_address, user_agent, outcome, failure_reason)
+			VALUES (
+				COALESCE(NULLIF($1::BIGINT, 0), (SELECT id FROM "user" WHERE phone_number = $2)),
+				$2, $3, $4, $5, NULLIF($6, '')
+			)
+			RETURNING user_id, outcome, created_at
+		)
 		UPDATE "user"
-		SET login_count = CASE
-			WHEN login_count IS NULL THEN 1
-			WHEN login_count IS NOT NULL THEN login_count+1
-		END
-		WHERE id = $1;
+		SET last_login_at = new_login_event.created_at
+		FROM new_login_event
+		WHERE "user".id = new_login_event.user_id
+			AND new_login_event.outcome = 'success';
+	`
+
+	queryGetLoginEvents = `
+		SELECT
+			id,
+			user_id,
+			phone_number,
+			ip_address,
+			user_agent,
+			outcome,
+			failure_reason,
+			created_at
+		FROM login_events
+		WHERE user_id = $1
+			AND ($2::BIGINT = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3;
 	`
 
 	queryInsertUser = `
-		INSERT INTO "user" (phone_number, password, full_name)
-		VALUES ($1, $2, $3)
-		RETURNING id;
+		WITH new_user AS (
+			INSERT INTO "user" (phone_number, password, full_name, registration_expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, password
+		), new_password_history AS (
+			INSERT INTO password_history (user_id, password)
+			SELECT id, password FROM new_user
+		)
+		SELECT id FROM new_user;
+	`
+
+	queryMarkPhoneNumberVerified = `
+		UPDATE "user"
+		SET phone_verified_at = NOW(),
+			registration_expires_at = NULL
+		WHERE id = $1
+			AND phone_verified_at IS NULL;
+	`
+
+	// Users created before phone verification existed have no
+	// registration_expires_at and are kept, and so are users who already
+	// logged in, which they can while LOGIN_ALLOW_UNVERIFIED is set.
+	queryDeleteExpiredRegistrations = `
+		DELETE FROM "user"
+		WHERE phone_verified_at IS NULL
+			AND registration_expires_at < $1
+			AND last_login_at IS NULL;
 	`
 
 	queryUpdateUser = `
@@ -41,4 +99,350 @@ const (
 		SET %s
 		WHERE id = $1;
 	`
+
+	queryUpdatePassword = `
+		WITH updated_user AS (
+			UPDATE "user"
+			SET password = $2,
+				password_changed_at = NOW(),
+				token_version = token_version + 1
+			WHERE id = $1
+			RETURNING id, password, token_version
+		), new_password_history AS (
+			INSERT INTO password_history (user_id, password)
+			SELECT id, password FROM updated_user
+		)
+		SELECT token_version FROM updated_user;
+	`
+
+	queryGetPasswordHistory = `
+		SELECT password
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2;
+	`
+
+	queryRehashPassword = `
+		UPDATE "user"
+		SET password = $3
+		WHERE id = $1
+			AND password = $2;
+	`
+
+	queryInsertRefreshToken = `
+		INSERT INTO refresh_token (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+	`
+
+	queryGetRefreshTokenByHash = `
+		SELECT
+			id,
+			user_id,
+			family_id,
+			token_hash,
+			expires_at,
+			rotated_at,
+			revoked_at
+		FROM refresh_token
+		WHERE token_hash = $1;
+	`
+
+	queryRotateRefreshToken = `
+		UPDATE refresh_token
+		SET rotated_at = NOW()
+		WHERE id = $1
+			AND rotated_at IS NULL
+			AND revoked_at IS NULL;
+	`
+
+	queryInsertSession = `
+		INSERT INTO "session" (id, user_id, expires_at, device_name, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+
+	// Locks the user for the rest of the transaction so that logins of the
+	// same user count and insert their sessions one at a time, and so do
+	// requests for one time codes.
+	queryLockUser = `
+		SELECT id
+		FROM "user"
+		WHERE id = $1
+		FOR UPDATE;
+	`
+
+	queryGetActiveSessionIDs = `
+		SELECT id
+		FROM "session"
+		WHERE user_id = $1
+			AND revoked_at IS NULL
+			AND expires_at > NOW()
+			AND last_seen_at > $2
+		ORDER BY created_at, id;
+	`
+
+	queryRevokeSessions = `
+		WITH revoked_session AS (
+			UPDATE "session"
+			SET revoked_at = NOW()
+			WHERE id = ANY($1)
+				AND revoked_at IS NULL
+		)
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE family_id = ANY($1)
+			AND revoked_at IS NULL;
+	`
+
+	queryGetSessionByID = `
+		SELECT
+			id,
+			user_id,
+			expires_at,
+			revoked_at
+		FROM "session"
+		WHERE id = $1;
+	`
+
+	// Only sessions still in use are touched, which extends them when they
+	// slide; the others come back as they are so that the caller can tell
+	// why they ended. The token version of the user comes along so that
+	// checking a token takes a single query.
+	queryTouchSession = `
+		WITH touched_session AS (
+			UPDATE "session"
+			SET last_seen_at = NOW(),
+				ip_address = $2
+			WHERE id = $1
+				AND revoked_at IS NULL
+				AND expires_at > NOW()
+				AND last_seen_at > $3
+			RETURNING id, last_seen_at
+		)
+		SELECT
+			"session".id,
+			"session".user_id,
+			"session".expires_at,
+			"session".revoked_at,
+			COALESCE(touched_session.last_seen_at, "session".last_seen_at),
+			"user".token_version
+		FROM "session"
+		JOIN "user" ON "user".id = "session".user_id
+		LEFT JOIN touched_session ON touched_session.id = "session".id
+		WHERE "session".id = $1;
+	`
+
+	queryGetActiveSessions = `
+		SELECT
+			id,
+			user_id,
+			device_name,
+			user_agent,
+			ip_address,
+			expires_at,
+			created_at,
+			last_seen_at
+		FROM "session"
+		WHERE user_id = $1
+			AND revoked_at IS NULL
+			AND expires_at > NOW()
+			AND last_seen_at > $2
+		ORDER BY last_seen_at DESC, id;
+	`
+
+	// Refresh tokens share their family id with the session they belong to,
+	// so revoking a session also revokes every refresh token issued for it.
+	queryRevokeSession = `
+		WITH revoked_session AS (
+			UPDATE "session"
+			SET revoked_at = NOW()
+			WHERE id = $1
+				AND revoked_at IS NULL
+		)
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE family_id = $1
+			AND revoked_at IS NULL;
+	`
+
+	// Revokes every session of a user but the one given, together with their
+	// refresh tokens, and returns the ids of the sessions it revoked.
+	queryRevokeOtherSessions = `
+		WITH revoked_session AS (
+			UPDATE "session"
+			SET revoked_at = NOW()
+			WHERE user_id = $1
+				AND id <> $2
+				AND revoked_at IS NULL
+			RETURNING id
+		), revoked_refresh_token AS (
+			UPDATE refresh_token
+			SET revoked_at = NOW()
+			WHERE user_id = $1
+				AND family_id <> $2
+				AND revoked_at IS NULL
+		)
+		SELECT id FROM revoked_session;
+	`
+
+	queryGetLoginAttempts = `
+		SELECT
+			"key",
+			failed_count,
+			last_failed_at,
+			locked_until
+		FROM login_attempt
+		WHERE "key" = ANY($1);
+	`
+
+	// Counters whose last failure happened before the window start ($2) are
+	// restarted instead of incremented.
+	queryIncrementLoginAttempts = `
+		INSERT INTO login_attempt ("key", failed_count, last_failed_at)
+		SELECT UNNEST($1::VARCHAR[]), 1, NOW()
+		ON CONFLICT ("key") DO UPDATE
+		SET failed_count = CASE
+				WHEN login_attempt.last_failed_at < $2 THEN 1
+				ELSE login_attempt.failed_count + 1
+			END,
+			last_failed_at = NOW()
+		RETURNING "key", failed_count, last_failed_at, locked_until;
+	`
+
+	queryLockLoginAttempt = `
+		UPDATE login_attempt
+		SET locked_until = $2
+		WHERE "key" = $1;
+	`
+
+	queryResetLoginAttempts = `
+		DELETE FROM login_attempt
+		WHERE "key" = ANY($1);
+	`
+
+	// A new code replaces the unused codes of the same user and purpose.
+	queryInsertOneTimeCode = `
+		WITH replaced_code AS (
+			UPDATE one_time_code
+			SET used_at = NOW()
+			WHERE user_id = $1
+				AND purpose = $2
+				AND used_at IS NULL
+		)
+		INSERT INTO one_time_code (user_id, purpose, phone_number, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id;
+	`
+
+	queryCountOneTimeCodes = `
+		SELECT COUNT(*)
+		FROM one_time_code
+		WHERE user_id = $1
+			AND purpose = $2
+			AND created_at > $3;
+	`
+
+	queryGetActiveOneTimeCode = `
+		SELECT
+			id,
+			user_id,
+			purpose,
+			phone_number,
+			code_hash,
+			attempts,
+			expires_at
+		FROM one_time_code
+		WHERE user_id = $1
+			AND purpose = $2
+			AND used_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1;
+	`
+
+	// Counts the attempt before the code is compared, and only while
+	// attempts are left, so that parallel guesses cannot get past the limit.
+	queryReserveOneTimeCodeAttempt = `
+		UPDATE one_time_code
+		SET attempts = attempts + 1
+		WHERE id = $1
+			AND used_at IS NULL
+			AND attempts < $2
+		RETURNING attempts;
+	`
+
+	queryUseOneTimeCode = `
+		UPDATE one_time_code
+		SET used_at = NOW()
+		WHERE id = $1
+			AND used_at IS NULL;
+	`
+
+	queryDeleteExpiredOneTimeCodes = `
+		DELETE FROM one_time_code
+		WHERE expires_at < $1;
+	`
+
+	// A confirmed secret is never replaced, enrolling again only replaces a
+	// secret that was not confirmed yet.
+	querySaveTOTPSecret = `
+		INSERT INTO user_totp (user_id, encrypted_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET encrypted_secret = EXCLUDED.encrypted_secret,
+			last_used_step = NULL,
+			created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL;
+	`
+
+	queryGetTOTPSecret = `
+		SELECT
+			user_id,
+			encrypted_secret,
+			last_used_step,
+			confirmed_at
+		FROM user_totp
+		WHERE user_id = $1;
+	`
+
+	queryConfirmTOTPSecret = `
+		UPDATE user_totp
+		SET confirmed_at = NOW(),
+			last_used_step = $2
+		WHERE user_id = $1
+			AND confirmed_at IS NULL;
+	`
+
+	queryUseTOTPStep = `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1
+			AND confirmed_at IS NOT NULL
+			AND (last_used_step IS NULL OR last_used_step < $2);
+	`
+
+	// New recovery codes replace every earlier code of the user, used or not.
+	queryReplaceRecoveryCodes = `
+		WITH replaced_code AS (
+			DELETE FROM recovery_code
+			WHERE user_id = $1
+		)
+		INSERT INTO recovery_code (user_id, code_hash)
+		SELECT $1, UNNEST($2::VARCHAR[]);
+	`
+
+	queryUseRecoveryCode = `
+		UPDATE recovery_code
+		SET used_at = NOW()
+		WHERE user_id = $1
+			AND code_hash = $2
+			AND used_at IS NULL;
+	`
+
+	queryCountRecoveryCodes = `
+		SELECT COUNT(*)
+		FROM recovery_code
+		WHERE user_id = $1
+			AND used_at IS NULL;
+	`
 )
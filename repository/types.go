@@ -1,9 +1,147 @@
 // This file contains types that are used in the repository layer.
 package repository
 
+import (
+	"errors"
+	"time"
+)
+
+// User is a registered user. A zero PhoneVerifiedAt means the user has not
+// proven yet that they own PhoneNumber. PasswordChangedAt is when the
+// password was last set, a rehash of the same password does not count.
+// LastLoginAt is zero until the first successful login. TokenVersion goes up
+// whenever the phone number or the password changes, which makes the access
+// tokens issued before stale. RegistrationExpiresAt is set for a new user
+// until their phone number is verified; an unverified user who never logged
+// in is deleted once it passes, so that nobody can hold on to a number they
+// do not own.
 type User struct {
+	ID                    int64
+	PhoneNumber           string
+	Password              string
+	FullName              string
+	PhoneVerifiedAt       time.Time
+	PasswordChangedAt     time.Time
+	LastLoginAt           time.Time
+	TokenVersion          int64
+	RegistrationExpiresAt time.Time
+}
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	RotatedAt time.Time
+	RevokedAt time.Time
+}
+
+// Session is a login on one device. DeviceName is the name the client gave
+// at login, if any, and UserAgent the one it logged in with. IPAddress and
+// LastSeenAt follow the latest authenticated request. TokenVersion is the
+// current token version of the user, only returned by TouchSession.
+type Session struct {
+	ID           string
+	UserID       int64
+	DeviceName   string
+	UserAgent    string
+	IPAddress    string
+	ExpiresAt    time.Time
+	RevokedAt    time.Time
+	CreatedAt    time.Time
+	LastSeenAt   time.Time
+	TokenVersion int64
+}
+
+// SessionLimit caps the active sessions of a user when a new one is
+// inserted. Sessions not seen since IdleSince have gone idle and do not
+// count. A zero MaxSessions means no limit. When the user is at the limit,
+// the oldest sessions are revoked to make room if EvictOldest is set, and
+// ErrSessionLimitReached is returned otherwise.
+type SessionLimit struct {
+	MaxSessions int
+	EvictOldest bool
+	IdleSince   time.Time
+}
+
+var ErrSessionLimitReached = errors.New("session limit reached")
+
+// ErrPhoneNumberTaken is returned when a user is saved with a phone number
+// that another user has, which the unique index on it catches even when
+// both saves run at the same time.
+var ErrPhoneNumberTaken = errors.New("phone number is already registered")
+
+// LoginAttempt counts the recent failed logins for a phone number or an IP
+// address. A zero LockedUntil means the key is not locked.
+type LoginAttempt struct {
+	Key          string
+	FailedCount  int
+	LastFailedAt time.Time
+	LockedUntil  time.Time
+}
+
+const (
+	LoginOutcomeSuccess                = "success"
+	LoginOutcomeFailure                = "failure"
+	LoginOutcomeLocked                 = "locked"
+	LoginOutcomeMFARequired            = "mfa_required"
+	LoginOutcomePasswordChangeRequired = "password_change_required"
+)
+
+// LoginEvent is a login attempt for PhoneNumber. UserID is zero when no user
+// has the phone number. FailureReason is only set for failed attempts.
+type LoginEvent struct {
+	ID            int64
+	UserID        int64
+	PhoneNumber   string
+	IPAddress     string
+	UserAgent     string
+	Outcome       string
+	FailureReason string
+	CreatedAt     time.Time
+}
+
+const (
+	OneTimeCodePurposePasswordReset = "password_reset"
+	OneTimeCodePurposeRegistration  = "registration"
+	OneTimeCodePurposePhoneChange   = "phone_change"
+	OneTimeCodePurposeLogin         = "login"
+)
+
+// OneTimeCode is a code sent to PhoneNumber for a single use. A zero UsedAt
+// means the code was neither used nor replaced by a newer one. For a phone
+// change, PhoneNumber is the new number the user asked for; the change is
+// pending until the code is used and is dropped once the code expires.
+type OneTimeCode struct {
 	ID          int64
+	UserID      int64
+	Purpose     string
 	PhoneNumber string
-	Password    string
-	FullName    string
+	CodeHash    string
+	Attempts    int
+	ExpiresAt   time.Time
+	UsedAt      time.Time
+}
+
+// OneTimeCodeLimit caps the codes a user is sent for a purpose. A new code
+// is refused with ErrOneTimeCodeLimitReached once MaxCodes were created for
+// the same purpose since Since, replaced and used ones included. A zero
+// MaxCodes means no limit.
+type OneTimeCodeLimit struct {
+	MaxCodes int
+	Since    time.Time
+}
+
+var ErrOneTimeCodeLimitReached = errors.New("one time code limit reached")
+
+// TOTPSecret is the authenticator app secret of a user, encrypted by the
+// caller. Two-factor authentication is enabled once ConfirmedAt is set.
+// LastUsedStep is the time step of the last accepted code, so that a code
+// cannot be used twice.
+type TOTPSecret struct {
+	UserID          int64
+	EncryptedSecret string
+	LastUsedStep    int64
+	ConfirmedAt     time.Time
 }
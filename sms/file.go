@@ -0,0 +1,52 @@
+// This file contains a sender for local development and tests that writes
+// messages to a file, or to the log, instead of delivering them.
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+type NewFileSenderOptions struct {
+	// Path of the file messages are appended to. Messages are logged when
+	// it is empty.
+	Path string
+}
+
+func NewFileSender(opts NewFileSenderOptions) *FileSender {
+	return &FileSender{
+		path: opts.Path,
+	}
+}
+
+func (s *FileSender) Send(ctx context.Context, phoneNumber string, message string) (err error) {
+	line := fmt.Sprintf("%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), phoneNumber, message)
+	if s.path == "" {
+		log.Infof("SMS to %s: %s", phoneNumber, message)
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(line)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,47 @@
+package sms
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_FileSender_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	s := NewFileSender(NewFileSenderOptions{
+		Path: path,
+	})
+
+	for _, message := range []string{"first message", "second message"} {
+		if err := s.Send(context.Background(), "+62821232342", message); err != nil {
+			t.Fatalf("Error When Send() %s", err.Error())
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("[Test_FileSender_Send] %s", err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "\t+62821232342\tfirst message") || !strings.HasSuffix(lines[1], "\t+62821232342\tsecond message") {
+		t.Errorf("Result When Send() %q", content)
+	}
+}
+
+func Test_FileSender_Send_log(t *testing.T) {
+	s := NewFileSender(NewFileSenderOptions{})
+	if err := s.Send(context.Background(), "+62821232342", "message"); err != nil {
+		t.Errorf("Error When Send() %s", err.Error())
+	}
+}
+
+func Test_FileSender_Send_error(t *testing.T) {
+	s := NewFileSender(NewFileSenderOptions{
+		Path: filepath.Join(t.TempDir(), "missing", "outbox.log"),
+	})
+	if err := s.Send(context.Background(), "+62821232342", "message"); err == nil {
+		t.Errorf("Error When Send() expected an error for a missing directory")
+	}
+}
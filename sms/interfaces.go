@@ -0,0 +1,11 @@
+// This file contains the interface used to deliver text messages to phone
+// numbers. For testing purpose we will generate mock implementations of
+// this interface using mockgen. See the Makefile for more information.
+package sms
+
+import "context"
+
+//go:generate mockgen -source=interfaces.go -destination=interfaces.mock.gen.go -package=sms
+type SMSSender interface {
+	Send(ctx context.Context, phoneNumber string, message string) (err error)
+}
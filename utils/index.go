@@ -4,6 +4,8 @@ const (
 	HttpErrorCode       = 500
 	ValidationErrorCode    = 422
 	AuthorizationErrorCode = 401
+	TooManyRequestsErrorCode = 429
+	ServiceUnavailableErrorCode = 503
 )
 
 func ErrorMessage(input error) string {